@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"api.swahilichess.com/internal/chesscom"
+	"api.swahilichess.com/internal/validator"
+	"github.com/labstack/echo/v4"
+)
+
+var chesscomVariants = []string{"rapid", "blitz", "bullet", "daily"}
+
+// chess.com doesn't flag provisional ratings, like lichess a rating is
+// provisional while its deviation is above this.
+const chesscomProvisionalRd = 110
+
+// chess.com has no batch endpoint, stats are fetched a few players at a time.
+const chesscomConcurrency = 4
+
+type chesscomPlayer struct {
+	Username string
+	Stats    chesscom.Stats
+}
+
+type chesscomCache struct {
+	players     map[string]chesscomPlayer
+	lastSuccess time.Time
+	lastError   string
+	mu          sync.RWMutex
+}
+
+// accountRating is a player's rating on one site in the combined leaderboard.
+type accountRating struct {
+	Username string `json:"username"`
+	Rating   int    `json:"rating"`
+	Games    int    `json:"games"`
+}
+
+type combinedEntry struct {
+	Rank     int            `json:"rank"`
+	Username string         `json:"username"`
+	FullName string         `json:"full_name"`
+	Photo    string         `json:"photo"`
+	Lichess  *accountRating `json:"lichess"`
+	Chesscom *accountRating `json:"chesscom"`
+	Best     int            `json:"best"`
+}
+
+func (app *application) runChesscomRefresher(ctx context.Context) {
+
+	cfg := app.config.Chesscom
+
+	for {
+		err := app.refreshChesscom(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("failed to refresh chess.com ratings", "error", err.Error())
+		}
+
+		wait := cfg.RefreshInterval
+		if cfg.RefreshJitter > 0 {
+			wait += rand.N(cfg.RefreshJitter)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("chess.com refresher stopped")
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// refreshChesscom fetches the stats of every registered chess.com username
+// and of the club members. Players whose fetch fails keep their previous stats.
+func (app *application) refreshChesscom(ctx context.Context) error {
+
+	usernames, err := app.store.GetChesscomUsernames(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get chess.com usernames: %w", err)
+	}
+
+	if app.config.Chesscom.ClubID != "" {
+		members, err := app.chesscom.ClubMembers(ctx, app.config.Chesscom.ClubID)
+		if err != nil {
+			return fmt.Errorf("failed to get chess.com club members: %w", err)
+		}
+		for _, member := range members {
+			usernames = append(usernames, member.Username)
+		}
+	}
+
+	seen := make(map[string]bool, len(usernames))
+	jobs := make(chan string)
+	players := make(map[string]chesscomPlayer, len(usernames))
+
+	app.chesscomCache.mu.RLock()
+	previous := app.chesscomCache.players
+	app.chesscomCache.mu.RUnlock()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		failures int
+		lastErr  error
+	)
+
+	for i := 0; i < chesscomConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for username := range jobs {
+				key := strings.ToLower(username)
+				stats, err := app.chesscom.Stats(ctx, username)
+
+				mu.Lock()
+				switch {
+				case err == nil:
+					players[key] = chesscomPlayer{Username: username, Stats: stats}
+				case errors.Is(err, chesscom.ErrNotFound):
+				default:
+					failures++
+					lastErr = err
+					if player, ok := previous[key]; ok {
+						players[key] = player
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	requested := 0
+	for _, username := range usernames {
+		key := strings.ToLower(username)
+		if username == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		requested++
+
+		select {
+		case jobs <- username:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	cache := &app.chesscomCache
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if requested > 0 && failures == requested {
+		cache.lastError = lastErr.Error()
+		return lastErr
+	}
+
+	cache.players = players
+	cache.lastSuccess = time.Now()
+	cache.lastError = ""
+
+	if failures > 0 {
+		slog.Warn("some chess.com stats failed to refresh", "failed", failures, "players", requested, "error", lastErr.Error())
+	}
+
+	return nil
+}
+
+// chesscomLeaderboardHandler works like the lichess leaderboard, without a
+// variant the rapid, blitz and bullet rankings are returned together.
+func (app *application) chesscomLeaderboardHandler(c echo.Context) error {
+
+	variant := c.QueryParam("variant")
+	if variant == "" {
+		if err := rejectRankingParams(c); err != nil {
+			return err
+		}
+	} else if !validator.In(variant, chesscomVariants...) {
+		return errBadRequest("unknown_variant", "unknown variant")
+	}
+
+	var filter leaderboardFilter
+
+	minGames, err := queryMinGames(c)
+	if err != nil {
+		return err
+	}
+	filter.MinGames = minGames
+
+	excludeProvisional, err := queryExcludeProvisional(c)
+	if err != nil {
+		return err
+	}
+	filter.ExcludeProvisional = excludeProvisional
+
+	page, pageSize, err := queryPage(c)
+	if err != nil {
+		return err
+	}
+
+	app.chesscomCache.mu.RLock()
+	players := app.chesscomCache.players
+	app.chesscomCache.mu.RUnlock()
+
+	if players == nil {
+		return &appError{
+			Status:  http.StatusServiceUnavailable,
+			Code:    "leaderboard_unavailable",
+			Message: "leaderboard is not available yet, try again later",
+		}
+	}
+
+	if variant == "" {
+		rank := func(variant string) []User {
+			users := []User{}
+			for _, s := range rankChesscom(players, variant, filter) {
+				users = append(users, User{Username: s.Username, Rating: s.Rating})
+			}
+			return users
+		}
+		return c.JSON(http.StatusOK, Leaderboard{
+			Rapid:  rank("rapid"),
+			Blitz:  rank("blitz"),
+			Bullet: rank("bullet"),
+		})
+	}
+
+	standings := rankChesscom(players, variant, filter)
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"variant":   variant,
+		"page":      page,
+		"page_size": pageSize,
+		"total":     len(standings),
+		"players":   pageStandings(standings, page, pageSize),
+	})
+
+}
+
+// combinedLeaderboardHandler lists the registered users with their lichess
+// and chess.com ratings in ?variant, ranked by the better of the two. Only
+// lichess accounts the user proved owning count.
+func (app *application) combinedLeaderboardHandler(c echo.Context) error {
+
+	variant := c.QueryParam("variant")
+	if variant == "" {
+		variant = "rapid"
+	}
+
+	if !validator.In(variant, "rapid", "blitz", "bullet") {
+		return errBadRequest("unknown_variant", "variant must be rapid, blitz or bullet")
+	}
+
+	accounts, err := app.store.GetLinkedAccounts(c.Request().Context())
+	if err != nil {
+		return errInternal("failed to get linked accounts", err)
+	}
+
+	app.leaderboardCache.mu.RLock()
+	lichessPlayers := make(map[string]accountRating, len(app.leaderboardCache.members))
+	for _, member := range app.leaderboardCache.members {
+		perf, ok := member.Perfs[variant]
+		if member.Disabled || !ok || perf.Rating == 0 {
+			continue
+		}
+		lichessPlayers[strings.ToLower(member.ID)] = accountRating{Username: member.Username, Rating: perf.Rating, Games: perf.Games}
+	}
+	app.leaderboardCache.mu.RUnlock()
+
+	app.chesscomCache.mu.RLock()
+	chesscomPlayers := make(map[string]accountRating, len(app.chesscomCache.players))
+	for key, player := range app.chesscomCache.players {
+		stats := player.Stats.Variant(variant)
+		if stats == nil {
+			continue
+		}
+		chesscomPlayers[key] = accountRating{Username: player.Username, Rating: stats.Last.Rating, Games: stats.Games()}
+	}
+	app.chesscomCache.mu.RUnlock()
+
+	entries := []combinedEntry{}
+
+	for _, account := range accounts {
+		entry := combinedEntry{
+			Username: account.Username,
+			FullName: account.FullName,
+			Photo:    account.Photo,
+		}
+
+		if rating, ok := lichessPlayers[account.LichessID.String]; ok && account.LichessID.Valid {
+			entry.Lichess = &rating
+			entry.Best = rating.Rating
+		}
+
+		if rating, ok := chesscomPlayers[strings.ToLower(account.ChesscomUsername)]; ok {
+			entry.Chesscom = &rating
+			entry.Best = max(entry.Best, rating.Rating)
+		}
+
+		if entry.Lichess == nil && entry.Chesscom == nil {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Best > entries[j].Best
+	})
+
+	for i := range entries {
+		if i > 0 && entries[i].Best == entries[i-1].Best {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"variant": variant,
+		"players": entries,
+	})
+
+}
+
+func rankChesscom(players map[string]chesscomPlayer, variant string, filter leaderboardFilter) []Standing {
+
+	standings := []Standing{}
+
+	for _, player := range players {
+		stats := player.Stats.Variant(variant)
+		if stats == nil || stats.Games() < filter.MinGames {
+			continue
+		}
+
+		provisional := stats.Last.Rd > chesscomProvisionalRd
+		if filter.ExcludeProvisional && provisional {
+			continue
+		}
+
+		standings = append(standings, Standing{
+			Username:    player.Username,
+			Rating:      stats.Last.Rating,
+			Games:       stats.Games(),
+			Rd:          stats.Last.Rd,
+			Provisional: provisional,
+		})
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		if standings[i].Rating == standings[j].Rating {
+			return standings[i].Username < standings[j].Username
+		}
+		return standings[i].Rating > standings[j].Rating
+	})
+
+	for i := range standings {
+		if i > 0 && standings[i].Rating == standings[i-1].Rating {
+			standings[i].Rank = standings[i-1].Rank
+		} else {
+			standings[i].Rank = i + 1
+		}
+	}
+
+	return standings
+}
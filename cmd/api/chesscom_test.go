@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"api.swahilichess.com/internal/chesscom"
+)
+
+func chesscomRapid(username string, rating, rd, games int) chesscomPlayer {
+	return chesscomPlayer{
+		Username: username,
+		Stats: chesscom.Stats{Rapid: &chesscom.GameStats{
+			Last:   chesscom.Rating{Rating: rating, Rd: rd},
+			Record: chesscom.Record{Win: games},
+		}},
+	}
+}
+
+func TestRankChesscom(t *testing.T) {
+
+	players := map[string]chesscomPlayer{
+		"asha":  chesscomRapid("Asha", 1800, 60, 40),
+		"juma":  chesscomRapid("Juma", 1900, 150, 3),
+		"neema": chesscomRapid("Neema", 1800, 110, 20),
+		"baraka": {Username: "Baraka", Stats: chesscom.Stats{
+			Blitz: &chesscom.GameStats{Last: chesscom.Rating{Rating: 2000}},
+		}},
+	}
+
+	tests := []struct {
+		name   string
+		filter leaderboardFilter
+		want   []string
+	}{
+		{name: "all", want: []string{"1 Juma", "2 Asha", "2 Neema"}},
+		{name: "min games", filter: leaderboardFilter{MinGames: 20}, want: []string{"1 Asha", "1 Neema"}},
+		{name: "exclude provisional", filter: leaderboardFilter{ExcludeProvisional: true}, want: []string{"1 Asha", "1 Neema"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			standings := rankChesscom(players, "rapid", tt.filter)
+
+			got := make([]string, len(standings))
+			for i, s := range standings {
+				got[i] = standingString(s)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("rankChesscom() = %v, want %v", got, tt.want)
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("rankChesscom() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+
+	for _, s := range rankChesscom(players, "rapid", leaderboardFilter{}) {
+		if want := s.Username == "Juma"; s.Provisional != want {
+			t.Errorf("%s provisional = %t, want %t", s.Username, s.Provisional, want)
+		}
+	}
+}
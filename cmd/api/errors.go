@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+)
+
+// appError is returned by handlers and middlewares, httpErrorHandler turns it
+// into the error envelope. Err is the underlying cause, it is logged for
+// server errors and never sent to the client.
+type appError struct {
+	Status  int
+	Code    string
+	Message string
+	Details map[string]string
+	Err     error
+}
+
+func (e *appError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s", e.Message, e.Err.Error())
+	}
+	return e.Message
+}
+
+func (e *appError) Unwrap() error {
+	return e.Err
+}
+
+type errorEnvelope struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+func errBadRequest(code, message string) error {
+	return &appError{Status: http.StatusBadRequest, Code: code, Message: message}
+}
+
+func errUnauthorized(code, message string) error {
+	return &appError{Status: http.StatusUnauthorized, Code: code, Message: message}
+}
+
+func errForbidden() error {
+	return &appError{Status: http.StatusForbidden, Code: "forbidden", Message: "you don't have permission to access this resource"}
+}
+
+func errNotFound(code, message string) error {
+	return &appError{Status: http.StatusNotFound, Code: code, Message: message}
+}
+
+func errConflict(code, message string) error {
+	return &appError{Status: http.StatusConflict, Code: code, Message: message}
+}
+
+func errPayloadTooLarge() error {
+	return &appError{Status: http.StatusRequestEntityTooLarge, Code: "payload_too_large", Message: "file too large"}
+}
+
+func errTooManyRequests(message string) error {
+	return &appError{Status: http.StatusTooManyRequests, Code: "too_many_requests", Message: message}
+}
+
+// errInternal hides err from the client, msg says what failed and is logged with it.
+func errInternal(msg string, err error) error {
+	return &appError{
+		Status:  http.StatusInternalServerError,
+		Code:    "internal_error",
+		Message: "internal server error",
+		Err:     fmt.Errorf("%s: %w", msg, err),
+	}
+}
+
+// errBind is returned when the request body can't be decoded.
+func errBind(err error) error {
+	return &appError{Status: http.StatusBadRequest, Code: "invalid_body", Message: "request body is malformed", Err: err}
+}
+
+// errValidation lists the failed fields by their json names.
+func errValidation(err error) error {
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return &appError{Status: http.StatusBadRequest, Code: "validation_failed", Message: "invalid input", Err: err}
+	}
+
+	details := make(map[string]string, len(verrs))
+	for _, fe := range verrs {
+		details[fe.Field()] = validationMessage(fe)
+	}
+
+	return &appError{
+		Status:  http.StatusBadRequest,
+		Code:    "validation_failed",
+		Message: "one or more fields are invalid",
+		Details: details,
+	}
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return fmt.Sprintf("must be at least %s characters", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s characters", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", fe.Param())
+	default:
+		return fmt.Sprintf("failed the %s check", fe.Tag())
+	}
+}
+
+// newValidator reports struct fields by their json tag so error details
+// match the names the client sent.
+func newValidator() *validator.Validate {
+
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	return v
+}
+
+// httpErrorHandler writes every error returned by a handler, middleware or
+// echo itself in the same envelope.
+func (app *application) httpErrorHandler(err error, c echo.Context) {
+
+	if c.Response().Committed {
+		return
+	}
+
+	var appErr *appError
+	var httpErr *echo.HTTPError
+
+	switch {
+	case errors.As(err, &appErr):
+
+	case errors.As(err, &httpErr):
+		appErr = &appError{
+			Status:  httpErr.Code,
+			Code:    httpErrorCode(httpErr.Code),
+			Message: strings.ToLower(http.StatusText(httpErr.Code)),
+			Err:     httpErr.Internal,
+		}
+		if msg, ok := httpErr.Message.(string); ok {
+			appErr.Message = msg
+		}
+
+	default:
+		appErr = &appError{
+			Status:  http.StatusInternalServerError,
+			Code:    "internal_error",
+			Message: "internal server error",
+			Err:     err,
+		}
+	}
+
+	if appErr.Status >= http.StatusInternalServerError {
+		slog.Error("request failed", "method", c.Request().Method, "path", c.Path(), "error", appErr.Error())
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(appErr.Status)
+	} else {
+		err = c.JSON(appErr.Status, errorEnvelope{Error: errorBody{
+			Code:    appErr.Code,
+			Message: appErr.Message,
+			Details: appErr.Details,
+		}})
+	}
+
+	if err != nil {
+		slog.Error("failed to write error response", "error", err.Error())
+	}
+}
+
+func httpErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "bad_request"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusMethodNotAllowed:
+		return "method_not_allowed"
+	case http.StatusRequestEntityTooLarge:
+		return "payload_too_large"
+	case http.StatusUnsupportedMediaType:
+		return "unsupported_media_type"
+	case http.StatusTooManyRequests:
+		return "too_many_requests"
+	case http.StatusServiceUnavailable:
+		return "service_unavailable"
+	default:
+		if status >= http.StatusInternalServerError {
+			return "internal_error"
+		}
+		return "error"
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+	"api.swahilichess.com/internal/jobs"
+	"api.swahilichess.com/internal/validator"
+	"github.com/labstack/echo/v4"
+)
+
+func (app *application) getJobsHandler(c echo.Context) error {
+
+	status := c.QueryParam("status")
+	if status == "" {
+		status = jobs.StatusDead
+	}
+
+	if !validator.In(status, jobs.StatusPending, jobs.StatusRunning, jobs.StatusDone, jobs.StatusDead) {
+		return errBadRequest("invalid_status", "invalid job status")
+	}
+
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	offset, err := strconv.Atoi(c.QueryParam("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	args := db.GetJobsByStatusParams{
+		Status: status,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	}
+
+	jobs, err := app.store.GetJobsByStatus(c.Request().Context(), args)
+	if err != nil {
+		return errInternal("failed to get jobs", err)
+	}
+
+	return c.JSON(http.StatusOK, jobs)
+
+}
+
+func (app *application) retryJobHandler(c echo.Context) error {
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return errBadRequest("invalid_id", "invalid job id")
+	}
+
+	rows, err := app.store.ReviveJob(c.Request().Context(), id)
+	if err != nil {
+		return errInternal("failed to retry job", err)
+	}
+
+	if rows == 0 {
+		return errNotFound("job_not_found", "dead job not found")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"success": "job queued for retry"})
+
+}
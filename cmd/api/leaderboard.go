@@ -1,31 +1,23 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"api.swahilichess.com/internal/lichess"
+	"api.swahilichess.com/internal/validator"
 	"github.com/labstack/echo/v4"
 )
 
-type Performance struct {
-	Games  int  `json:"games"`
-	Rating int  `json:"rating"`
-	Rd     int  `json:"rd"`
-	Prog   int  `json:"prog"`
-	Prov   bool `json:"prov"`
-}
-
-type Member struct {
-	Username string                 `json:"username"`
-	Perfs    map[string]Performance `json:"perfs"`
-	Disabled bool                   `json:"disabled"`
-}
-
+// Leaderboard is the response of the leaderboard without a variant, kept as
+// it was before rankings could be paged and filtered.
 type Leaderboard struct {
 	Rapid  []User `json:"rapid"`
 	Blitz  []User `json:"blitz"`
@@ -37,95 +29,393 @@ type User struct {
 	Rating   int    `json:"rating"`
 }
 
+// Standing is a member's place in the leaderboard of one variant.
+type Standing struct {
+	Rank        int    `json:"rank"`
+	Username    string `json:"username"`
+	Rating      int    `json:"rating"`
+	Games       int    `json:"games"`
+	Rd          int    `json:"rd"`
+	Prog        int    `json:"prog"`
+	Provisional bool   `json:"provisional"`
+	// Member is the swahilichess user who verified owning the account.
+	Member string `json:"member,omitempty"`
+}
+
+type leaderboardFilter struct {
+	MinGames           int
+	ExcludeProvisional bool
+}
+
+// lichessVariants are the perf keys with a rating, puzzle storm, racer and
+// streak only have scores.
+var lichessVariants = []string{
+	"ultraBullet", "bullet", "blitz", "rapid", "classical", "correspondence",
+	"chess960", "crazyhouse", "antichess", "atomic", "horde", "kingOfTheHill",
+	"racingKings", "threeCheck", "puzzle",
+}
+
 const (
-	user_url            = "https://lichess.org/api/users"
-	leaderboardCacheTTL = 3 * time.Minute
+	defaultLeaderboardPageSize = 50
+	maxLeaderboardPageSize     = 200
 )
 
+// refreshCall is a leaderboard refresh in progress, callers arriving while it
+// runs wait for its result instead of starting their own.
+type refreshCall struct {
+	done chan struct{}
+	err  error
+}
+
+// leaderboardHandler ranks the members in ?variant, paginated. Without a
+// variant the rapid, blitz and bullet rankings are returned together in the
+// original format, which can't be paged or filtered.
 func (app *application) leaderboardHandler(c echo.Context) error {
 
+	variant := c.QueryParam("variant")
+	if variant == "" {
+		if err := rejectRankingParams(c); err != nil {
+			return err
+		}
+	} else if !validator.In(variant, lichessVariants...) {
+		return errBadRequest("unknown_variant", "unknown variant")
+	}
+
+	var filter leaderboardFilter
+
+	minGames, err := queryMinGames(c)
+	if err != nil {
+		return err
+	}
+	filter.MinGames = minGames
+
+	excludeProvisional, err := queryExcludeProvisional(c)
+	if err != nil {
+		return err
+	}
+	filter.ExcludeProvisional = excludeProvisional
+
+	page, pageSize, err := queryPage(c)
+	if err != nil {
+		return err
+	}
+
 	app.leaderboardCache.mu.RLock()
-	if app.leaderboardCache.data != nil && time.Now().Before(app.leaderboardCache.expiresAt) {
-		cachedData := app.leaderboardCache.data
+	members := app.leaderboardCache.members
+	app.leaderboardCache.mu.RUnlock()
+
+	// only before the first successful refresh, afterwards stale data is
+	// served while the refresher retries
+	if members == nil {
+		err := app.refreshLeaderboard(c.Request().Context())
+		if err != nil {
+			return &appError{
+				Status:  http.StatusServiceUnavailable,
+				Code:    "leaderboard_unavailable",
+				Message: "leaderboard is not available yet, try again later",
+				Err:     err,
+			}
+		}
+
+		app.leaderboardCache.mu.RLock()
+		members = app.leaderboardCache.members
 		app.leaderboardCache.mu.RUnlock()
-		slog.Info("serving leaderboard from cache")
-		return c.JSON(http.StatusOK, cachedData)
 	}
-	app.leaderboardCache.mu.RUnlock()
 
-	slog.Info("fetching fresh leaderboard data from Lichess API")
+	if variant == "" {
+		return c.JSON(http.StatusOK, legacyLeaderboard(members))
+	}
 
-	members_ids, err := app.store.GetLichessTeamMembers(c.Request().Context())
-	if err != nil {
-		slog.Error("failed to get lichess team member ids", "error", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	linked := app.linkedLichessAccounts(c)
+
+	standings := rankVariant(members, variant, filter)
+	for i := range standings {
+		standings[i].Member = linked[strings.ToLower(standings[i].Username)]
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"variant":   variant,
+		"page":      page,
+		"page_size": pageSize,
+		"total":     len(standings),
+		"players":   pageStandings(standings, page, pageSize),
+	})
+
+}
+
+func (app *application) leaderboardStatusHandler(c echo.Context) error {
+
+	cache := &app.leaderboardCache
+
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+
+	status := map[string]any{
+		"last_success": nil,
+		"last_attempt": nil,
+		"last_error":   cache.lastError,
+		"refreshing":   cache.refreshing != nil,
+		"stale":        time.Since(cache.lastSuccess) > 2*app.config.Leaderboard.RefreshInterval,
+	}
+
+	if !cache.lastSuccess.IsZero() {
+		status["last_success"] = cache.lastSuccess
+	}
+
+	if !cache.lastAttempt.IsZero() {
+		status["last_attempt"] = cache.lastAttempt
+	}
+
+	return c.JSON(http.StatusOK, status)
+
+}
+
+// runLeaderboardRefresher refreshes the leaderboard until ctx is cancelled.
+// The jitter keeps replicas from hitting lichess at the same moment.
+func (app *application) runLeaderboardRefresher(ctx context.Context) {
+
+	cfg := app.config.Leaderboard
+
+	for {
+		err := app.refreshLeaderboard(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("failed to refresh leaderboard", "error", err.Error())
+		}
+
+		wait := cfg.RefreshInterval
+		if cfg.RefreshJitter > 0 {
+			wait += rand.N(cfg.RefreshJitter)
+		}
+
+		select {
+		case <-ctx.Done():
+			slog.Info("leaderboard refresher stopped")
+			return
+		case <-time.After(wait):
+		}
+	}
+}
 
+// refreshLeaderboard fetches the members' ratings from lichess and replaces
+// the cached leaderboard. Concurrent calls share a single fetch and a failed
+// fetch keeps the previous leaderboard. The fetch isn't tied to the caller
+// that started it, each caller stops waiting when its own ctx is done.
+func (app *application) refreshLeaderboard(ctx context.Context) error {
+
+	cache := &app.leaderboardCache
+
+	cache.mu.Lock()
+	call := cache.refreshing
+	if call == nil {
+		call = &refreshCall{done: make(chan struct{})}
+		cache.refreshing = call
+		app.background(func() {
+			app.runRefresh(context.WithoutCancel(ctx), call)
+		})
 	}
+	cache.mu.Unlock()
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	select {
+	case <-call.done:
+		return call.err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
+
+// runRefresh does the fetch shared by refreshLeaderboard's callers, it is
+// bounded by the refresh timeout instead of a caller's ctx.
+func (app *application) runRefresh(ctx context.Context, call *refreshCall) {
 
-	req, err := http.NewRequest("POST", user_url, strings.NewReader(strings.Join(members_ids, ",")))
+	cache := &app.leaderboardCache
+
+	members, err := app.fetchMembers(ctx)
+
+	cache.mu.Lock()
+	cache.lastAttempt = time.Now()
 	if err != nil {
-		slog.Error(fmt.Sprintf("failed to create request %s", user_url), "error", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		cache.lastError = err.Error()
+	} else {
+		cache.members = members
+		cache.lastSuccess = cache.lastAttempt
+		cache.lastError = ""
+	}
+	cache.refreshing = nil
+	cache.mu.Unlock()
+
+	call.err = err
+	close(call.done)
 
+	if err != nil {
+		return
 	}
-	req.Header.Set("Content-Type", "text/plain")
 
-	resp, err := client.Do(req)
+	ctx, cancel := context.WithTimeout(ctx, app.config.Leaderboard.RefreshTimeout)
+	defer cancel()
+
+	err = app.snapshotLeaderboard(ctx, members)
 	if err != nil {
-		slog.Error("failed to fetch team members data", "err", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		slog.Error("failed to store leaderboard snapshot", "error", err.Error())
 	}
+}
+
+func (app *application) fetchMembers(ctx context.Context) ([]lichess.User, error) {
 
-	defer resp.Body.Close()
+	ctx, cancel := context.WithTimeout(ctx, app.config.Leaderboard.RefreshTimeout)
+	defer cancel()
+
+	members_ids, err := app.store.GetLichessTeamMembers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lichess team member ids: %w", err)
+	}
 
-	var members []Member
-	err = json.NewDecoder(resp.Body).Decode(&members)
+	members, err := app.lichess.Users(ctx, members_ids)
 	if err != nil {
-		slog.Error("error while reading bod (users)", "err", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		return nil, fmt.Errorf("failed to fetch team members data: %w", err)
 	}
 
-	rapid := []User{}
-	blitz := []User{}
-	bullet := []User{}
+	return members, nil
+}
+
+// rankVariant orders the members by their rating in the variant, members
+// who never played it are left out. Equal ratings share a rank.
+func rankVariant(members []lichess.User, variant string, filter leaderboardFilter) []Standing {
+
+	standings := []Standing{}
+
+	for _, member := range members {
+		if member.Disabled {
+			continue
+		}
+
+		perf, ok := member.Perfs[variant]
+		if !ok || perf.Rating == 0 {
+			continue
+		}
 
-	for _, user := range members {
-		if !user.Disabled {
-			rapid = append(rapid, User{Username: user.Username, Rating: user.Perfs["rapid"].Rating})
-			blitz = append(blitz, User{Username: user.Username, Rating: user.Perfs["blitz"].Rating})
-			bullet = append(bullet, User{Username: user.Username, Rating: user.Perfs["bullet"].Rating})
+		if perf.Games < filter.MinGames || (filter.ExcludeProvisional && perf.Prov) {
+			continue
 		}
 
+		standings = append(standings, Standing{
+			Username:    member.Username,
+			Rating:      perf.Rating,
+			Games:       perf.Games,
+			Rd:          perf.Rd,
+			Prog:        perf.Prog,
+			Provisional: perf.Prov,
+		})
 	}
 
-	sort.Slice(rapid, func(i, j int) bool {
-		return rapid[i].Rating > rapid[j].Rating
+	sort.SliceStable(standings, func(i, j int) bool {
+		return standings[i].Rating > standings[j].Rating
 	})
 
-	sort.Slice(blitz, func(i, j int) bool {
-		return blitz[i].Rating > blitz[j].Rating
-	})
+	for i := range standings {
+		if i > 0 && standings[i].Rating == standings[i-1].Rating {
+			standings[i].Rank = standings[i-1].Rank
+		} else {
+			standings[i].Rank = i + 1
+		}
+	}
 
-	sort.Slice(bullet, func(i, j int) bool {
-		return bullet[i].Rating > bullet[j].Rating
-	})
+	return standings
+}
+
+// legacyLeaderboard lists every enabled member in rapid, blitz and bullet,
+// members who never played a variant are listed with a rating of 0.
+func legacyLeaderboard(members []lichess.User) Leaderboard {
+
+	rank := func(variant string) []User {
+		users := []User{}
+		for _, member := range members {
+			if !member.Disabled {
+				users = append(users, User{Username: member.Username, Rating: member.Perfs[variant].Rating})
+			}
+		}
+		sort.SliceStable(users, func(i, j int) bool {
+			return users[i].Rating > users[j].Rating
+		})
+		return users
+	}
+
+	return Leaderboard{
+		Rapid:  rank("rapid"),
+		Blitz:  rank("blitz"),
+		Bullet: rank("bullet"),
+	}
+}
+
+// rejectRankingParams fails when paging or filtering is asked for without a
+// variant, the combined response has no room for either.
+func rejectRankingParams(c echo.Context) error {
+	for _, param := range []string{"page", "page_size", "min_games", "exclude_provisional"} {
+		if c.QueryParam(param) != "" {
+			return errBadRequest("variant_required", param+" needs a variant")
+		}
+	}
+	return nil
+}
+
+func queryMinGames(c echo.Context) (int, error) {
+
+	param := c.QueryParam("min_games")
+	if param == "" {
+		return 0, nil
+	}
+
+	minGames, err := strconv.Atoi(param)
+	if err != nil || minGames < 0 {
+		return 0, errBadRequest("invalid_min_games", "min_games must be a positive number")
+	}
+
+	return minGames, nil
+}
 
-	leaderboard := Leaderboard{
-		Rapid:  rapid,
-		Blitz:  blitz,
-		Bullet: bullet,
+func queryExcludeProvisional(c echo.Context) (bool, error) {
+
+	param := c.QueryParam("exclude_provisional")
+	if param == "" {
+		return false, nil
+	}
+
+	exclude, err := strconv.ParseBool(param)
+	if err != nil {
+		return false, errBadRequest("invalid_exclude_provisional", "exclude_provisional must be true or false")
 	}
 
-	app.leaderboardCache.mu.Lock()
-	app.leaderboardCache.data = &leaderboard
-	app.leaderboardCache.expiresAt = time.Now().Add(leaderboardCacheTTL)
-	app.leaderboardCache.mu.Unlock()
+	return exclude, nil
+}
+
+// pageStandings returns the standings on the page, pages past the end are
+// empty.
+func pageStandings(standings []Standing, page, pageSize int) []Standing {
 
+	start := min((page-1)*pageSize, len(standings))
+	end := min(start+pageSize, len(standings))
+
+	return standings[start:end]
+}
 
-	return c.JSON(http.StatusOK, leaderboard)
+func queryPage(c echo.Context) (int, int, error) {
+
+	page, pageSize := 1, defaultLeaderboardPageSize
+
+	if param := c.QueryParam("page"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 1 {
+			return 0, 0, errBadRequest("invalid_page", "page must be a positive number")
+		}
+		page = n
+	}
+
+	if param := c.QueryParam("page_size"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 1 || n > maxLeaderboardPageSize {
+			return 0, 0, errBadRequest("invalid_page_size", fmt.Sprintf("page_size must be between 1 and %d", maxLeaderboardPageSize))
+		}
+		pageSize = n
+	}
 
+	return page, pageSize, nil
 }
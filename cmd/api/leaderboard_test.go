@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"api.swahilichess.com/internal/lichess"
+	"github.com/labstack/echo/v4"
+)
+
+// standingString is the rank and username of a standing.
+func standingString(s Standing) string {
+	return strconv.Itoa(s.Rank) + " " + s.Username
+}
+
+func lichessMember(username string, perfs map[string]lichess.Perf) lichess.User {
+	return lichess.User{ID: username, Username: username, Perfs: perfs}
+}
+
+// lichessMembers play rapid, except Rehema who never did and Zawadi who is
+// disabled. Asha and Neema share a rating.
+var lichessMembers = []lichess.User{
+	lichessMember("Asha", map[string]lichess.Perf{"rapid": {Rating: 1800, Games: 40}, "blitz": {Rating: 1700, Games: 10}}),
+	lichessMember("Juma", map[string]lichess.Perf{"rapid": {Rating: 1900, Games: 5, Prov: true}}),
+	lichessMember("Neema", map[string]lichess.Perf{"rapid": {Rating: 1800, Games: 20}}),
+	lichessMember("Baraka", map[string]lichess.Perf{"rapid": {Rating: 1500, Games: 60}, "bullet": {Rating: 2100, Games: 300}}),
+	lichessMember("Rehema", map[string]lichess.Perf{"blitz": {Rating: 1600, Games: 30}}),
+	{ID: "zawadi", Username: "Zawadi", Disabled: true, Perfs: map[string]lichess.Perf{"rapid": {Rating: 2200, Games: 100}}},
+}
+
+func TestRankVariant(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		variant string
+		filter  leaderboardFilter
+		want    []string
+	}{
+		{name: "shared rank", variant: "rapid", want: []string{"1 Juma", "2 Asha", "2 Neema", "4 Baraka"}},
+		{name: "min games", variant: "rapid", filter: leaderboardFilter{MinGames: 20}, want: []string{"1 Asha", "1 Neema", "3 Baraka"}},
+		{name: "exclude provisional", variant: "rapid", filter: leaderboardFilter{ExcludeProvisional: true}, want: []string{"1 Asha", "1 Neema", "3 Baraka"}},
+		{name: "unplayed variant left out", variant: "blitz", want: []string{"1 Asha", "2 Rehema"}},
+		{name: "nobody played", variant: "chess960", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			standings := rankVariant(lichessMembers, tt.variant, tt.filter)
+
+			got := make([]string, len(standings))
+			for i, s := range standings {
+				got[i] = standingString(s)
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("rankVariant(%s) = %v, want %v", tt.variant, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLegacyLeaderboard(t *testing.T) {
+
+	board := legacyLeaderboard(lichessMembers)
+
+	tests := []struct {
+		variant string
+		users   []User
+		want    string
+	}{
+		{variant: "rapid", users: board.Rapid, want: "[{Juma 1900} {Asha 1800} {Neema 1800} {Baraka 1500} {Rehema 0}]"},
+		{variant: "blitz", users: board.Blitz, want: "[{Asha 1700} {Rehema 1600} {Juma 0} {Neema 0} {Baraka 0}]"},
+		{variant: "bullet", users: board.Bullet, want: "[{Baraka 2100} {Asha 0} {Juma 0} {Neema 0} {Rehema 0}]"},
+	}
+
+	for _, tt := range tests {
+		if got := fmt.Sprint(tt.users); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.variant, got, tt.want)
+		}
+	}
+}
+
+func TestQueryPage(t *testing.T) {
+
+	tests := []struct {
+		query        string
+		wantPage     int
+		wantPageSize int
+		wantCode     string
+	}{
+		{query: "", wantPage: 1, wantPageSize: defaultLeaderboardPageSize},
+		{query: "page=3&page_size=10", wantPage: 3, wantPageSize: 10},
+		{query: "page_size=200", wantPage: 1, wantPageSize: maxLeaderboardPageSize},
+		{query: "page=0", wantCode: "invalid_page"},
+		{query: "page=two", wantCode: "invalid_page"},
+		{query: "page_size=0", wantCode: "invalid_page_size"},
+		{query: "page_size=201", wantCode: "invalid_page_size"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+
+			req := httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+			c := echo.New().NewContext(req, httptest.NewRecorder())
+
+			page, pageSize, err := queryPage(c)
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("queryPage() error = %v, want %q", err, tt.wantCode)
+			}
+
+			if page != tt.wantPage || pageSize != tt.wantPageSize {
+				t.Errorf("queryPage() = %d, %d, want %d, %d", page, pageSize, tt.wantPage, tt.wantPageSize)
+			}
+		})
+	}
+}
+
+func TestPageStandings(t *testing.T) {
+
+	standings := rankVariant(lichessMembers, "rapid", leaderboardFilter{})
+
+	tests := []struct {
+		page     int
+		pageSize int
+		want     []string
+	}{
+		{page: 1, pageSize: 50, want: []string{"1 Juma", "2 Asha", "2 Neema", "4 Baraka"}},
+		{page: 1, pageSize: 3, want: []string{"1 Juma", "2 Asha", "2 Neema"}},
+		{page: 2, pageSize: 3, want: []string{"4 Baraka"}},
+		{page: 2, pageSize: 2, want: []string{"2 Neema", "4 Baraka"}},
+		{page: 5, pageSize: 2, want: []string{}},
+	}
+
+	for _, tt := range tests {
+
+		page := pageStandings(standings, tt.page, tt.pageSize)
+
+		got := make([]string, len(page))
+		for i, s := range page {
+			got[i] = standingString(s)
+		}
+
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("page %d of %d = %v, want %v", tt.page, tt.pageSize, got, tt.want)
+		}
+	}
+}
@@ -1,19 +1,41 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
-	"github.com/labstack/echo/v4"
+	"strconv"
+	"time"
+
 	db "api.swahilichess.com/internal/db/sqlc"
+	"api.swahilichess.com/internal/jobs"
+	"github.com/labstack/echo/v4"
 )
 
+// getLichessTeamMemberHandler lists the ids of every member ever synced, as
+// the bot has always received them. ?active=true leaves out those who left.
 func (app *application) getLichessTeamMemberHandler(c echo.Context) error {
 
-	members, err := app.store.GetLichessTeamMembers(c.Request().Context())
+	var active bool
+	if param := c.QueryParam("active"); param != "" {
+		var err error
+		active, err = strconv.ParseBool(param)
+		if err != nil {
+			return errBadRequest("invalid_active", "active must be true or false")
+		}
+	}
 
+	query := app.store.GetLichessTeamMemberIds
+	if active {
+		query = app.store.GetLichessTeamMembers
+	}
+
+	members, err := query(c.Request().Context())
 	if err != nil {
-		slog.Error("failed to get lichess member on db", "error", err)
-		c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		return errInternal("failed to get lichess member on db", err)
 	}
 
 	return c.JSON(http.StatusOK, members)
@@ -24,25 +46,125 @@ func (app *application) insertLichessTeamMemberHandler(c echo.Context) error {
 
 	var input struct {
 		LichessID string `json:"lichess_id"`
-        Username  string `json:"username"`
+		Username  string `json:"username"`
 	}
 
 	if err := c.Bind(&input); err != nil {
-		c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return errBind(err)
 	}
 
-	args := db.InsertLichessTeamMemberParams {
-          LichessID: input.LichessID,
-		  Username: input.Username,
-	} 
+	args := db.InsertLichessTeamMemberParams{
+		LichessID: input.LichessID,
+		Username:  input.Username,
+	}
 
 	err := app.store.InsertLichessTeamMember(c.Request().Context(), args)
 
 	if err != nil {
-		slog.Error("failed to insert lichess member on db", "error", err)
-		c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		return errInternal("failed to insert lichess member on db", err)
 	}
 
 	return c.JSON(http.StatusOK, nil)
 
 }
+
+const jobSyncLichessTeam = "sync_lichess_team"
+
+// runTeamSyncScheduler queues a team sync on start and then on every interval.
+// The job is keyed on the interval it falls in, so however many replicas run
+// the scheduler the team is synced once per interval.
+func (app *application) runTeamSyncScheduler(ctx context.Context) {
+
+	interval := app.config.Lichess.TeamSyncInterval
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		opts := jobs.Options{
+			DedupeKey: fmt.Sprintf("%s:%d", jobSyncLichessTeam, time.Now().Truncate(interval).Unix()),
+		}
+
+		_, err := app.jobs.EnqueueWith(ctx, app.store, jobSyncLichessTeam, struct{}{}, opts)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("failed to queue lichess team sync", "error", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncLichessTeamJob pulls the team roster from lichess and records who
+// joined or left since the last sync.
+func (app *application) syncLichessTeamJob(ctx context.Context, payload json.RawMessage) error {
+
+	members, err := app.lichess.TeamMembers(ctx, app.config.Lichess.TeamID)
+	if err != nil {
+		return err
+	}
+
+	// an empty roster is far more likely a lichess hiccup than everyone leaving
+	if len(members) == 0 {
+		return errors.New("lichess returned an empty team roster")
+	}
+
+	roster := make([]db.InsertLichessTeamMemberParams, 0, len(members))
+	for _, member := range members {
+		roster = append(roster, db.InsertLichessTeamMemberParams{
+			LichessID: member.ID,
+			Username:  member.Username,
+		})
+	}
+
+	result, err := app.store.SyncLichessTeamTx(ctx, roster)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("lichess team synced", "team", app.config.Lichess.TeamID, "members", len(roster), "joined", len(result.Joined), "left", len(result.Left))
+
+	return nil
+}
+
+func (app *application) syncLichessTeamHandler(c echo.Context) error {
+
+	if app.config.Lichess.TeamID == "" {
+		return errBadRequest("team_sync_disabled", "no lichess team is configured")
+	}
+
+	_, err := app.jobs.Enqueue(c.Request().Context(), jobSyncLichessTeam, struct{}{})
+	if err != nil {
+		return errInternal("failed to queue lichess team sync", err)
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]string{"success": "team sync queued"})
+
+}
+
+func (app *application) getLichessMembershipEventsHandler(c echo.Context) error {
+
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	offset, err := strconv.Atoi(c.QueryParam("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	events, err := app.store.GetLichessMembershipEvents(c.Request().Context(), db.GetLichessMembershipEventsParams{
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return errInternal("failed to get lichess membership events", err)
+	}
+
+	return c.JSON(http.StatusOK, events)
+
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log/slog"
 	"os"
@@ -8,8 +9,15 @@ import (
 	"time"
 
 	"api.swahilichess.com/config"
+	"api.swahilichess.com/internal/chesscom"
 	db "api.swahilichess.com/internal/db/sqlc"
+	"api.swahilichess.com/internal/jobs"
+	"api.swahilichess.com/internal/lichess"
 	"api.swahilichess.com/internal/nextsms"
+	"api.swahilichess.com/internal/passcode"
+	"api.swahilichess.com/internal/ratelimit"
+	"api.swahilichess.com/internal/sms"
+	"api.swahilichess.com/internal/storage"
 	"github.com/go-playground/validator/v10"
 	_ "github.com/lib/pq"
 )
@@ -17,9 +25,12 @@ import (
 const version = "1.0.0"
 
 type leaderboardCache struct {
-	data      *Leaderboard
-	expiresAt time.Time
-	mu        sync.RWMutex
+	members     []lichess.User
+	lastSuccess time.Time
+	lastAttempt time.Time
+	lastError   string
+	refreshing  *refreshCall
+	mu          sync.RWMutex
 }
 
 type application struct {
@@ -27,8 +38,16 @@ type application struct {
 	store            db.Store
 	wg               sync.WaitGroup
 	validator        *validator.Validate
-	nextsms          nextsms.NextSmS
+	sms              sms.Sender
+	storage          storage.Storage
+	jobs             *jobs.Queue
+	lichess          *lichess.Client
+	chesscom         *chesscom.Client
+	stopWorkers      context.CancelFunc
+	limiter          *ratelimit.Limiter
+	otp              *passcode.Manager
 	leaderboardCache leaderboardCache
+	chesscomCache    chesscomCache
 }
 
 func init() {
@@ -52,11 +71,67 @@ func main() {
 
 	flag.StringVar(&cfg.NextSmS.Username, "nextsms-username", os.Getenv("NEXTSMS_USERNAME"), "nextsms-username")
 	flag.StringVar(&cfg.NextSmS.Password, "nextsms-password", os.Getenv("NEXTSMS_PASSWORD"), "nextsms-password")
+	flag.StringVar(&cfg.NextSmS.Url, "nextsms-url", nextsms.DefaultURL, "nextsms base url")
+
+	flag.StringVar(&cfg.Lichess.URL, "lichess-url", lichess.DefaultURL, "lichess base url")
+	flag.StringVar(&cfg.Lichess.Token, "lichess-token", os.Getenv("LICHESS_TOKEN"), "lichess API token, raises rate limits")
+
+	flag.StringVar(&cfg.Lichess.TeamID, "lichess-team", os.Getenv("LICHESS_TEAM_ID"), "lichess team whose roster is synced, sync is off when empty")
+	flag.DurationVar(&cfg.Lichess.TeamSyncInterval, "lichess-team-sync-interval", time.Hour, "How often the lichess team roster is synced")
+	flag.StringVar(&cfg.Lichess.ClientID, "lichess-client-id", "api.swahilichess.com", "OAuth client id sent to lichess when linking accounts")
+	flag.StringVar(&cfg.Lichess.RedirectURL, "lichess-redirect-url", "https://api.swahilichess.com/lichess/oauth/callback", "OAuth callback lichess redirects to after linking")
+	flag.StringVar(&cfg.Lichess.LinkRedirectURL, "lichess-link-redirect-url", os.Getenv("LICHESS_LINK_REDIRECT_URL"), "Page the browser is sent to after linking a lichess account, the outcome is returned as JSON when empty")
+
+	flag.StringVar(&cfg.Chesscom.URL, "chesscom-url", chesscom.DefaultURL, "chess.com published data API base url")
+	flag.StringVar(&cfg.Chesscom.ClubID, "chesscom-club", os.Getenv("CHESSCOM_CLUB_ID"), "chess.com club whose members are ranked besides registered users")
+	flag.DurationVar(&cfg.Chesscom.RefreshInterval, "chesscom-refresh-interval", 15*time.Minute, "How often chess.com ratings are refreshed")
+	flag.DurationVar(&cfg.Chesscom.RefreshJitter, "chesscom-refresh-jitter", time.Minute, "Random delay added to each chess.com refresh")
+
+	flag.StringVar(&cfg.Storage.Backend, "storage-backend", "local", "Where uploads are stored (local|s3|memory)")
+	flag.StringVar(&cfg.Storage.LocalDir, "storage-local-dir", "/var/www/lugano/images", "Directory of the local storage backend")
+	flag.StringVar(&cfg.Storage.PublicURL, "storage-public-url", "https://images.swahilichess.com", "Base url uploads are served from, the s3 bucket url when empty")
+	flag.StringVar(&cfg.Storage.S3.Endpoint, "storage-s3-endpoint", os.Getenv("STORAGE_S3_ENDPOINT"), "S3 compatible endpoint, e.g. https://s3.amazonaws.com")
+	flag.StringVar(&cfg.Storage.S3.Region, "storage-s3-region", "us-east-1", "S3 region")
+	flag.StringVar(&cfg.Storage.S3.Bucket, "storage-s3-bucket", os.Getenv("STORAGE_S3_BUCKET"), "S3 bucket")
+	flag.StringVar(&cfg.Storage.S3.AccessKey, "storage-s3-access-key", os.Getenv("STORAGE_S3_ACCESS_KEY"), "S3 access key")
+	flag.StringVar(&cfg.Storage.S3.SecretKey, "storage-s3-secret-key", os.Getenv("STORAGE_S3_SECRET_KEY"), "S3 secret key")
+	flag.BoolVar(&cfg.Storage.S3.PathStyle, "storage-s3-path-style", true, "Address the bucket in the path instead of the host, as MinIO expects")
+
+	flag.StringVar(&cfg.SMS.Provider, "sms-provider", "nextsms", "SMS provider (nextsms|fake)")
+	flag.StringVar(&cfg.SMS.FakeFile, "sms-fake-file", "", "File the fake SMS provider appends messages to")
 
 	flag.IntVar(&cfg.DB.MaxOpenConns, "db-max-open-conns", 25, "PostgreSQL max open connections")
 	flag.IntVar(&cfg.DB.MaxIdleConns, "db-max-idle-conns", 25, "PostgreSQL max ilde connections")
 	flag.StringVar(&cfg.DB.MaxIdleTime, "db-max-idle-time", "15m", "PostgreSQL max connection  connections")
 
+	flag.StringVar(&cfg.OTP.Secret, "otp-secret", os.Getenv("OTP_SECRET"), "Secret key used to hash one-time passcodes")
+	flag.DurationVar(&cfg.OTP.TTL, "otp-ttl", 10*time.Minute, "One-time passcode lifetime")
+	flag.IntVar(&cfg.OTP.MaxAttempts, "otp-max-attempts", 5, "Wrong guesses allowed per one-time passcode")
+
+	flag.BoolVar(&cfg.RateLimit.Enabled, "limiter-enabled", true, "Enable rate limiting and login lockout")
+	flag.IntVar(&cfg.RateLimit.Requests, "limiter-requests", 10, "Requests allowed per IP and route in each window")
+	flag.DurationVar(&cfg.RateLimit.Window, "limiter-window", time.Minute, "Rate limiter window")
+	flag.StringVar(&cfg.RateLimit.Routes, "limiter-routes", "login=10/1m,register=5/1h,resend-activation=3/15m,forgot-password=3/15m", "Per route limits as name=requests/window, routes left out use the default limit")
+	flag.IntVar(&cfg.RateLimit.MaxFailures, "limiter-max-failures", 5, "Failed attempts allowed before lockout")
+	flag.DurationVar(&cfg.RateLimit.LockoutBase, "limiter-lockout-base", time.Minute, "First lockout duration, doubled on each further failure")
+	flag.DurationVar(&cfg.RateLimit.LockoutMax, "limiter-lockout-max", 24*time.Hour, "Maximum lockout duration")
+	flag.DurationVar(&cfg.RateLimit.FailureReset, "limiter-failure-reset", 24*time.Hour, "Forget failed attempts after this long without failures")
+	flag.DurationVar(&cfg.RateLimit.PruneInterval, "limiter-prune-interval", time.Hour, "How often ended rate limit windows and forgotten failures are deleted")
+
+	flag.IntVar(&cfg.Jobs.Workers, "jobs-workers", 2, "Number of background job workers")
+	flag.DurationVar(&cfg.Jobs.PollInterval, "jobs-poll-interval", 2*time.Second, "How often idle workers look for new jobs")
+	flag.IntVar(&cfg.Jobs.MaxAttempts, "jobs-max-attempts", 8, "Attempts before a job is moved to the dead state")
+	flag.DurationVar(&cfg.Jobs.Retention, "jobs-retention", 7*24*time.Hour, "How long done and dead jobs are kept")
+
+	flag.DurationVar(&cfg.Leaderboard.RefreshInterval, "leaderboard-refresh-interval", 3*time.Minute, "How often the leaderboard is refreshed from lichess")
+	flag.DurationVar(&cfg.Leaderboard.RefreshJitter, "leaderboard-refresh-jitter", 30*time.Second, "Random delay added to each leaderboard refresh")
+	flag.DurationVar(&cfg.Leaderboard.RefreshTimeout, "leaderboard-refresh-timeout", 10*time.Second, "Timeout of a single leaderboard refresh")
+	flag.DurationVar(&cfg.Leaderboard.SnapshotInterval, "leaderboard-snapshot-interval", time.Hour, "Minimum time between stored leaderboard snapshots")
+	flag.DurationVar(&cfg.Leaderboard.SnapshotRetention, "leaderboard-snapshot-retention", maxHistoryDays*24*time.Hour, "How long leaderboard snapshots are kept, 0 keeps them forever")
+
+	// the first federation admin can't be granted through the API
+	grantAdmin := flag.String("grant-federation-admin", "", "Grant the federation_admin role to this username and exit")
+
 	flag.Parse()
 
 	conn, err := config.OpenDB(cfg)
@@ -67,11 +142,118 @@ func main() {
 	defer conn.Close()
 	slog.Info("database connection pool established")
 
+	store := db.NewStore(conn)
+
+	if *grantAdmin != "" {
+		err = grantFederationAdmin(context.Background(), store, *grantAdmin)
+		if err != nil {
+			slog.Error("failed to grant federation admin", "username", *grantAdmin, "error", err)
+			return
+		}
+		slog.Info("federation admin granted", "username", *grantAdmin)
+		return
+	}
+
+	if cfg.OTP.Secret == "" {
+		slog.Error("otp secret is required")
+		return
+	}
+
+	routeLimits, err := ratelimit.ParseRules(cfg.RateLimit.Routes, rateLimitedRoutes)
+	if err != nil {
+		slog.Error("invalid route limits", "error", err)
+		return
+	}
+
+	var smsSender sms.Sender
+	switch cfg.SMS.Provider {
+	case "nextsms":
+		smsSender = nextsms.New(cfg.NextSmS.Username, cfg.NextSmS.Password, cfg.NextSmS.Url)
+	case "fake":
+		smsSender = sms.NewFake(cfg.SMS.FakeFile)
+	default:
+		slog.Error("unknown sms provider", "provider", cfg.SMS.Provider)
+		return
+	}
+
+	var mediaStorage storage.Storage
+	switch cfg.Storage.Backend {
+	case "local":
+		mediaStorage = storage.NewLocal(cfg.Storage.LocalDir, cfg.Storage.PublicURL)
+	case "s3":
+		mediaStorage, err = storage.NewS3(storage.S3Config{
+			Endpoint:  cfg.Storage.S3.Endpoint,
+			Region:    cfg.Storage.S3.Region,
+			Bucket:    cfg.Storage.S3.Bucket,
+			AccessKey: cfg.Storage.S3.AccessKey,
+			SecretKey: cfg.Storage.S3.SecretKey,
+			PathStyle: cfg.Storage.S3.PathStyle,
+			PublicURL: cfg.Storage.PublicURL,
+		})
+		if err != nil {
+			slog.Error("failed to configure s3 storage", "error", err)
+			return
+		}
+	case "memory":
+		mediaStorage = storage.NewMemory(cfg.Storage.PublicURL)
+	default:
+		slog.Error("unknown storage backend", "backend", cfg.Storage.Backend)
+		return
+	}
+
 	app := &application{
 		config:    cfg,
-		store:     db.NewStore(conn),
-		validator: validator.New(),
-		nextsms:   nextsms.New(cfg.NextSmS.Username, cfg.NextSmS.Password),
+		store:     store,
+		validator: newValidator(),
+		sms:       smsSender,
+		storage:   mediaStorage,
+		lichess:   lichess.New(cfg.Lichess.URL, cfg.Lichess.Token),
+		chesscom:  chesscom.New(cfg.Chesscom.URL),
+		otp:       passcode.New(store, cfg.OTP.Secret, cfg.OTP.TTL, cfg.OTP.MaxAttempts),
+		limiter: ratelimit.New(store, ratelimit.Config{
+			Requests:     cfg.RateLimit.Requests,
+			Window:       cfg.RateLimit.Window,
+			Routes:       routeLimits,
+			MaxFailures:  cfg.RateLimit.MaxFailures,
+			LockoutBase:  cfg.RateLimit.LockoutBase,
+			LockoutMax:   cfg.RateLimit.LockoutMax,
+			FailureReset: cfg.RateLimit.FailureReset,
+		}),
+		jobs: jobs.New(store, jobs.Config{
+			Workers:      cfg.Jobs.Workers,
+			PollInterval: cfg.Jobs.PollInterval,
+			MaxAttempts:  cfg.Jobs.MaxAttempts,
+			BaseBackoff:  30 * time.Second,
+			MaxBackoff:   time.Hour,
+			JobTimeout:   30 * time.Second,
+			StaleAfter:   5 * time.Minute,
+			Retention:    cfg.Jobs.Retention,
+		}),
+	}
+
+	app.jobs.Register(jobSendSMS, app.sendSMSJob)
+	app.jobs.Register(jobSyncLichessTeam, app.syncLichessTeamJob)
+
+	ctx, stopWorkers := context.WithCancel(context.Background())
+	app.stopWorkers = stopWorkers
+	app.background(func() {
+		app.jobs.Run(ctx)
+	})
+	app.background(func() {
+		app.runLeaderboardRefresher(ctx)
+	})
+	app.background(func() {
+		app.runChesscomRefresher(ctx)
+	})
+	if cfg.RateLimit.Enabled {
+		app.background(func() {
+			app.runLimiterPruner(ctx)
+		})
+	}
+	if cfg.Lichess.TeamID != "" {
+		app.background(func() {
+			app.runTeamSyncScheduler(ctx)
+		})
 	}
 
 	err = app.serve()
@@ -1,11 +1,10 @@
 package main
 
 import (
-	"crypto/sha256"
 	"database/sql"
 	"errors"
 	"log/slog"
-	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -20,20 +19,18 @@ func (app *application) authenticate(next echo.HandlerFunc) echo.HandlerFunc {
 
 		authorizationHeader := c.Request().Header.Get("Authorization")
 		if authorizationHeader == "" {
-			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return errUnauthorized("missing_token", "unauthorized")
 		}
 
 		headerParts := strings.Split(authorizationHeader, " ")
 		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
-			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid auth token"})
-
+			return errUnauthorized("invalid_token", "invalid auth token")
 		}
 
 		tokenString := headerParts[1]
-		tokenHash := sha256.Sum256([]byte(tokenString))
 
 		params := db.GetUserByTokenParams{
-			Hash:   tokenHash[:],
+			Hash:   token.Hash(tokenString),
 			Scope:  token.ScopeAuthentication,
 			Expiry: time.Now(),
 		}
@@ -43,13 +40,17 @@ func (app *application) authenticate(next echo.HandlerFunc) echo.HandlerFunc {
 		if err != nil {
 			switch {
 			case errors.Is(err, sql.ErrNoRows):
-				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid or expired auth token"})
+				return errUnauthorized("invalid_token", "invalid or expired auth token")
 			default:
-				slog.Error("Error on getting user associated with token ", "error", err.Error())
-				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+				return errInternal("error on getting user associated with token", err)
 			}
 		}
 
+		err = app.store.TouchToken(c.Request().Context(), params.Hash)
+		if err != nil {
+			slog.Error("failed to update token last used time", "error", err.Error())
+		}
+
 		c.Set("user", user)
 
 		return next(c)
@@ -57,3 +58,81 @@ func (app *application) authenticate(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 
 }
+
+// contextGetUser returns the user stored by the authenticate middleware.
+func (app *application) contextGetUser(c echo.Context) db.GetUserByTokenRow {
+	user, ok := c.Get("user").(db.GetUserByTokenRow)
+	if !ok {
+		panic("missing user value in request context")
+	}
+	return user
+}
+
+// requireSelfOrPermission allows the request when the :id route parameter
+// is the authenticated user or when one of the user's roles grants the
+// permission.
+func (app *application) requireSelfOrPermission(permission string) echo.MiddlewareFunc {
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+
+		return func(c echo.Context) error {
+
+			user := app.contextGetUser(c)
+
+			if c.Param("id") == user.ID.String() {
+				return next(c)
+			}
+
+			ok, err := app.userHasPermission(c, permission)
+			if err != nil {
+				return errInternal("failed to get user permissions", err)
+			}
+
+			if !ok {
+				return errForbidden()
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// requirePermission allows the request when one of the user's roles grants the permission.
+func (app *application) requirePermission(permission string) echo.MiddlewareFunc {
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+
+		return func(c echo.Context) error {
+
+			ok, err := app.userHasPermission(c, permission)
+			if err != nil {
+				return errInternal("failed to get user permissions", err)
+			}
+
+			if !ok {
+				return errForbidden()
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// userHasPermission loads the authenticated user's permissions once per request.
+func (app *application) userHasPermission(c echo.Context, permission string) (bool, error) {
+
+	permissions, ok := c.Get("permissions").([]string)
+	if !ok {
+		user := app.contextGetUser(c)
+
+		var err error
+		permissions, err = app.store.GetUserPermissions(c.Request().Context(), user.ID)
+		if err != nil {
+			return false, err
+		}
+
+		c.Set("permissions", permissions)
+	}
+
+	return slices.Contains(permissions, permission), nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+	"api.swahilichess.com/internal/lichess"
+	"api.swahilichess.com/internal/token"
+	"github.com/labstack/echo/v4"
+)
+
+const oauthProviderLichess = "lichess"
+const oauthStateTTL = 10 * time.Minute
+const duplicate_lichess_id = `pq: duplicate key value violates unique constraint "users_lichess_id_key"`
+
+func (app *application) lichessOAuth() lichess.OAuthConfig {
+	return lichess.OAuthConfig{
+		ClientID:    app.config.Lichess.ClientID,
+		RedirectURL: app.config.Lichess.RedirectURL,
+	}
+}
+
+// startLichessLinkHandler returns the lichess page where the authenticated
+// user proves they own the account, lichess then calls lichessCallbackHandler.
+func (app *application) startLichessLinkHandler(c echo.Context) error {
+
+	user := app.contextGetUser(c)
+
+	state, err := randomString()
+	if err != nil {
+		return errInternal("failed to generate oauth state", err)
+	}
+
+	verifier, err := lichess.NewVerifier()
+	if err != nil {
+		return errInternal("failed to generate code verifier", err)
+	}
+
+	err = app.store.DeleteExpiredOAuthStates(c.Request().Context(), time.Now())
+	if err != nil {
+		slog.Error("failed to delete expired oauth states", "error", err.Error())
+	}
+
+	err = app.store.CreateOAuthState(c.Request().Context(), db.CreateOAuthStateParams{
+		Hash:         token.Hash(state),
+		UserID:       user.ID,
+		Provider:     oauthProviderLichess,
+		CodeVerifier: verifier,
+		Expiry:       time.Now().Add(oauthStateTTL),
+	})
+	if err != nil {
+		return errInternal("failed to store oauth state", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"url": app.lichess.AuthCodeURL(app.lichessOAuth(), state, verifier)})
+
+}
+
+// lichessCallbackHandler is where lichess sends the browser back. When a link
+// redirect is configured the browser is sent on to it with the outcome in
+// the query, otherwise the outcome is written as JSON.
+func (app *application) lichessCallbackHandler(c echo.Context) error {
+
+	err := app.linkLichessAccount(c)
+
+	if app.config.Lichess.LinkRedirectURL == "" {
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, map[string]string{"success": "lichess account linked"})
+	}
+
+	redirect, parseErr := url.Parse(app.config.Lichess.LinkRedirectURL)
+	if parseErr != nil {
+		return errInternal("invalid lichess link redirect url", parseErr)
+	}
+
+	query := redirect.Query()
+	if err != nil {
+		var appErr *appError
+		if !errors.As(err, &appErr) || appErr.Status >= http.StatusInternalServerError {
+			slog.Error("failed to link lichess account", "error", err.Error())
+			appErr = errInternal("failed to link lichess account", err).(*appError)
+		}
+		query.Set("lichess", "error")
+		query.Set("code", appErr.Code)
+	} else {
+		query.Set("lichess", "linked")
+	}
+	redirect.RawQuery = query.Encode()
+
+	return c.Redirect(http.StatusFound, redirect.String())
+
+}
+
+func (app *application) linkLichessAccount(c echo.Context) error {
+
+	ctx := c.Request().Context()
+
+	if c.QueryParam("error") != "" {
+		return errBadRequest("lichess_access_denied", "access to the lichess account was denied")
+	}
+
+	code := c.QueryParam("code")
+	state := c.QueryParam("state")
+	if code == "" || state == "" {
+		return errBadRequest("invalid_oauth_callback", "code and state are required")
+	}
+
+	pending, err := app.store.ConsumeOAuthState(ctx, db.ConsumeOAuthStateParams{
+		Hash:     token.Hash(state),
+		Provider: oauthProviderLichess,
+		Expiry:   time.Now(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return errBadRequest("invalid_oauth_state", "link request is invalid or expired, start again")
+		default:
+			return errInternal("failed to get oauth state", err)
+		}
+	}
+
+	accessToken, err := app.lichess.Exchange(ctx, app.lichessOAuth(), code, pending.CodeVerifier)
+	if err != nil {
+		switch {
+		case errors.Is(err, lichess.ErrInvalidGrant):
+			return errBadRequest("invalid_oauth_code", "lichess refused the authorization code, start again")
+		default:
+			return errInternal("failed to exchange lichess authorization code", err)
+		}
+	}
+
+	account, err := app.lichess.Account(ctx, accessToken)
+
+	// the token was only needed to learn who the user is
+	revokeErr := app.lichess.RevokeToken(ctx, accessToken)
+	if revokeErr != nil {
+		slog.Error("failed to revoke lichess access token", "error", revokeErr.Error())
+	}
+
+	if err != nil {
+		return errInternal("failed to get lichess account", err)
+	}
+
+	err = app.store.LinkLichessAccount(ctx, db.LinkLichessAccountParams{
+		LichessID:       sql.NullString{String: strings.ToLower(account.ID), Valid: true},
+		LichessUsername: account.Username,
+		ID:              pending.UserID,
+	})
+	if err != nil {
+		switch {
+		case err.Error() == duplicate_lichess_id:
+			return errBadRequest("lichess_account_taken", "lichess account is linked to another user")
+		default:
+			return errInternal("failed to link lichess account", err)
+		}
+	}
+
+	return nil
+}
+
+func (app *application) unlinkLichessHandler(c echo.Context) error {
+
+	user := app.contextGetUser(c)
+
+	err := app.store.UnlinkLichessAccount(c.Request().Context(), user.ID)
+	if err != nil {
+		return errInternal("failed to unlink lichess account", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"success": "lichess account unlinked"})
+
+}
+
+// linkedLichessAccounts maps verified lichess ids to the swahilichess usernames.
+func (app *application) linkedLichessAccounts(c echo.Context) map[string]string {
+
+	accounts, err := app.store.GetLinkedLichessAccounts(c.Request().Context())
+	if err != nil {
+		// the leaderboard is still useful without the marks
+		slog.Error("failed to get linked lichess accounts", "error", err.Error())
+		return nil
+	}
+
+	linked := make(map[string]string, len(accounts))
+	for _, account := range accounts {
+		linked[account.LichessID.String] = account.Username
+	}
+
+	return linked
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"api.swahilichess.com/config"
+	db "api.swahilichess.com/internal/db/sqlc"
+	"api.swahilichess.com/internal/lichess"
+	"api.swahilichess.com/internal/lichess/lichesstest"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// oauthStore keeps the oauth states and linked accounts in memory, the
+// other store methods are not used by the link flow.
+type oauthStore struct {
+	db.Store
+
+	mu     sync.Mutex
+	states map[string]db.OauthState
+	linked map[uuid.UUID]db.LinkLichessAccountParams
+}
+
+func newOAuthStore() *oauthStore {
+	return &oauthStore{
+		states: make(map[string]db.OauthState),
+		linked: make(map[uuid.UUID]db.LinkLichessAccountParams),
+	}
+}
+
+func (s *oauthStore) CreateOAuthState(ctx context.Context, arg db.CreateOAuthStateParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[string(arg.Hash)] = db.OauthState{
+		Hash:         arg.Hash,
+		UserID:       arg.UserID,
+		Provider:     arg.Provider,
+		CodeVerifier: arg.CodeVerifier,
+		Expiry:       arg.Expiry,
+	}
+	return nil
+}
+
+func (s *oauthStore) DeleteExpiredOAuthStates(ctx context.Context, expiry time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, state := range s.states {
+		if state.Expiry.Before(expiry) {
+			delete(s.states, hash)
+		}
+	}
+	return nil
+}
+
+func (s *oauthStore) ConsumeOAuthState(ctx context.Context, arg db.ConsumeOAuthStateParams) (db.ConsumeOAuthStateRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[string(arg.Hash)]
+	delete(s.states, string(arg.Hash))
+
+	if !ok || state.Provider != arg.Provider || !state.Expiry.After(arg.Expiry) {
+		return db.ConsumeOAuthStateRow{}, sql.ErrNoRows
+	}
+
+	return db.ConsumeOAuthStateRow{UserID: state.UserID, CodeVerifier: state.CodeVerifier}, nil
+}
+
+func (s *oauthStore) LinkLichessAccount(ctx context.Context, arg db.LinkLichessAccountParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, linked := range s.linked {
+		if id != arg.ID && linked.LichessID == arg.LichessID {
+			return errors.New(duplicate_lichess_id)
+		}
+	}
+
+	s.linked[arg.ID] = arg
+	return nil
+}
+
+func newOAuthApp(srv *lichesstest.Server, store db.Store) *application {
+
+	var cfg config.Config
+	cfg.Lichess.ClientID = "swahilichess-test"
+	cfg.Lichess.RedirectURL = "https://api.example.com/v1/auth/lichess/callback"
+
+	return &application{
+		config:  cfg,
+		store:   store,
+		lichess: srv.Client(),
+	}
+}
+
+// startLink runs startLichessLinkHandler as the user, follows the returned
+// url on the fake and returns the query lichess sends the browser back with.
+func startLink(t *testing.T, app *application, userID uuid.UUID) url.Values {
+
+	t.Helper()
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+	c.Set("user", db.GetUserByTokenRow{ID: userID})
+
+	err := app.startLichessLinkHandler(c)
+	if err != nil {
+		t.Fatalf("startLichessLinkHandler() error = %v", err)
+	}
+
+	var res struct {
+		URL string `json:"url"`
+	}
+	err = json.Unmarshal(rec.Body.Bytes(), &res)
+	if err != nil {
+		t.Fatalf("invalid start response %q: %v", rec.Body.String(), err)
+	}
+
+	authURL, err := url.Parse(res.URL)
+	if err != nil {
+		t.Fatalf("invalid auth url %q: %v", res.URL, err)
+	}
+
+	if authURL.Query().Get("code_challenge_method") != "S256" || authURL.Query().Get("code_challenge") == "" {
+		t.Fatalf("auth url %q has no S256 code challenge", res.URL)
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(res.URL)
+	if err != nil {
+		t.Fatalf("GET %s error = %v", res.URL, err)
+	}
+	resp.Body.Close()
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect %q: %v", resp.Header.Get("Location"), err)
+	}
+
+	return location.Query()
+}
+
+// callback runs lichessCallbackHandler with the query.
+func callback(app *application, query url.Values) (*httptest.ResponseRecorder, error) {
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil), rec)
+
+	return rec, app.lichessCallbackHandler(c)
+}
+
+func errorCode(err error) string {
+	var appErr *appError
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return ""
+}
+
+func TestLichessLink(t *testing.T) {
+
+	srv := lichesstest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser(lichess.User{Username: "Juma"})
+	srv.Authorize("Juma")
+
+	store := newOAuthStore()
+	app := newOAuthApp(srv, store)
+
+	userID := uuid.New()
+
+	query := startLink(t, app, userID)
+
+	rec, err := callback(app, query)
+	if err != nil {
+		t.Fatalf("callback error = %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("callback status = %d, want 200", rec.Code)
+	}
+
+	linked := store.linked[userID]
+	if linked.LichessID.String != "juma" || linked.LichessUsername != "Juma" {
+		t.Errorf("linked %+v, want juma", linked)
+	}
+
+	if srv.Tokens() != 0 {
+		t.Errorf("%d lichess tokens left, want the token revoked", srv.Tokens())
+	}
+
+	// the state is consumed by the first callback
+	_, err = callback(app, query)
+	if code := errorCode(err); code != "invalid_oauth_state" {
+		t.Errorf("replayed callback error = %v, want invalid_oauth_state", err)
+	}
+}
+
+func TestLichessLinkErrors(t *testing.T) {
+
+	srv := lichesstest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser(lichess.User{Username: "Juma"})
+
+	store := newOAuthStore()
+	app := newOAuthApp(srv, store)
+
+	t.Run("denied", func(t *testing.T) {
+		srv.Authorize("")
+		query := startLink(t, app, uuid.New())
+
+		_, err := callback(app, query)
+		if code := errorCode(err); code != "lichess_access_denied" {
+			t.Errorf("callback error = %v, want lichess_access_denied", err)
+		}
+	})
+
+	srv.Authorize("Juma")
+
+	t.Run("missing code", func(t *testing.T) {
+		_, err := callback(app, url.Values{"state": {"state"}})
+		if code := errorCode(err); code != "invalid_oauth_callback" {
+			t.Errorf("callback error = %v, want invalid_oauth_callback", err)
+		}
+	})
+
+	t.Run("unknown state", func(t *testing.T) {
+		query := startLink(t, app, uuid.New())
+		query.Set("state", "forged")
+
+		_, err := callback(app, query)
+		if code := errorCode(err); code != "invalid_oauth_state" {
+			t.Errorf("callback error = %v, want invalid_oauth_state", err)
+		}
+	})
+
+	t.Run("expired state", func(t *testing.T) {
+		query := startLink(t, app, uuid.New())
+
+		store.mu.Lock()
+		for hash, state := range store.states {
+			state.Expiry = time.Now().Add(-time.Second)
+			store.states[hash] = state
+		}
+		store.mu.Unlock()
+
+		_, err := callback(app, query)
+		if code := errorCode(err); code != "invalid_oauth_state" {
+			t.Errorf("callback error = %v, want invalid_oauth_state", err)
+		}
+	})
+
+	t.Run("invalid code", func(t *testing.T) {
+		query := startLink(t, app, uuid.New())
+		query.Set("code", "forged")
+
+		_, err := callback(app, query)
+		if code := errorCode(err); code != "invalid_oauth_code" {
+			t.Errorf("callback error = %v, want invalid_oauth_code", err)
+		}
+	})
+
+	t.Run("account linked to another user", func(t *testing.T) {
+		_, err := callback(app, startLink(t, app, uuid.New()))
+		if err != nil {
+			t.Fatalf("first link error = %v", err)
+		}
+
+		_, err = callback(app, startLink(t, app, uuid.New()))
+		if code := errorCode(err); code != "lichess_account_taken" {
+			t.Errorf("second link error = %v, want lichess_account_taken", err)
+		}
+	})
+}
+
+func TestLichessLinkRedirect(t *testing.T) {
+
+	srv := lichesstest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser(lichess.User{Username: "Juma"})
+
+	app := newOAuthApp(srv, newOAuthStore())
+	app.config.Lichess.LinkRedirectURL = "https://swahilichess.com/profile?tab=accounts"
+
+	tests := []struct {
+		name      string
+		authorize string
+		want      url.Values
+	}{
+		{name: "linked", authorize: "Juma", want: url.Values{"tab": {"accounts"}, "lichess": {"linked"}}},
+		{name: "denied", authorize: "", want: url.Values{"tab": {"accounts"}, "lichess": {"error"}, "code": {"lichess_access_denied"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			srv.Authorize(tt.authorize)
+
+			rec, err := callback(app, startLink(t, app, uuid.New()))
+			if err != nil {
+				t.Fatalf("callback error = %v", err)
+			}
+
+			if rec.Code != http.StatusFound {
+				t.Fatalf("callback status = %d, want 302", rec.Code)
+			}
+
+			location, err := url.Parse(rec.Header().Get("Location"))
+			if err != nil {
+				t.Fatalf("invalid redirect %q: %v", rec.Header().Get("Location"), err)
+			}
+
+			if location.Host != "swahilichess.com" || location.Query().Encode() != tt.want.Encode() {
+				t.Errorf("redirect = %s, want swahilichess.com with %s", location, tt.want.Encode())
+			}
+		})
+	}
+}
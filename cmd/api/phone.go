@@ -0,0 +1,141 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+	"api.swahilichess.com/internal/passcode"
+	"api.swahilichess.com/internal/phone"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func (app *application) changePhoneNumberHandler(c echo.Context) error {
+
+	var input struct {
+		PhoneNumber string `json:"phone_number" validate:"required"`
+		Password    string `json:"password" validate:"required"`
+	}
+
+	if err := c.Bind(&input); err != nil {
+		return errBind(err)
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		return errValidation(err)
+	}
+
+	phoneNumber, err := normalizePhoneNumber(input.PhoneNumber)
+	if err != nil {
+		return errBadRequest("invalid_phone_number", "invalid phone number")
+	}
+	input.PhoneNumber = phoneNumber
+
+	user := app.contextGetUser(c)
+
+	err = bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(input.Password))
+	if err != nil {
+		switch {
+		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+			return errBadRequest("invalid_credentials", "invalid password")
+
+		default:
+			return errInternal("failed comparing hash", err)
+		}
+	}
+
+	if input.PhoneNumber == user.PhoneNumber {
+		return errBadRequest("phone_number_unchanged", "phone number is the same as the current one")
+	}
+
+	_, err = app.store.GetUserByUsernameOrPhone(c.Request().Context(), db.GetUserByUsernameOrPhoneParams{PhoneNumber: input.PhoneNumber})
+	switch {
+	case err == nil:
+		return errBadRequest("phone_number_taken", "phone number already exists")
+
+	case !errors.Is(err, sql.ErrNoRows):
+		return errInternal("failed to get user by phone", err)
+	}
+
+	err = app.store.ExecTx(c.Request().Context(), func(q db.Querier) error {
+		return app.sendPasscode(c.Request().Context(), q, user.ID, passcode.PurposePhoneChange, input.PhoneNumber, input.PhoneNumber, phoneChangeSMS)
+	})
+	if err != nil {
+		return errInternal("failed to send phone change passcode", err)
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]string{"success": "passcode sent to the new phone number"})
+
+}
+
+func (app *application) confirmPhoneNumberHandler(c echo.Context) error {
+
+	var input struct {
+		Passcode int32 `json:"passcode" validate:"required"`
+	}
+
+	if err := c.Bind(&input); err != nil {
+		return errBind(err)
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		return errValidation(err)
+	}
+
+	user := app.contextGetUser(c)
+
+	key := lockoutKey("phone-change", user.ID)
+	if locked, err := app.checkLockout(c, key); locked {
+		return err
+	}
+
+	phoneNumber, err := app.otp.Verify(c.Request().Context(), user.ID, passcode.PurposePhoneChange, int(input.Passcode))
+	if err != nil {
+		return app.passcodeError(c, key, err)
+	}
+
+	args := db.UpdateUserPhoneNumberParams{
+		PhoneNumber: phoneNumber,
+		ID:          user.ID,
+	}
+
+	// the old number is told about the change
+	msg := fmt.Sprintf("The phone number of your swahilichess account was changed to %s.", phoneNumber)
+
+	err = app.store.ExecTx(c.Request().Context(), func(q db.Querier) error {
+
+		err := q.UpdateUserPhoneNumber(c.Request().Context(), args)
+		if err != nil {
+			return err
+		}
+
+		return app.enqueueSMS(c.Request().Context(), q, msg, user.PhoneNumber, time.Time{})
+	})
+	if err != nil {
+		switch {
+		case err.Error() == duplicate_phone:
+			return errBadRequest("phone_number_taken", "phone number already exists")
+
+		default:
+			return errInternal("failed to update phone number", err)
+		}
+	}
+
+	app.resetFailures(c, key)
+
+	return c.JSON(http.StatusOK, map[string]string{"success": "phone number changed"})
+
+}
+
+// normalizePhoneNumber converts a phone number to E.164, an empty phone
+// number is left empty for lookups by username.
+func normalizePhoneNumber(phoneNumber string) (string, error) {
+	if phoneNumber == "" {
+		return "", nil
+	}
+	return phone.Parse(phoneNumber)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"api.swahilichess.com/internal/imaging"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const max_photo_size = 5 << 20
+
+// photo_prefix is where savePhoto stores photos in app.storage.
+const photo_prefix = "photos/"
+
+// formPhoto returns the uploaded photo, nil when the form has none.
+func formPhoto(c echo.Context) (*multipart.FileHeader, error) {
+
+	file, err := c.FormFile("photo")
+	if err != nil {
+		switch {
+		case errors.Is(err, http.ErrMissingFile):
+			return nil, nil
+		case strings.Contains(strings.ToLower(err.Error()), "too large"):
+			return nil, errPayloadTooLarge()
+		default:
+			return nil, errInternal("failed processing file upload", err)
+		}
+	}
+
+	return file, nil
+}
+
+// savePhoto checks the upload is an image, strips its metadata and stores it
+// with its thumbnails. It returns the URL of the photo.
+func (app *application) savePhoto(ctx context.Context, file *multipart.FileHeader) (string, error) {
+
+	if file.Size > max_photo_size {
+		return "", errPayloadTooLarge()
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return "", errInternal("failed to open uploaded file", err)
+	}
+	defer src.Close()
+
+	img, err := imaging.Process(src, max_photo_size)
+	if err != nil {
+		switch {
+		case errors.Is(err, imaging.ErrTooLarge):
+			return "", errPayloadTooLarge()
+		case errors.Is(err, imaging.ErrUnsupported):
+			return "", errBadRequest("unsupported_image", "photo must be a JPEG, PNG or GIF image")
+		case errors.Is(err, imaging.ErrInvalid):
+			return "", errBadRequest("invalid_image", "photo is not a valid image")
+		default:
+			return "", errInternal("failed to process photo", err)
+		}
+	}
+
+	key := photo_prefix + strings.ReplaceAll(uuid.New().String(), "-", "") + img.Ext
+
+	objects := map[string][]byte{key: img.Original}
+	for size, data := range img.Thumbnails {
+		objects[imaging.ThumbnailName(key, size)] = data
+	}
+
+	for object, data := range objects {
+		err := app.storage.Put(ctx, object, data, img.ContentType)
+		if err != nil {
+			app.removePhotoObjects(ctx, key)
+			return "", errInternal("failed to store photo", err)
+		}
+	}
+
+	return app.storage.URL(key), nil
+}
+
+// deletePhoto removes a photo stored by savePhoto and its thumbnails, the
+// default photo and photos hosted elsewhere are left alone.
+func (app *application) deletePhoto(ctx context.Context, photo string) {
+
+	key, ok := app.photoKey(photo)
+	if !ok {
+		return
+	}
+
+	app.removePhotoObjects(ctx, key)
+}
+
+// photoThumbnails returns the URLs of the photo's thumbnails keyed by their
+// edge. Only photos stored under photo_prefix have thumbnails, older uploads
+// were kept as they came.
+func (app *application) photoThumbnails(photo string) map[string]string {
+
+	thumbnails := map[string]string{}
+
+	key, ok := app.photoKey(photo)
+	if !ok || !strings.HasPrefix(key, photo_prefix) {
+		return thumbnails
+	}
+
+	for _, size := range imaging.ThumbnailSizes {
+		thumbnails[strconv.Itoa(size)] = app.storage.URL(imaging.ThumbnailName(key, size))
+	}
+
+	return thumbnails
+}
+
+func (app *application) photoKey(photo string) (string, bool) {
+
+	if photo == default_image {
+		return "", false
+	}
+
+	key, ok := strings.CutPrefix(photo, app.storage.URL(""))
+	if !ok || key == "" {
+		return "", false
+	}
+
+	return key, true
+}
+
+func (app *application) removePhotoObjects(ctx context.Context, key string) {
+
+	objects := []string{key}
+	for _, size := range imaging.ThumbnailSizes {
+		objects = append(objects, imaging.ThumbnailName(key, size))
+	}
+
+	for _, object := range objects {
+		err := app.storage.Delete(ctx, object)
+		if err != nil {
+			slog.Error("failed to delete photo", "key", object, "error", err.Error())
+		}
+	}
+}
@@ -10,10 +10,10 @@ import (
 func (app *application) pingHandler(c echo.Context) error {
 
 	ping := map[string]string{
-		"status":      "available",
-		"environment": app.config.ENV,
-		"version":     version,
-		"current_time":        time.Now().Format(time.RFC3339),
+		"status":       "available",
+		"environment":  app.config.ENV,
+		"version":      version,
+		"current_time": time.Now().Format(time.RFC3339),
 	}
 
 	return c.JSON(http.StatusOK, ping)
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// profileRating is a current rating taken from the cached leaderboard data.
+type profileRating struct {
+	Rating int `json:"rating"`
+	Games  int `json:"games"`
+}
+
+type linkedAccount struct {
+	Username string                   `json:"username"`
+	Verified bool                     `json:"verified"`
+	Ratings  map[string]profileRating `json:"ratings"`
+}
+
+// publicProfile is what anyone can read about a user, private fields such
+// as the phone number are only in ownProfile.
+type publicProfile struct {
+	Username   string            `json:"username"`
+	FullName   string            `json:"full_name"`
+	Photo      string            `json:"photo"`
+	Thumbnails map[string]string `json:"thumbnails"`
+	Lichess    *linkedAccount    `json:"lichess"`
+	Chesscom   *linkedAccount    `json:"chesscom"`
+	JoinedAt   time.Time         `json:"joined_at"`
+}
+
+type ownProfile struct {
+	ID uuid.UUID `json:"id"`
+	publicProfile
+	PhoneNumber string   `json:"phone_number"`
+	Activated   bool     `json:"activated"`
+	Enabled     bool     `json:"enabled"`
+	Roles       []string `json:"roles"`
+}
+
+func (app *application) getUserProfileHandler(c echo.Context) error {
+
+	user, err := app.store.GetUserByUsername(c.Request().Context(), c.Param("username"))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return errNotFound("user_not_found", "user not found")
+		default:
+			return errInternal("failed to get user by username", err)
+		}
+	}
+
+	if !(user.Activated && user.Enabled) {
+		return errNotFound("user_not_found", "user not found")
+	}
+
+	profile := app.buildProfile(user.Username, user.FullName, user.Photo, user.LichessUsername, user.LichessID, user.ChesscomUsername, user.CreatedAt)
+
+	return c.JSON(http.StatusOK, profile)
+
+}
+
+func (app *application) getMeHandler(c echo.Context) error {
+
+	user := app.contextGetUser(c)
+
+	roles, err := app.store.GetUserRoles(c.Request().Context(), user.ID)
+	if err != nil {
+		return errInternal("failed to get user roles", err)
+	}
+
+	profile := ownProfile{
+		ID:            user.ID,
+		publicProfile: app.buildProfile(user.Username, user.FullName, user.Photo, user.LichessUsername, user.LichessID, user.ChesscomUsername, user.CreatedAt),
+		PhoneNumber:   user.PhoneNumber,
+		Activated:     user.Activated,
+		Enabled:       user.Enabled,
+		Roles:         roles,
+	}
+
+	return c.JSON(http.StatusOK, profile)
+
+}
+
+func (app *application) buildProfile(username, fullName, photo, lichessUsername string, lichessID sql.NullString, chesscomUsername string, createdAt time.Time) publicProfile {
+
+	profile := publicProfile{
+		Username:   username,
+		FullName:   fullName,
+		Photo:      photo,
+		Thumbnails: app.photoThumbnails(photo),
+		JoinedAt:   createdAt,
+	}
+
+	if lichessUsername != "" || lichessID.Valid {
+		profile.Lichess = app.lichessAccount(lichessUsername, lichessID)
+	}
+
+	if chesscomUsername != "" {
+		profile.Chesscom = app.chesscomAccount(chesscomUsername)
+	}
+
+	return profile
+}
+
+// lichessAccount looks the account up by the verified id when there is one,
+// the free text username otherwise.
+func (app *application) lichessAccount(username string, id sql.NullString) *linkedAccount {
+
+	account := &linkedAccount{
+		Username: username,
+		Verified: id.Valid,
+		Ratings:  map[string]profileRating{},
+	}
+
+	key := strings.ToLower(username)
+	if id.Valid {
+		key = id.String
+	}
+
+	app.leaderboardCache.mu.RLock()
+	defer app.leaderboardCache.mu.RUnlock()
+
+	for _, member := range app.leaderboardCache.members {
+		if member.ID != key {
+			continue
+		}
+
+		account.Username = member.Username
+		for _, variant := range lichessVariants {
+			perf, ok := member.Perfs[variant]
+			if ok && perf.Rating != 0 {
+				account.Ratings[variant] = profileRating{Rating: perf.Rating, Games: perf.Games}
+			}
+		}
+		break
+	}
+
+	return account
+}
+
+func (app *application) chesscomAccount(username string) *linkedAccount {
+
+	account := &linkedAccount{
+		Username: username,
+		Ratings:  map[string]profileRating{},
+	}
+
+	app.chesscomCache.mu.RLock()
+	player, ok := app.chesscomCache.players[strings.ToLower(username)]
+	app.chesscomCache.mu.RUnlock()
+
+	if !ok {
+		return account
+	}
+
+	for _, variant := range chesscomVariants {
+		stats := player.Stats.Variant(variant)
+		if stats != nil {
+			account.Ratings[variant] = profileRating{Rating: stats.Last.Rating, Games: stats.Games()}
+		}
+	}
+
+	return account
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// rateLimitedRoutes are the route names that can be given their own rule.
+var rateLimitedRoutes = []string{
+	"login",
+	"refresh",
+	"register",
+	"activate",
+	"resend-activation",
+	"forgot-password",
+	"change-password",
+	"change-phone",
+	"confirm-phone",
+}
+
+// rateLimit limits how often a single IP can call the route, the window is
+// the route's rule or the default one.
+func (app *application) rateLimit(name string) echo.MiddlewareFunc {
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+
+		return func(c echo.Context) error {
+
+			if !app.config.RateLimit.Enabled {
+				return next(c)
+			}
+
+			key := fmt.Sprintf("ip:%s:%s", name, c.RealIP())
+
+			allowed, retryAfter, err := app.limiter.Allow(c.Request().Context(), name, key)
+			if err != nil {
+				// fail open, the database being down shouldn't lock everyone out
+				slog.Error("failed to check rate limit", "error", err.Error())
+				return next(c)
+			}
+
+			if !allowed {
+				return tooManyRequests(c, retryAfter)
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// lockoutKey identifies the account an attempt is made against. It is keyed
+// on the user rather than the identifiers sent, which can name the same
+// account in several ways.
+//
+// The key leaves out the client IP on purpose: guesses spread over many IPs
+// still lock the account. The trade-off is that anyone who knows a username
+// can lock its owner out for up to the maximum lockout, the per IP request
+// limit only slows that down.
+func lockoutKey(name string, userID uuid.UUID) string {
+	return fmt.Sprintf("%s:user:%s", name, userID)
+}
+
+// unknownAccount counts an attempt against identifiers that match no account
+// towards the IP's lockout. It returns err, or the lockout error once the IP
+// is locked out.
+func (app *application) unknownAccount(c echo.Context, name string, err error) error {
+
+	key := fmt.Sprintf("%s:ip:%s", name, c.RealIP())
+	if locked, lockErr := app.checkLockout(c, key); locked {
+		return lockErr
+	}
+
+	app.recordFailure(c, key)
+
+	return err
+}
+
+// checkLockout returns a 429 error when the key is locked out, in which case
+// the caller must return it straight away.
+func (app *application) checkLockout(c echo.Context, key string) (bool, error) {
+
+	if !app.config.RateLimit.Enabled {
+		return false, nil
+	}
+
+	remaining, err := app.limiter.Locked(c.Request().Context(), key)
+	if err != nil {
+		slog.Error("failed to check lockout", "error", err.Error())
+		return false, nil
+	}
+
+	if remaining > 0 {
+		return true, tooManyRequests(c, remaining)
+	}
+
+	return false, nil
+}
+
+func (app *application) recordFailure(c echo.Context, key string) {
+
+	if !app.config.RateLimit.Enabled {
+		return
+	}
+
+	lockout, err := app.limiter.Fail(c.Request().Context(), key)
+	if err != nil {
+		slog.Error("failed to record failed attempt", "error", err.Error())
+		return
+	}
+
+	if lockout > 0 {
+		slog.Warn("locking out after repeated failures", "key", key, "lockout", lockout.String())
+	}
+}
+
+func (app *application) resetFailures(c echo.Context, key string) {
+
+	if !app.config.RateLimit.Enabled {
+		return
+	}
+
+	err := app.limiter.Reset(c.Request().Context(), key)
+	if err != nil {
+		slog.Error("failed to reset failed attempts", "error", err.Error())
+	}
+}
+
+// runLimiterPruner deletes ended request windows and forgotten failures so
+// the rate limit tables don't grow with every IP seen.
+func (app *application) runLimiterPruner(ctx context.Context) {
+
+	ticker := time.NewTicker(app.config.RateLimit.PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		rows, err := app.limiter.Prune(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("failed to prune rate limits", "error", err.Error())
+			}
+			continue
+		}
+
+		if rows > 0 {
+			slog.Info("pruned rate limits", "rows", rows)
+		}
+	}
+}
+
+func tooManyRequests(c echo.Context, retryAfter time.Duration) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return errTooManyRequests("too many attempts, try again later")
+}
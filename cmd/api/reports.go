@@ -0,0 +1,626 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+	"api.swahilichess.com/internal/standings"
+	"api.swahilichess.com/internal/trf"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const max_trf_size = 1 << 20
+
+// trfDateLayouts are the 042 start dates pairing programs write.
+var trfDateLayouts = []string{"2006/01/02", "2006-01-02", "2006.01.02", "02.01.2006", "02/01/2006"}
+
+var formatNames = map[string]string{
+	formatSwiss:            "Swiss System",
+	formatRoundRobin:       "Round Robin",
+	formatDoubleRoundRobin: "Double Round Robin",
+	formatKnockout:         "Knockout",
+}
+
+var tiebreakLabels = map[string]string{
+	standings.Buchholz:        "BH",
+	standings.BuchholzCut1:    "BH-C1",
+	standings.SonnebornBerger: "SB",
+	standings.DirectEncounter: "DE",
+	standings.Wins:            "WIN",
+	standings.Progressive:     "PS",
+}
+
+// crossRow is a player's line of the crosstable, Cells holds a round each.
+type crossRow struct {
+	Rank      int
+	No        int
+	Name      string
+	Rating    int
+	Cells     []string
+	Points    string
+	Tiebreaks []string
+}
+
+// report is a tournament laid out for the exports, players are numbered by
+// seed and the rows are in ranking order.
+type report struct {
+	Tournament db.Tournament
+	Format     string
+	Rounds     []int
+	Tiebreaks  []string
+	Rows       []crossRow
+	trf        trf.Tournament
+}
+
+// exportTournamentHandler renders the tournament as a FIDE TRF-16 report, a
+// crosstable CSV or a printable HTML crosstable. Tiebreak games of knockout
+// matches aren't part of any of them.
+func (app *application) exportTournamentHandler(c echo.Context) error {
+
+	tournament, err := app.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	format := c.Param("format")
+	if format != "trf" && format != "csv" && format != "html" {
+		return errBadRequest("invalid_format", "export format must be trf, csv or html")
+	}
+
+	r, err := app.tournamentReport(c.Request().Context(), tournament)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	switch format {
+	case "trf":
+		err = trf.Write(&buf, r.trf)
+	case "csv":
+		err = writeCrosstableCSV(&buf, r)
+	case "html":
+		err = crosstableTemplate.Execute(&buf, r)
+	}
+	if err != nil {
+		return errInternal("failed to export tournament", err)
+	}
+
+	switch format {
+	case "trf":
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="tournament-%d.trf"`, tournament.ID))
+		return c.Blob(http.StatusOK, "text/plain; charset=utf-8", buf.Bytes())
+	case "csv":
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="tournament-%d.csv"`, tournament.ID))
+		return c.Blob(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	default:
+		return c.HTMLBlob(http.StatusOK, buf.Bytes())
+	}
+
+}
+
+// importTournamentHandler creates a finished or running tournament from the
+// TRF report of a pairing program. Players come in as guests numbered by
+// their starting rank, the report doesn't carry the time control or format
+// the API needs so they are form fields next to the trf file.
+func (app *application) importTournamentHandler(c echo.Context) error {
+
+	timeControl := c.FormValue("time_control")
+	if _, ok := timeControlVariant(timeControl); !ok {
+		return errBadRequest("invalid_time_control", "time control must be minutes+increment, e.g. 10+5")
+	}
+
+	format := c.FormValue("format")
+	if format == "" {
+		format = formatSwiss
+	}
+	if _, ok := formatNames[format]; !ok {
+		return errBadRequest("invalid_format", "format must be swiss, round_robin, double_round_robin or knockout")
+	}
+
+	file, err := c.FormFile("trf")
+	if err != nil {
+		switch {
+		case errors.Is(err, http.ErrMissingFile):
+			return errBadRequest("missing_file", "trf file is required")
+		case strings.Contains(strings.ToLower(err.Error()), "too large"):
+			return errPayloadTooLarge()
+		default:
+			return errInternal("failed processing file upload", err)
+		}
+	}
+
+	if file.Size > max_trf_size {
+		return errPayloadTooLarge()
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return errInternal("failed to open uploaded file", err)
+	}
+	defer src.Close()
+
+	t, err := trf.Parse(io.LimitReader(src, max_trf_size))
+	if err != nil {
+		return errBadRequest("invalid_trf", err.Error())
+	}
+
+	switch {
+	case len([]rune(t.Name)) < 3 || len([]rune(t.Name)) > 100:
+		return errBadRequest("invalid_trf", "tournament name (012) must be 3 to 100 characters")
+	case len(t.Players) == 0:
+		return errBadRequest("invalid_trf", "report has no players")
+	case t.Rounds > 30:
+		return errBadRequest("invalid_trf", "report has more than 30 rounds")
+	}
+
+	sort.Slice(t.Players, func(i, j int) bool {
+		return t.Players[i].StartRank < t.Players[j].StartRank
+	})
+
+	games, err := importGames(t)
+	if err != nil {
+		return errBadRequest("invalid_trf", err.Error())
+	}
+
+	startsAt := time.Now()
+	for _, layout := range trfDateLayouts {
+		if date, err := time.Parse(layout, t.StartDate); err == nil {
+			startsAt = date
+			break
+		}
+	}
+
+	players := make([]db.CreateTournamentPlayerParams, len(t.Players))
+	for i, p := range t.Players {
+		players[i] = db.CreateTournamentPlayerParams{Name: p.Name, Rating: int32(p.Rating)}
+	}
+
+	status := db.TournamentScheduled
+	if len(games) > 0 {
+		status = db.TournamentFinished
+		last := 0
+		for _, g := range games {
+			last = max(last, int(g.Round))
+			if g.Result == db.ResultPending {
+				status = db.TournamentOngoing
+			}
+		}
+		if last < t.Rounds {
+			status = db.TournamentOngoing
+		}
+	}
+
+	user := app.contextGetUser(c)
+
+	tournament, err := app.store.ImportTournamentTx(c.Request().Context(), db.ImportTournamentParams{
+		Tournament: db.CreateTournamentParams{
+			Name:                 t.Name,
+			Format:               format,
+			TimeControl:          timeControl,
+			Rounds:               int32(t.Rounds),
+			Venue:                t.City,
+			StartsAt:             startsAt,
+			RegistrationOpensAt:  startsAt.Add(-24 * time.Hour),
+			RegistrationClosesAt: startsAt,
+			CreatedBy:            uuid.NullUUID{UUID: user.ID, Valid: true},
+			Tiebreaks:            []string{},
+		},
+		Players: players,
+		Games:   games,
+		Status:  status,
+	})
+	if err != nil {
+		return errInternal("failed to import tournament", err)
+	}
+
+	return c.JSON(http.StatusCreated, tournament)
+
+}
+
+// tournamentReport numbers the players by seed and lays out their regular
+// games round by round, with the standings after the last reported round.
+func (app *application) tournamentReport(ctx context.Context, tournament db.Tournament) (report, error) {
+
+	players, err := app.store.GetTournamentPlayers(ctx, tournament.ID)
+	if err != nil {
+		return report{}, errInternal("failed to get tournament players", err)
+	}
+
+	games, err := app.store.GetTournamentGames(ctx, tournament.ID)
+	if err != nil {
+		return report{}, errInternal("failed to get tournament games", err)
+	}
+
+	tiebreaks := tournament.Tiebreaks
+	if len(tiebreaks) == 0 {
+		tiebreaks = defaultTiebreaks(tournament.Format)
+	}
+
+	entries, err := computeStandings(players, games, reportedRound(games), tiebreaks)
+	if err != nil {
+		return report{}, errInternal("failed to compute standings", err)
+	}
+
+	rounds := 0
+	for _, g := range games {
+		if g.Kind == db.GameRegular {
+			rounds = max(rounds, int(g.Round))
+		}
+	}
+
+	seeded := seedOrder(players)
+	numbers := make(map[int64]int, len(seeded))
+	list := make([]trf.Player, len(seeded))
+
+	for i, p := range seeded {
+		numbers[p.ID] = i + 1
+		list[i] = trf.Player{
+			StartRank: i + 1,
+			Name:      p.Name,
+			Rating:    int(p.Rating),
+			Results:   make([]trf.Result, rounds),
+		}
+		for r := range list[i].Results {
+			list[i].Results[r] = trf.Result{Color: trf.NoColor, Code: trf.ZeroBye}
+		}
+	}
+
+	for _, g := range games {
+		if g.Kind != db.GameRegular {
+			continue
+		}
+		white := numbers[g.WhiteID]
+		if white == 0 {
+			continue
+		}
+		if !g.BlackID.Valid {
+			list[white-1].Results[g.Round-1] = trf.Result{Color: trf.NoColor, Code: trfByeCode(g.Result)}
+			continue
+		}
+		black := numbers[g.BlackID.Int64]
+		if black == 0 {
+			continue
+		}
+		whiteCode, blackCode := trfCodes(g.Result)
+		list[white-1].Results[g.Round-1] = trf.Result{Opponent: black, Color: trf.WhiteColor, Code: whiteCode}
+		list[black-1].Results[g.Round-1] = trf.Result{Opponent: white, Color: trf.BlackColor, Code: blackCode}
+	}
+
+	r := report{
+		Tournament: tournament,
+		Format:     formatNames[tournament.Format],
+		Rounds:     make([]int, rounds),
+		Tiebreaks:  make([]string, len(tiebreaks)),
+		Rows:       make([]crossRow, 0, len(entries)),
+	}
+
+	for i := range r.Rounds {
+		r.Rounds[i] = i + 1
+	}
+
+	for i, name := range tiebreaks {
+		r.Tiebreaks[i] = tiebreakLabels[name]
+	}
+
+	for _, e := range entries {
+
+		p := &list[numbers[e.ID]-1]
+		p.Points = e.Points
+		p.Rank = e.Rank
+
+		row := crossRow{
+			Rank:      e.Rank,
+			No:        p.StartRank,
+			Name:      p.Name,
+			Rating:    p.Rating,
+			Cells:     make([]string, len(p.Results)),
+			Points:    formatPoints(e.Points),
+			Tiebreaks: make([]string, len(e.Tiebreaks)),
+		}
+		for i, res := range p.Results {
+			row.Cells[i] = crossCell(res)
+		}
+		for i, v := range e.Tiebreaks {
+			row.Tiebreaks[i] = formatPoints(v)
+		}
+
+		r.Rows = append(r.Rows, row)
+	}
+
+	r.trf = trf.Tournament{
+		Name:        tournament.Name,
+		City:        tournament.Venue,
+		StartDate:   tournament.StartsAt.Format("2006/01/02"),
+		Type:        r.Format,
+		TimeControl: tournament.TimeControl,
+		Rounds:      max(int(tournament.Rounds), rounds),
+		Players:     list,
+	}
+
+	return r, nil
+}
+
+func writeCrosstableCSV(w io.Writer, r report) error {
+
+	cw := csv.NewWriter(w)
+
+	header := []string{"Rank", "No", "Name", "Rating"}
+	for _, round := range r.Rounds {
+		header = append(header, "R"+strconv.Itoa(round))
+	}
+	header = append(header, "Points")
+	header = append(header, r.Tiebreaks...)
+
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range r.Rows {
+		record := []string{strconv.Itoa(row.Rank), strconv.Itoa(row.No), csvText(row.Name), strconv.Itoa(row.Rating)}
+		record = append(record, row.Cells...)
+		record = append(record, row.Points)
+		record = append(record, row.Tiebreaks...)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvText keeps spreadsheets from running a player's name as a formula, names
+// starting with =, +, -, @, a tab or a carriage return get a leading quote.
+// The other cells are numbers and results the API writes itself.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// importGames pairs up the players' results into games, board numbers follow
+// the higher seeded player of each game with byes last. Both players of a
+// game must report it the same way. The players are sorted by starting rank,
+// games refer to them by index.
+func importGames(t trf.Tournament) ([]db.ImportGame, error) {
+
+	index := make(map[int]int, len(t.Players))
+	for i, p := range t.Players {
+		index[p.StartRank] = i
+	}
+
+	type seat struct {
+		game db.ImportGame
+		top  int
+		bye  bool
+	}
+
+	rounds := make([][]seat, t.Rounds)
+
+	for _, p := range t.Players {
+		for r, res := range p.Results {
+
+			if res.Opponent == 0 {
+				result := ""
+				switch res.Code {
+				case trf.PairingBye, trf.FullBye, trf.ForfeitWin:
+					result = db.ResultBye
+				case trf.HalfBye:
+					result = db.ResultHalfBye
+				case trf.ZeroBye:
+					result = db.ResultZeroBye
+				default:
+					continue
+				}
+				rounds[r] = append(rounds[r], seat{
+					game: db.ImportGame{Round: int32(r + 1), White: index[p.StartRank], Black: -1, Result: result},
+					top:  p.StartRank,
+					bye:  true,
+				})
+				continue
+			}
+
+			oi, ok := index[res.Opponent]
+			if !ok {
+				return nil, fmt.Errorf("round %d: player %d meets unknown player %d", r+1, p.StartRank, res.Opponent)
+			}
+
+			o := t.Players[oi]
+			if len(o.Results) <= r || o.Results[r].Opponent != p.StartRank {
+				return nil, fmt.Errorf("round %d: player %d meets %d who doesn't meet them", r+1, p.StartRank, res.Opponent)
+			}
+			if p.StartRank > o.StartRank {
+				continue
+			}
+
+			white, black := p, o
+			whiteRes, blackRes := res, o.Results[r]
+			if res.Color == trf.BlackColor || (res.Color == trf.NoColor && o.Results[r].Color == trf.WhiteColor) {
+				white, black = o, p
+				whiteRes, blackRes = blackRes, whiteRes
+			}
+
+			result, ok := importResult(whiteRes.Code, blackRes.Code)
+			if !ok {
+				return nil, fmt.Errorf("round %d: players %d and %d report different results", r+1, p.StartRank, o.StartRank)
+			}
+
+			rounds[r] = append(rounds[r], seat{
+				game: db.ImportGame{
+					Round:  int32(r + 1),
+					White:  index[white.StartRank],
+					Black:  index[black.StartRank],
+					Result: result,
+				},
+				top: p.StartRank,
+			})
+		}
+	}
+
+	var games []db.ImportGame
+
+	for _, seats := range rounds {
+		sort.Slice(seats, func(i, j int) bool {
+			if seats[i].bye != seats[j].bye {
+				return !seats[i].bye
+			}
+			return seats[i].top < seats[j].top
+		})
+		for board, s := range seats {
+			s.game.Board = int32(board + 1)
+			games = append(games, s.game)
+		}
+	}
+
+	return games, nil
+}
+
+// importResult is the game result of the codes white and black report.
+func importResult(white rune, black rune) (string, bool) {
+
+	normalize := func(code rune) rune {
+		switch code {
+		case trf.UnratedWin:
+			return trf.Win
+		case trf.UnratedDraw:
+			return trf.Draw
+		case trf.UnratedLoss:
+			return trf.Loss
+		default:
+			return code
+		}
+	}
+
+	switch string([]rune{normalize(white), normalize(black)}) {
+	case "10":
+		return db.ResultWhiteWins, true
+	case "01":
+		return db.ResultBlackWins, true
+	case "==":
+		return db.ResultDraw, true
+	case "+-":
+		return db.ResultWhiteForfeit, true
+	case "-+":
+		return db.ResultBlackForfeit, true
+	case "--":
+		return db.ResultDoubleForfeit, true
+	case "  ":
+		return db.ResultPending, true
+	default:
+		return "", false
+	}
+}
+
+// trfCodes are the result codes of white and black for a game result.
+func trfCodes(result string) (rune, rune) {
+	switch result {
+	case db.ResultWhiteWins:
+		return trf.Win, trf.Loss
+	case db.ResultBlackWins:
+		return trf.Loss, trf.Win
+	case db.ResultDraw:
+		return trf.Draw, trf.Draw
+	case db.ResultWhiteForfeit:
+		return trf.ForfeitWin, trf.ForfeitLoss
+	case db.ResultBlackForfeit:
+		return trf.ForfeitLoss, trf.ForfeitWin
+	case db.ResultDoubleForfeit:
+		return trf.ForfeitLoss, trf.ForfeitLoss
+	default:
+		return trf.NotPlayedYet, trf.NotPlayedYet
+	}
+}
+
+func trfByeCode(result string) rune {
+	switch result {
+	case db.ResultBye:
+		return trf.PairingBye
+	case db.ResultHalfBye:
+		return trf.HalfBye
+	case db.ResultZeroBye:
+		return trf.ZeroBye
+	default:
+		return trf.NotPlayedYet
+	}
+}
+
+// crossCell writes a round as the opponent's number, the color and the
+// score, 5w1 or 12b½. Byes are +, ½ or - and a game not reported yet has no
+// score.
+func crossCell(r trf.Result) string {
+
+	var score string
+	switch r.Code {
+	case trf.Win, trf.UnratedWin:
+		score = "1"
+	case trf.Loss, trf.UnratedLoss:
+		score = "0"
+	case trf.Draw, trf.UnratedDraw, trf.HalfBye:
+		score = "½"
+	case trf.ForfeitWin, trf.PairingBye, trf.FullBye:
+		score = "+"
+	case trf.ForfeitLoss, trf.ZeroBye:
+		score = "-"
+	}
+
+	if r.Opponent == 0 {
+		return score
+	}
+
+	return strconv.Itoa(r.Opponent) + string(r.Color) + score
+}
+
+func formatPoints(points float64) string {
+	return strconv.FormatFloat(points, 'f', -1, 64)
+}
+
+var crosstableTemplate = template.Must(template.New("crosstable").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Tournament.Name}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+h1 { font-size: 1.4em; margin-bottom: 0.2em; }
+p { color: #555; margin-top: 0; }
+table { border-collapse: collapse; font-size: 0.9em; }
+th, td { border: 1px solid #999; padding: 0.25em 0.5em; text-align: center; }
+td.name { text-align: left; white-space: nowrap; }
+th { background: #eee; }
+tbody tr:nth-child(even) { background: #f7f7f7; }
+@media print {
+	body { margin: 0; }
+	table { font-size: 8pt; }
+	th, tbody tr:nth-child(even) { -webkit-print-color-adjust: exact; print-color-adjust: exact; }
+	@page { size: landscape; margin: 1cm; }
+}
+</style>
+</head>
+<body>
+<h1>{{.Tournament.Name}}</h1>
+<p>{{.Format}} · {{.Tournament.TimeControl}}{{with .Tournament.Venue}} · {{.}}{{end}} · {{.Tournament.StartsAt.Format "2 January 2006"}}</p>
+<table>
+<thead>
+<tr><th>Rank</th><th>No</th><th>Name</th><th>Rating</th>{{range .Rounds}}<th>R{{.}}</th>{{end}}<th>Pts</th>{{range .Tiebreaks}}<th>{{.}}</th>{{end}}</tr>
+</thead>
+<tbody>
+{{range .Rows}}<tr><td>{{.Rank}}</td><td>{{.No}}</td><td class="name">{{.Name}}</td><td>{{if .Rating}}{{.Rating}}{{end}}</td>{{range .Cells}}<td>{{.}}</td>{{end}}<td><strong>{{.Points}}</strong></td>{{range .Tiebreaks}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+</body>
+</html>
+`))
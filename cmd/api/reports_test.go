@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+	"api.swahilichess.com/internal/trf"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// parseReport reads the trf report, the fixture is shared with the trf
+// package.
+func parseReport(t *testing.T, report string) trf.Tournament {
+
+	t.Helper()
+
+	tournament, err := trf.Parse(strings.NewReader(report))
+	if err != nil {
+		t.Fatalf("trf.Parse() error = %v", err)
+	}
+
+	return tournament
+}
+
+func readFixture(t *testing.T) string {
+
+	t.Helper()
+
+	data, err := os.ReadFile("../../internal/trf/testdata/swiss.trf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestImportGames(t *testing.T) {
+
+	games, err := importGames(parseReport(t, readFixture(t)))
+	if err != nil {
+		t.Fatalf("importGames() error = %v", err)
+	}
+
+	// players are indexes, starting rank 1 is 0. Byes take the last boards.
+	want := []db.ImportGame{
+		{Round: 1, Board: 1, White: 0, Black: 4, Result: db.ResultWhiteWins},
+		{Round: 1, Board: 2, White: 5, Black: 1, Result: db.ResultBlackWins},
+		{Round: 1, Board: 3, White: 2, Black: 6, Result: db.ResultDraw},
+		{Round: 1, Board: 4, White: 3, Black: -1, Result: db.ResultBye},
+		{Round: 2, Board: 1, White: 1, Black: 0, Result: db.ResultDraw},
+		{Round: 2, Board: 2, White: 3, Black: 2, Result: db.ResultWhiteForfeit},
+		{Round: 2, Board: 3, White: 4, Black: 5, Result: db.ResultWhiteWins},
+		{Round: 2, Board: 4, White: 6, Black: -1, Result: db.ResultHalfBye},
+		{Round: 3, Board: 1, White: 0, Black: 3, Result: db.ResultWhiteWins},
+		{Round: 3, Board: 2, White: 2, Black: 1, Result: db.ResultBlackWins},
+		{Round: 3, Board: 3, White: 5, Black: 6, Result: db.ResultDraw},
+		{Round: 3, Board: 4, White: 4, Black: -1, Result: db.ResultZeroBye},
+	}
+
+	if fmt.Sprint(games) != fmt.Sprint(want) {
+		t.Errorf("importGames() =\n%v\nwant\n%v", games, want)
+	}
+}
+
+func TestImportGamesErrors(t *testing.T) {
+
+	fixture := readFixture(t)
+
+	player := func(rank int, results ...trf.Result) trf.Player {
+		return trf.Player{StartRank: rank, Name: fmt.Sprintf("Player %d", rank), Results: results}
+	}
+
+	tests := []struct {
+		name       string
+		tournament trf.Tournament
+		wantErr    string
+	}{
+		{
+			// player 1 reports a win over 5 who reports a draw
+			name:       "mismatched results",
+			tournament: parseReport(t, strings.Replace(fixture, "   1 b 0     6 w 1", "   1 b =     6 w 1", 1)),
+			wantErr:    "round 1: players 1 and 5 report different results",
+		},
+		{
+			name:       "forfeit against a played game",
+			tournament: parseReport(t, strings.Replace(fixture, "   4 b -", "   4 b 0", 1)),
+			wantErr:    "round 2: players 3 and 4 report different results",
+		},
+		{
+			// player 6 has a round 2 game against 5 who sits out
+			name:       "opponent doesn't meet them",
+			tournament: parseReport(t, strings.Replace(fixture, "   1 b 0     6 w 1", "   1 b 0  0000 - H", 1)),
+			wantErr:    "round 2: player 6 meets 5 who doesn't meet them",
+		},
+		{
+			name: "unknown opponent",
+			tournament: trf.Tournament{Rounds: 1, Players: []trf.Player{
+				player(1, trf.Result{Opponent: 3, Color: trf.WhiteColor, Code: trf.Win}),
+				player(2, trf.Result{Color: trf.NoColor, Code: trf.PairingBye}),
+			}},
+			wantErr: "round 1: player 1 meets unknown player 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := importGames(tt.tournament)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("importGames() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestImportGamesColors(t *testing.T) {
+
+	player := func(rank int, results ...trf.Result) trf.Player {
+		return trf.Player{StartRank: rank, Name: fmt.Sprintf("Player %d", rank), Results: results}
+	}
+
+	// reports without colors give white to the higher seed unless the
+	// opponent says they had white, unplayed and absent rounds leave no game
+	games, err := importGames(trf.Tournament{Rounds: 3, Players: []trf.Player{
+		player(1,
+			trf.Result{Opponent: 2, Color: trf.NoColor, Code: trf.Win},
+			trf.Result{Opponent: 2, Color: trf.NoColor, Code: trf.Draw},
+			trf.Result{Opponent: 2, Color: trf.BlackColor, Code: trf.NotPlayedYet},
+		),
+		player(2,
+			trf.Result{Opponent: 1, Color: trf.NoColor, Code: trf.UnratedLoss},
+			trf.Result{Opponent: 1, Color: trf.WhiteColor, Code: trf.Draw},
+			trf.Result{Opponent: 1, Color: trf.WhiteColor, Code: trf.NotPlayedYet},
+		),
+		player(3,
+			trf.Result{Color: trf.NoColor, Code: trf.ForfeitLoss},
+			trf.Result{Color: trf.NoColor, Code: trf.ForfeitWin},
+			trf.Result{Color: trf.NoColor, Code: trf.NotPlayedYet},
+		),
+	}})
+	if err != nil {
+		t.Fatalf("importGames() error = %v", err)
+	}
+
+	want := []db.ImportGame{
+		{Round: 1, Board: 1, White: 0, Black: 1, Result: db.ResultWhiteWins},
+		{Round: 2, Board: 1, White: 1, Black: 0, Result: db.ResultDraw},
+		{Round: 2, Board: 2, White: 2, Black: -1, Result: db.ResultBye},
+		{Round: 3, Board: 1, White: 1, Black: 0, Result: db.ResultPending},
+	}
+
+	if fmt.Sprint(games) != fmt.Sprint(want) {
+		t.Errorf("importGames() =\n%v\nwant\n%v", games, want)
+	}
+}
+
+func TestImportResult(t *testing.T) {
+
+	tests := []struct {
+		white rune
+		black rune
+		want  string
+		ok    bool
+	}{
+		{trf.Win, trf.Loss, db.ResultWhiteWins, true},
+		{trf.Loss, trf.Win, db.ResultBlackWins, true},
+		{trf.Draw, trf.Draw, db.ResultDraw, true},
+		{trf.UnratedWin, trf.UnratedLoss, db.ResultWhiteWins, true},
+		{trf.UnratedLoss, trf.Win, db.ResultBlackWins, true},
+		{trf.UnratedDraw, trf.Draw, db.ResultDraw, true},
+		{trf.ForfeitWin, trf.ForfeitLoss, db.ResultWhiteForfeit, true},
+		{trf.ForfeitLoss, trf.ForfeitWin, db.ResultBlackForfeit, true},
+		{trf.ForfeitLoss, trf.ForfeitLoss, db.ResultDoubleForfeit, true},
+		{trf.NotPlayedYet, trf.NotPlayedYet, db.ResultPending, true},
+		{trf.Win, trf.Win, "", false},
+		{trf.Win, trf.Draw, "", false},
+		{trf.ForfeitWin, trf.Loss, "", false},
+		{trf.Win, trf.NotPlayedYet, "", false},
+		{trf.HalfBye, trf.HalfBye, "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := importResult(tt.white, tt.black)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("importResult(%q, %q) = %q, %t, want %q, %t", tt.white, tt.black, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// TestExportImport reads the report back from the codes the export writes
+// for every result.
+func TestExportImport(t *testing.T) {
+
+	results := []string{db.ResultWhiteWins, db.ResultBlackWins, db.ResultDraw, db.ResultWhiteForfeit, db.ResultBlackForfeit, db.ResultDoubleForfeit, db.ResultPending}
+
+	for _, result := range results {
+		white, black := trfCodes(result)
+		got, ok := importResult(white, black)
+		if !ok || got != result {
+			t.Errorf("importResult(trfCodes(%q)) = %q, %t", result, got, ok)
+		}
+	}
+
+	for _, result := range []string{db.ResultBye, db.ResultHalfBye, db.ResultZeroBye} {
+
+		games, err := importGames(trf.Tournament{Rounds: 1, Players: []trf.Player{
+			{StartRank: 1, Name: "Player 1", Results: []trf.Result{{Color: trf.NoColor, Code: trfByeCode(result)}}},
+		}})
+		if err != nil {
+			t.Fatalf("importGames() error = %v", err)
+		}
+
+		if len(games) != 1 || games[0].Result != result {
+			t.Errorf("importGames() of trfByeCode(%q) = %v", result, games)
+		}
+	}
+}
+
+func TestWriteCrosstableCSV(t *testing.T) {
+
+	names := []string{
+		"Kimaro, Baraka",
+		"=HYPERLINK(\"http://evil.example\",\"x\")",
+		"+255 Mushi",
+		"-1+1",
+		"@SUM(A1)",
+		"\t=1+1",
+		"O'Neil-Mwakyusa",
+	}
+
+	r := report{Rounds: []int{1}, Tiebreaks: []string{"BH"}}
+	for i, name := range names {
+		r.Rows = append(r.Rows, crossRow{Rank: i + 1, No: i + 1, Name: name, Cells: []string{"+"}, Points: "1", Tiebreaks: []string{"0"}})
+	}
+
+	var buf strings.Builder
+	if err := writeCrosstableCSV(&buf, r); err != nil {
+		t.Fatalf("writeCrosstableCSV() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"Rank,No,Name,Rating,R1,Points,BH",
+		`1,1,"Kimaro, Baraka",0,+,1,0`,
+		`2,2,"'=HYPERLINK(""http://evil.example"",""x"")",0,+,1,0`,
+		"3,3,'+255 Mushi,0,+,1,0",
+		"4,4,'-1+1,0,+,1,0",
+		"5,5,'@SUM(A1),0,+,1,0",
+		"6,6,'\t=1+1,0,+,1,0",
+		"7,7,O'Neil-Mwakyusa,0,+,1,0",
+		"",
+	}, "\n")
+
+	if buf.String() != want {
+		t.Errorf("writeCrosstableCSV() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+// importStore keeps the imported tournament, the other store methods are not
+// used by the import.
+type importStore struct {
+	db.Store
+
+	imported *db.ImportTournamentParams
+}
+
+func (s *importStore) ImportTournamentTx(ctx context.Context, arg db.ImportTournamentParams) (db.Tournament, error) {
+	s.imported = &arg
+	return db.Tournament{ID: 1, Name: arg.Tournament.Name, Status: arg.Status, CreatedBy: arg.Tournament.CreatedBy}, nil
+}
+
+// postImport sends the form to importTournamentHandler as the user, the trf
+// file is left out when report is nil.
+func postImport(app *application, userID uuid.UUID, fields map[string]string, report []byte) (*httptest.ResponseRecorder, error) {
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+
+	if report != nil {
+		fw, _ := mw.CreateFormFile("trf", "report.trf")
+		fw.Write(report)
+	}
+
+	mw.Close()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", &body)
+	req.Header.Set(echo.HeaderContentType, mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+	c.Set("user", db.GetUserByTokenRow{ID: userID})
+
+	return rec, app.importTournamentHandler(c)
+}
+
+func TestImportTournament(t *testing.T) {
+
+	store := &importStore{}
+	app := &application{store: store}
+
+	userID := uuid.New()
+
+	rec, err := postImport(app, userID, map[string]string{"time_control": "15+10"}, []byte(readFixture(t)))
+	if err != nil {
+		t.Fatalf("importTournamentHandler() error = %v", err)
+	}
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("importTournamentHandler() status = %d, want 201", rec.Code)
+	}
+
+	imported := store.imported
+	if imported == nil {
+		t.Fatal("importTournamentHandler() imported nothing")
+	}
+
+	if by := imported.Tournament.CreatedBy; !by.Valid || by.UUID != userID {
+		t.Errorf("created by = %v, want the importing user %s", by, userID)
+	}
+
+	if imported.Tournament.Name != "Dar es Salaam Rapid Open 2026" || imported.Tournament.Format != formatSwiss || imported.Tournament.Rounds != 3 {
+		t.Errorf("tournament = %+v", imported.Tournament)
+	}
+
+	if got := imported.Tournament.StartsAt.Format("2006-01-02"); got != "2026-03-14" {
+		t.Errorf("starts at = %s, want 2026-03-14", got)
+	}
+
+	if len(imported.Players) != 7 || len(imported.Games) != 12 || imported.Status != db.TournamentFinished {
+		t.Errorf("imported %d players and %d games with status %q, want 7, 12 and finished", len(imported.Players), len(imported.Games), imported.Status)
+	}
+}
+
+func TestImportTournamentErrors(t *testing.T) {
+
+	fixture := []byte(readFixture(t))
+
+	tests := []struct {
+		name     string
+		fields   map[string]string
+		report   []byte
+		wantCode string
+	}{
+		{name: "missing file", fields: map[string]string{"time_control": "15+10"}, wantCode: "missing_file"},
+		{name: "file too large", fields: map[string]string{"time_control": "15+10"}, report: bytes.Repeat([]byte("x"), max_trf_size+1), wantCode: "payload_too_large"},
+		{name: "invalid time control", fields: map[string]string{"time_control": "rapid"}, report: fixture, wantCode: "invalid_time_control"},
+		{name: "invalid format", fields: map[string]string{"time_control": "15+10", "format": "arena"}, report: fixture, wantCode: "invalid_format"},
+		{name: "invalid report", fields: map[string]string{"time_control": "15+10"}, report: []byte("001    x"), wantCode: "invalid_trf"},
+		{name: "no players", fields: map[string]string{"time_control": "15+10"}, report: []byte("012 Kombe la Meya\n"), wantCode: "invalid_trf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			store := &importStore{}
+			app := &application{store: store}
+
+			_, err := postImport(app, uuid.New(), tt.fields, tt.report)
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("importTournamentHandler() error = %v, want %q", err, tt.wantCode)
+			}
+
+			if store.imported != nil {
+				t.Errorf("importTournamentHandler() imported %+v", store.imported.Tournament)
+			}
+		})
+	}
+}
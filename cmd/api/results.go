@@ -0,0 +1,279 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+	"api.swahilichess.com/internal/standings"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type standingView struct {
+	Rank      int        `json:"rank"`
+	Player    gamePlayer `json:"player"`
+	Status    string     `json:"status"`
+	Points    float64    `json:"points"`
+	Played    int        `json:"played"`
+	Wins      int        `json:"wins"`
+	Tiebreaks []float64  `json:"tiebreaks"`
+}
+
+// reportResultHandler lets arbiters enter or correct a game's result, every
+// change lands in the tournament's result changes. Correcting a result once a
+// later round is paired or the tournament is finished takes "override".
+func (app *application) reportResultHandler(c echo.Context) error {
+
+	tournamentID, err := paramID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	gameID, err := paramID(c, "game")
+	if err != nil {
+		return err
+	}
+
+	var input struct {
+		Result   string `json:"result" validate:"required"`
+		Reason   string `json:"reason" validate:"max=500"`
+		Override bool   `json:"override"`
+	}
+
+	if err := c.Bind(&input); err != nil {
+		return errBind(err)
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		return errValidation(err)
+	}
+
+	user := app.contextGetUser(c)
+
+	game, err := app.store.ReportResultTx(c.Request().Context(), db.ReportResultParams{
+		TournamentID: tournamentID,
+		GameID:       gameID,
+		Result:       normalizeResult(input.Result),
+		Reason:       strings.TrimSpace(input.Reason),
+		ChangedBy:    uuid.NullUUID{UUID: user.ID, Valid: true},
+		Override:     input.Override,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return errNotFound("game_not_found", "game not found")
+		case errors.Is(err, db.ErrInvalidResult):
+			return errBadRequest("invalid_result", "result must be 1-0, 0-1, 1/2-1/2, +-, -+ or -- for a game and bye, half-bye or zero-bye for a bye")
+		case errors.Is(err, db.ErrReasonRequired):
+			return errBadRequest("reason_required", "give a reason to correct a reported result")
+		case errors.Is(err, db.ErrResultLocked):
+			return errBadRequest("result_locked", "a later round is paired or the tournament is finished, set override to change the result")
+		case errors.Is(err, db.ErrTournamentClosed):
+			return errBadRequest("tournament_closed", "tournament is cancelled")
+		default:
+			return errInternal("failed to report result", err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, game)
+
+}
+
+func (app *application) listResultChangesHandler(c echo.Context) error {
+
+	tournament, err := app.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	offset, err := strconv.Atoi(c.QueryParam("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	changes, err := app.store.GetResultChanges(c.Request().Context(), db.GetResultChangesParams{
+		TournamentID: tournament.ID,
+		Limit:        int32(limit),
+		Offset:       int32(offset),
+	})
+	if err != nil {
+		return errInternal("failed to get result changes", err)
+	}
+
+	return c.JSON(http.StatusOK, changes)
+
+}
+
+// standingsHandler ranks the players after ?round=, by default the last
+// round with a reported game. The round in progress counts with the results
+// reported so far, tiebreak games of knockout matches don't count.
+func (app *application) standingsHandler(c echo.Context) error {
+
+	tournament, err := app.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	players, err := app.store.GetTournamentPlayers(c.Request().Context(), tournament.ID)
+	if err != nil {
+		return errInternal("failed to get tournament players", err)
+	}
+
+	games, err := app.store.GetTournamentGames(c.Request().Context(), tournament.ID)
+	if err != nil {
+		return errInternal("failed to get tournament games", err)
+	}
+
+	reported := reportedRound(games)
+
+	round := reported
+	if param := c.QueryParam("round"); param != "" {
+		round, err = strconv.Atoi(param)
+		if err != nil || round < 0 {
+			return errBadRequest("invalid_round", "invalid round")
+		}
+		round = min(round, reported)
+	}
+
+	tiebreaks := tournament.Tiebreaks
+	if len(tiebreaks) == 0 {
+		tiebreaks = defaultTiebreaks(tournament.Format)
+	}
+
+	entries, err := computeStandings(players, games, round, tiebreaks)
+	if err != nil {
+		return errInternal("failed to compute standings", err)
+	}
+
+	byID := make(map[int64]db.TournamentPlayer, len(players))
+	for _, p := range players {
+		byID[p.ID] = p
+	}
+
+	views := make([]standingView, len(entries))
+	for i, e := range entries {
+		p := byID[e.ID]
+		views[i] = standingView{
+			Rank:      e.Rank,
+			Player:    gamePlayer{ID: p.ID, Name: p.Name, Rating: p.Rating},
+			Status:    p.Status,
+			Points:    e.Points,
+			Played:    e.Played,
+			Wins:      e.Wins,
+			Tiebreaks: e.Tiebreaks,
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"round":     round,
+		"tiebreaks": tiebreaks,
+		"standings": views,
+	})
+
+}
+
+// computeStandings ranks the players after the round with the reported
+// regular games, remaining ties go to the higher rated player.
+func computeStandings(players []db.TournamentPlayer, games []db.TournamentGame, round int, tiebreaks []string) ([]standings.Entry, error) {
+
+	list := make([]standings.Game, 0, len(games))
+	for _, g := range games {
+		if g.Kind != db.GameRegular || g.Result == db.ResultPending {
+			continue
+		}
+		white, black, played := resultPoints(g.Result)
+		list = append(list, standings.Game{
+			Round:       int(g.Round),
+			White:       g.WhiteID,
+			Black:       g.BlackID.Int64,
+			WhitePoints: white,
+			BlackPoints: black,
+			Played:      played,
+		})
+	}
+
+	seeded := seedOrder(players)
+
+	ids := make([]int64, len(seeded))
+	for i, p := range seeded {
+		ids[i] = p.ID
+	}
+
+	return standings.Compute(ids, round, list, tiebreaks)
+}
+
+// reportedRound is the last round with a reported game, 0 before the first
+// result.
+func reportedRound(games []db.TournamentGame) int {
+	var round int
+	for _, g := range games {
+		if g.Result != db.ResultPending {
+			round = max(round, int(g.Round))
+		}
+	}
+	return round
+}
+
+// seedOrder sorts the players by rating, then by when they registered.
+func seedOrder(players []db.TournamentPlayer) []db.TournamentPlayer {
+	seeded := append([]db.TournamentPlayer{}, players...)
+	sort.SliceStable(seeded, func(i, j int) bool {
+		if seeded[i].Rating != seeded[j].Rating {
+			return seeded[i].Rating > seeded[j].Rating
+		}
+		return seeded[i].ID < seeded[j].ID
+	})
+	return seeded
+}
+
+// defaultTiebreaks apply when the organizers picked none.
+func defaultTiebreaks(format string) []string {
+	switch format {
+	case formatRoundRobin, formatDoubleRoundRobin:
+		return []string{standings.DirectEncounter, standings.SonnebornBerger, standings.Wins}
+	default:
+		return []string{standings.BuchholzCut1, standings.Buchholz, standings.SonnebornBerger, standings.DirectEncounter}
+	}
+}
+
+// resultPoints is what white and black score with the result and whether the
+// game was played over the board.
+func resultPoints(result string) (float64, float64, bool) {
+	switch result {
+	case db.ResultWhiteWins:
+		return 1, 0, true
+	case db.ResultBlackWins:
+		return 0, 1, true
+	case db.ResultDraw:
+		return 0.5, 0.5, true
+	case db.ResultWhiteForfeit, db.ResultBye:
+		return 1, 0, false
+	case db.ResultBlackForfeit:
+		return 0, 1, false
+	case db.ResultHalfBye:
+		return 0.5, 0, false
+	default:
+		return 0, 0, false
+	}
+}
+
+// normalizeResult accepts the usual ways of writing a draw.
+func normalizeResult(result string) string {
+	result = strings.ToLower(strings.TrimSpace(result))
+	switch result {
+	case "½-½", "=", "0.5-0.5", "draw":
+		return db.ResultDraw
+	default:
+		return result
+	}
+}
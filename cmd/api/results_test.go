@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+)
+
+func TestNormalizeResult(t *testing.T) {
+
+	tests := []struct {
+		result string
+		want   string
+	}{
+		{result: "1-0", want: db.ResultWhiteWins},
+		{result: " 0-1 ", want: db.ResultBlackWins},
+		{result: "1/2-1/2", want: db.ResultDraw},
+		{result: "½-½", want: db.ResultDraw},
+		{result: "=", want: db.ResultDraw},
+		{result: "0.5-0.5", want: db.ResultDraw},
+		{result: "Draw", want: db.ResultDraw},
+		{result: "BYE", want: db.ResultBye},
+		{result: "Half-Bye", want: db.ResultHalfBye},
+		{result: "+-", want: db.ResultWhiteForfeit},
+		{result: "1-1", want: "1-1"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeResult(tt.result); got != tt.want {
+			t.Errorf("normalizeResult(%q) = %q, want %q", tt.result, got, tt.want)
+		}
+	}
+}
+
+func TestResultPoints(t *testing.T) {
+
+	tests := []struct {
+		result string
+		white  float64
+		black  float64
+		played bool
+	}{
+		{result: db.ResultWhiteWins, white: 1, black: 0, played: true},
+		{result: db.ResultBlackWins, white: 0, black: 1, played: true},
+		{result: db.ResultDraw, white: 0.5, black: 0.5, played: true},
+		{result: db.ResultWhiteForfeit, white: 1, black: 0},
+		{result: db.ResultBlackForfeit, white: 0, black: 1},
+		{result: db.ResultDoubleForfeit, white: 0, black: 0},
+		{result: db.ResultBye, white: 1, black: 0},
+		{result: db.ResultHalfBye, white: 0.5, black: 0},
+		{result: db.ResultZeroBye, white: 0, black: 0},
+		{result: db.ResultPending, white: 0, black: 0},
+	}
+
+	for _, tt := range tests {
+		white, black, played := resultPoints(tt.result)
+		if white != tt.white || black != tt.black || played != tt.played {
+			t.Errorf("resultPoints(%q) = %v, %v, %t, want %v, %v, %t", tt.result, white, black, played, tt.white, tt.black, tt.played)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+	"api.swahilichess.com/internal/validator"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	rolePlayer          = "player"
+	roleClubAdmin       = "club_admin"
+	roleArbiter         = "arbiter"
+	roleFederationAdmin = "federation_admin"
+)
+
+const (
+	permissionUsersWrite        = "users:write"
+	permissionRolesWrite        = "roles:write"
+	permissionTournamentsWrite  = "tournaments:write"
+	permissionTournamentsManage = "tournaments:manage"
+	permissionResultsWrite      = "results:write"
+)
+
+var validRoles = []string{rolePlayer, roleClubAdmin, roleArbiter, roleFederationAdmin}
+
+func (app *application) getRolesHandler(c echo.Context) error {
+
+	roles, err := app.store.GetRoles(c.Request().Context())
+	if err != nil {
+		return errInternal("failed to get roles", err)
+	}
+
+	return c.JSON(http.StatusOK, roles)
+
+}
+
+func (app *application) getUserRolesHandler(c echo.Context) error {
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return errBadRequest("invalid_id", "invalid uuid")
+	}
+
+	roles, err := app.store.GetUserRoles(c.Request().Context(), id)
+	if err != nil {
+		return errInternal("failed to get user roles", err)
+	}
+
+	return c.JSON(http.StatusOK, roles)
+
+}
+
+func (app *application) grantUserRoleHandler(c echo.Context) error {
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return errBadRequest("invalid_id", "invalid uuid")
+	}
+
+	var input struct {
+		Role string `json:"role" validate:"required"`
+	}
+
+	if err := c.Bind(&input); err != nil {
+		return errBind(err)
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		return errValidation(err)
+	}
+
+	if !validator.In(input.Role, validRoles...) {
+		return errBadRequest("unknown_role", "unknown role")
+	}
+
+	_, err = app.store.GetUserById(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return errNotFound("user_not_found", "user not found")
+		default:
+			return errInternal("failed to get user by id", err)
+		}
+	}
+
+	admin := app.contextGetUser(c)
+
+	args := db.AddUserRoleParams{
+		UserID:    id,
+		Role:      input.Role,
+		GrantedBy: uuid.NullUUID{UUID: admin.ID, Valid: true},
+	}
+
+	err = app.store.AddUserRole(c.Request().Context(), args)
+	if err != nil {
+		return errInternal("failed to grant user role", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"success": "role granted"})
+
+}
+
+func (app *application) revokeUserRoleHandler(c echo.Context) error {
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return errBadRequest("invalid_id", "invalid uuid")
+	}
+
+	args := db.RemoveUserRoleParams{
+		UserID: id,
+		Role:   c.Param("role"),
+	}
+
+	rows, err := app.store.RemoveUserRole(c.Request().Context(), args)
+	if err != nil {
+		return errInternal("failed to revoke user role", err)
+	}
+
+	if rows == 0 {
+		return errNotFound("role_not_found", "user doesn't have this role")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"success": "role revoked"})
+
+}
+
+// grantFederationAdmin makes an existing user a federation admin, it bootstraps
+// the first admin who then grants roles through the API.
+func grantFederationAdmin(ctx context.Context, store db.Store, username string) error {
+
+	user, err := store.GetUserByUsername(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	return store.AddUserRole(ctx, db.AddUserRoleParams{UserID: user.ID, Role: roleFederationAdmin})
+}
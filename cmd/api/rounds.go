@@ -0,0 +1,550 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+	"api.swahilichess.com/internal/pairing"
+	"github.com/labstack/echo/v4"
+)
+
+type gamePlayer struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Rating int32  `json:"rating"`
+}
+
+// gameView is a game with its players, Black is null for a bye.
+type gameView struct {
+	ID     int64       `json:"id"`
+	Board  int32       `json:"board"`
+	Game   int32       `json:"game"`
+	Kind   string      `json:"kind"`
+	White  gamePlayer  `json:"white"`
+	Black  *gamePlayer `json:"black"`
+	Result string      `json:"result"`
+}
+
+type roundView struct {
+	Round int32      `json:"round"`
+	Games []gameView `json:"games"`
+}
+
+func (app *application) listRoundsHandler(c echo.Context) error {
+
+	tournament, err := app.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	players, err := app.store.GetTournamentPlayers(c.Request().Context(), tournament.ID)
+	if err != nil {
+		return errInternal("failed to get tournament players", err)
+	}
+
+	games, err := app.store.GetTournamentGames(c.Request().Context(), tournament.ID)
+	if err != nil {
+		return errInternal("failed to get tournament games", err)
+	}
+
+	return c.JSON(http.StatusOK, roundViews(players, games))
+
+}
+
+func (app *application) getRoundHandler(c echo.Context) error {
+
+	tournament, err := app.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	round, err := paramID(c, "round")
+	if err != nil {
+		return err
+	}
+
+	games, err := app.store.GetRoundGames(c.Request().Context(), db.GetRoundGamesParams{
+		TournamentID: tournament.ID,
+		Round:        int32(round),
+	})
+	if err != nil {
+		return errInternal("failed to get round games", err)
+	}
+
+	if len(games) == 0 {
+		return errNotFound("round_not_found", "round not found")
+	}
+
+	players, err := app.store.GetTournamentPlayers(c.Request().Context(), tournament.ID)
+	if err != nil {
+		return errInternal("failed to get tournament players", err)
+	}
+
+	return c.JSON(http.StatusOK, roundViews(players, games)[0])
+
+}
+
+// pairRoundHandler pairs the next round from the registered players and the
+// games played so far, the previous round must be fully reported. Swiss
+// tournaments are paired round by round with the Dutch system, round-robins
+// get their whole Berger schedule at once and knockouts pair the winners of
+// the previous round's matches. It returns the rounds created.
+func (app *application) pairRoundHandler(c echo.Context) error {
+
+	tournament, err := app.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	err = app.requireOrganizer(c, tournament)
+	if err != nil {
+		return err
+	}
+
+	if tournament.Status == db.TournamentFinished || tournament.Status == db.TournamentCancelled {
+		return errBadRequest("tournament_closed", "tournament is over")
+	}
+
+	players, err := app.store.GetTournamentPlayers(c.Request().Context(), tournament.ID)
+	if err != nil {
+		return errInternal("failed to get tournament players", err)
+	}
+
+	games, err := app.store.GetTournamentGames(c.Request().Context(), tournament.ID)
+	if err != nil {
+		return errInternal("failed to get tournament games", err)
+	}
+
+	var last int32
+	for _, g := range games {
+		if g.Result == db.ResultPending {
+			return errBadRequest("round_in_progress", "every game of the previous round must be reported")
+		}
+		last = max(last, g.Round)
+	}
+
+	var args db.CreateRoundParams
+
+	switch tournament.Format {
+	case formatSwiss:
+		args, err = swissRound(tournament, players, games, last)
+	case formatRoundRobin, formatDoubleRoundRobin:
+		args, err = roundRobinSchedule(tournament, players, last)
+	case formatKnockout:
+		args, err = knockoutRound(tournament, players, games, last)
+	default:
+		err = errBadRequest("unsupported_format", "tournament format can't be paired")
+	}
+	if err != nil {
+		return err
+	}
+
+	created, err := app.store.CreateRoundTx(c.Request().Context(), args)
+	if err != nil {
+		return roundError(err, "failed to create round")
+	}
+
+	return c.JSON(http.StatusCreated, roundViews(players, created))
+
+}
+
+// deleteRoundHandler drops the last round while none of its games are
+// reported.
+func (app *application) deleteRoundHandler(c echo.Context) error {
+
+	tournament, err := app.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	err = app.requireOrganizer(c, tournament)
+	if err != nil {
+		return err
+	}
+
+	round, err := paramID(c, "round")
+	if err != nil {
+		return err
+	}
+
+	err = app.store.DeleteRoundTx(c.Request().Context(), db.DeleteRoundGamesParams{
+		TournamentID: tournament.ID,
+		Round:        int32(round),
+	})
+	if err != nil {
+		return roundError(err, "failed to delete round")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"success": "round deleted"})
+
+}
+
+// addMatchGameHandler adds a tiebreak or armageddon game to a tied knockout
+// match once its games are reported. Colors are reversed from the match's
+// last game unless the organizer picks white.
+func (app *application) addMatchGameHandler(c echo.Context) error {
+
+	tournament, err := app.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	err = app.requireOrganizer(c, tournament)
+	if err != nil {
+		return err
+	}
+
+	if tournament.Format != formatKnockout {
+		return errBadRequest("unsupported_format", "only knockout matches have tiebreak games")
+	}
+
+	round, err := paramID(c, "round")
+	if err != nil {
+		return err
+	}
+
+	board, err := paramID(c, "board")
+	if err != nil {
+		return err
+	}
+
+	var input struct {
+		Kind  string `json:"kind" validate:"required,oneof=tiebreak armageddon"`
+		White int64  `json:"white"`
+	}
+
+	if err := c.Bind(&input); err != nil {
+		return errBind(err)
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		return errValidation(err)
+	}
+
+	games, err := app.store.GetRoundGames(c.Request().Context(), db.GetRoundGamesParams{
+		TournamentID: tournament.ID,
+		Round:        int32(round),
+	})
+	if err != nil {
+		return errInternal("failed to get round games", err)
+	}
+
+	var match []db.TournamentGame
+	for _, g := range games {
+		if g.Board == int32(board) {
+			match = append(match, g)
+		}
+	}
+
+	if len(match) == 0 {
+		return errNotFound("match_not_found", "match not found")
+	}
+
+	for _, g := range match {
+		if g.Result == db.ResultPending {
+			return errBadRequest("match_in_progress", "every game of the match must be reported")
+		}
+	}
+
+	if _, err := pairing.MatchWinner(matchGames(match)); err == nil {
+		return errBadRequest("match_decided", "match is already decided")
+	}
+
+	previous := match[len(match)-1]
+	white, black := previous.BlackID.Int64, previous.WhiteID
+
+	switch input.White {
+	case 0, white:
+	case black:
+		white, black = black, white
+	default:
+		return errBadRequest("invalid_player", "white must be one of the match's players")
+	}
+
+	game, err := app.store.AddMatchGameTx(c.Request().Context(), db.CreateTournamentGameParams{
+		TournamentID: tournament.ID,
+		Round:        int32(round),
+		Board:        int32(board),
+		Kind:         input.Kind,
+		WhiteID:      white,
+		BlackID:      sql.NullInt64{Int64: black, Valid: true},
+		Result:       db.ResultPending,
+	})
+	if err != nil {
+		return roundError(err, "failed to add match game")
+	}
+
+	players, err := app.store.GetTournamentPlayers(c.Request().Context(), tournament.ID)
+	if err != nil {
+		return errInternal("failed to get tournament players", err)
+	}
+
+	return c.JSON(http.StatusCreated, roundViews(players, []db.TournamentGame{game})[0].Games[0])
+
+}
+
+func swissRound(tournament db.Tournament, players []db.TournamentPlayer, games []db.TournamentGame, last int32) (db.CreateRoundParams, error) {
+
+	if last >= tournament.Rounds {
+		return db.CreateRoundParams{}, errBadRequest("all_rounds_paired", "every round is already paired")
+	}
+
+	var entrants []pairing.Entrant
+	for _, p := range players {
+		if p.Status == db.PlayerRegistered {
+			entrants = append(entrants, pairing.Entrant{ID: p.ID, Rating: int(p.Rating)})
+		}
+	}
+
+	round, err := pairing.Swiss(pairing.Players(entrants, pairingGames(games)))
+	if err != nil {
+		return db.CreateRoundParams{}, pairingError(err)
+	}
+
+	return db.CreateRoundParams{
+		TournamentID: tournament.ID,
+		Round:        last + 1,
+		Games:        roundGames(tournament.ID, last+1, round.Pairings, round.Bye),
+	}, nil
+}
+
+// roundRobinSchedule draws every round of a round-robin, seeded by rating.
+func roundRobinSchedule(tournament db.Tournament, players []db.TournamentPlayer, last int32) (db.CreateRoundParams, error) {
+
+	if last > 0 {
+		return db.CreateRoundParams{}, errBadRequest("all_rounds_paired", "the schedule is already drawn")
+	}
+
+	rounds, err := pairing.RoundRobin(seeds(players), tournament.Format == formatDoubleRoundRobin)
+	if err != nil {
+		return db.CreateRoundParams{}, pairingError(err)
+	}
+
+	var games []db.CreateTournamentGameParams
+	for i, round := range rounds {
+		games = append(games, roundGames(tournament.ID, int32(i+1), round.Pairings, round.Bye)...)
+	}
+
+	return db.CreateRoundParams{
+		TournamentID: tournament.ID,
+		Round:        1,
+		Rounds:       int32(len(rounds)),
+		Games:        games,
+	}, nil
+}
+
+// knockoutRound seeds the bracket by rating for the first round, later rounds
+// pair the winners of the previous round's matches.
+func knockoutRound(tournament db.Tournament, players []db.TournamentPlayer, games []db.TournamentGame, last int32) (db.CreateRoundParams, error) {
+
+	if last == 0 {
+		ids := seeds(players)
+
+		pairings, err := pairing.Knockout(ids)
+		if err != nil {
+			return db.CreateRoundParams{}, pairingError(err)
+		}
+
+		return db.CreateRoundParams{
+			TournamentID: tournament.ID,
+			Round:        1,
+			Rounds:       int32(pairing.KnockoutRounds(len(ids))),
+			Games:        roundGames(tournament.ID, 1, pairings, 0),
+		}, nil
+	}
+
+	var winners []int64
+	var match []db.TournamentGame
+
+	for i, g := range games {
+		if g.Round != last {
+			continue
+		}
+
+		match = append(match, g)
+		if i+1 < len(games) && games[i+1].Round == g.Round && games[i+1].Board == g.Board {
+			continue
+		}
+
+		winner, err := pairing.MatchWinner(matchGames(match))
+		if err != nil {
+			return db.CreateRoundParams{}, errBadRequest("match_undecided", fmt.Sprintf("the match on board %d needs a tiebreak game", g.Board))
+		}
+
+		winners = append(winners, winner)
+		match = nil
+	}
+
+	if len(winners) == 1 {
+		return db.CreateRoundParams{}, errBadRequest("all_rounds_paired", "the final is over")
+	}
+
+	pairings, err := pairing.NextKnockoutRound(winners)
+	if err != nil {
+		return db.CreateRoundParams{}, pairingError(err)
+	}
+
+	return db.CreateRoundParams{
+		TournamentID: tournament.ID,
+		Round:        last + 1,
+		Games:        roundGames(tournament.ID, last+1, pairings, 0),
+	}, nil
+}
+
+// seeds lists the registered players by rating, GetTournamentPlayers sorts
+// them that way.
+func seeds(players []db.TournamentPlayer) []int64 {
+
+	var ids []int64
+	for _, p := range players {
+		if p.Status == db.PlayerRegistered {
+			ids = append(ids, p.ID)
+		}
+	}
+
+	return ids
+}
+
+// roundGames numbers the boards of a round. A pairing without black is a bye
+// on its board, a separate bye comes last.
+func roundGames(tournamentID int64, number int32, pairings []pairing.Pairing, bye int64) []db.CreateTournamentGameParams {
+
+	games := make([]db.CreateTournamentGameParams, 0, len(pairings)+1)
+
+	if bye != 0 {
+		pairings = append(pairings, pairing.Pairing{White: bye})
+	}
+
+	for i, p := range pairings {
+		game := db.CreateTournamentGameParams{
+			TournamentID: tournamentID,
+			Round:        number,
+			Board:        int32(i + 1),
+			Game:         1,
+			Kind:         db.GameRegular,
+			WhiteID:      p.White,
+			Result:       db.ResultPending,
+		}
+
+		if p.Black == 0 {
+			game.Result = db.ResultBye
+		} else {
+			game.BlackID = sql.NullInt64{Int64: p.Black, Valid: true}
+		}
+
+		games = append(games, game)
+	}
+
+	return games
+}
+
+func pairingGames(games []db.TournamentGame) []pairing.Game {
+
+	list := make([]pairing.Game, 0, len(games))
+
+	for _, g := range games {
+		white, black, played := resultPoints(g.Result)
+		list = append(list, pairing.Game{
+			Round:       int(g.Round),
+			White:       g.WhiteID,
+			Black:       g.BlackID.Int64,
+			WhitePoints: white,
+			BlackPoints: black,
+			Forfeit:     !played && g.BlackID.Valid,
+			Bye:         g.Result == db.ResultBye,
+		})
+	}
+
+	return list
+}
+
+func matchGames(games []db.TournamentGame) []pairing.MatchGame {
+
+	list := make([]pairing.MatchGame, 0, len(games))
+
+	for _, g := range games {
+		white, black, _ := resultPoints(g.Result)
+		list = append(list, pairing.MatchGame{
+			White:       g.WhiteID,
+			Black:       g.BlackID.Int64,
+			WhitePoints: white,
+			BlackPoints: black,
+			Armageddon:  g.Kind == db.GameArmageddon,
+		})
+	}
+
+	return list
+}
+
+// roundViews groups the games by round, they must be sorted by round.
+func roundViews(players []db.TournamentPlayer, games []db.TournamentGame) []roundView {
+
+	byID := make(map[int64]gamePlayer, len(players))
+	for _, p := range players {
+		byID[p.ID] = gamePlayer{ID: p.ID, Name: p.Name, Rating: p.Rating}
+	}
+
+	rounds := []roundView{}
+
+	for _, g := range games {
+		if len(rounds) == 0 || rounds[len(rounds)-1].Round != g.Round {
+			rounds = append(rounds, roundView{Round: g.Round, Games: []gameView{}})
+		}
+
+		view := gameView{
+			ID:     g.ID,
+			Board:  g.Board,
+			Game:   g.Game,
+			Kind:   g.Kind,
+			White:  byID[g.WhiteID],
+			Result: g.Result,
+		}
+
+		if g.BlackID.Valid {
+			black := byID[g.BlackID.Int64]
+			view.Black = &black
+		}
+
+		r := &rounds[len(rounds)-1]
+		r.Games = append(r.Games, view)
+	}
+
+	return rounds
+}
+
+func pairingError(err error) error {
+	switch {
+	case errors.Is(err, pairing.ErrTooFewPlayers):
+		return errBadRequest("too_few_players", "at least two registered players are needed")
+	case errors.Is(err, pairing.ErrNoPairing):
+		return errBadRequest("no_pairing", "no pairing avoids repeat games and color conflicts, pair this round by hand")
+	default:
+		return errInternal("failed to pair round", err)
+	}
+}
+
+func roundError(err error, message string) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return errNotFound("tournament_not_found", "tournament not found")
+	case errors.Is(err, db.ErrTournamentClosed):
+		return errBadRequest("tournament_closed", "tournament is over")
+	case errors.Is(err, db.ErrRoundInProgress):
+		return errBadRequest("round_in_progress", "every game of the previous round must be reported")
+	case errors.Is(err, db.ErrRoundConflict):
+		return errBadRequest("round_conflict", "the round was paired meanwhile, reload the tournament")
+	case errors.Is(err, db.ErrRoundReported):
+		return errBadRequest("round_reported", "round has reported results")
+	case errors.Is(err, db.ErrNotLastRound):
+		return errBadRequest("not_last_round", "only the last round can be changed")
+	case errors.Is(err, db.ErrMatchNotFound):
+		return errNotFound("match_not_found", "match not found")
+	case errors.Is(err, db.ErrMatchInProgress):
+		return errBadRequest("match_in_progress", "every game of the match must be reported")
+	default:
+		return errInternal(message, err)
+	}
+}
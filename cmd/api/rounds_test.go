@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+	"github.com/google/uuid"
+)
+
+func (s *tournamentStore) GetTournamentPlayers(ctx context.Context, tournamentID int64) ([]db.TournamentPlayer, error) {
+	return s.players, nil
+}
+
+func (s *tournamentStore) GetTournamentGames(ctx context.Context, tournamentID int64) ([]db.TournamentGame, error) {
+	return s.games, nil
+}
+
+func (s *tournamentStore) CreateRoundTx(ctx context.Context, arg db.CreateRoundParams) ([]db.TournamentGame, error) {
+	var created []db.TournamentGame
+	for _, g := range arg.Games {
+		game := db.TournamentGame{ID: int64(len(s.games) + 1), TournamentID: g.TournamentID, Round: g.Round, Board: g.Board, Game: g.Game, Kind: g.Kind, WhiteID: g.WhiteID, BlackID: g.BlackID, Result: g.Result}
+		s.games = append(s.games, game)
+		created = append(created, game)
+	}
+	return created, nil
+}
+
+func (s *tournamentStore) DeleteRoundTx(ctx context.Context, arg db.DeleteRoundGamesParams) error {
+	s.deletedRounds = append(s.deletedRounds, arg.Round)
+	return nil
+}
+
+func (s *tournamentStore) GetRoundGames(ctx context.Context, arg db.GetRoundGamesParams) ([]db.TournamentGame, error) {
+	var games []db.TournamentGame
+	for _, g := range s.games {
+		if g.TournamentID == arg.TournamentID && g.Round == arg.Round {
+			games = append(games, g)
+		}
+	}
+	return games, nil
+}
+
+func (s *tournamentStore) AddMatchGameTx(ctx context.Context, arg db.CreateTournamentGameParams) (db.TournamentGame, error) {
+	game := db.TournamentGame{ID: int64(len(s.games) + 1), TournamentID: arg.TournamentID, Round: arg.Round, Board: arg.Board, Game: 2, Kind: arg.Kind, WhiteID: arg.WhiteID, BlackID: arg.BlackID, Result: arg.Result}
+	s.games = append(s.games, game)
+	return game, nil
+}
+
+func TestRoundsOrganizer(t *testing.T) {
+
+	organizer, other, admin := uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name     string
+		user     uuid.UUID
+		wantCode string
+	}{
+		{name: "organizer", user: organizer},
+		{name: "other organizer", user: other, wantCode: "forbidden"},
+		{name: "tournaments manager", user: admin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			store := newTournamentStore(organizer)
+			store.permissions[admin] = []string{permissionTournamentsWrite, permissionTournamentsManage}
+			store.players = []db.TournamentPlayer{
+				{ID: 1, TournamentID: 1, Name: "Juma", Rating: 1800, Status: db.PlayerRegistered},
+				{ID: 2, TournamentID: 1, Name: "Asha", Rating: 1700, Status: db.PlayerRegistered},
+			}
+
+			app := &application{store: store}
+
+			_, err := serveTournament(app.pairRoundHandler, http.MethodPost, "", tt.user)
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("pairRoundHandler() error = %v, want %q", err, tt.wantCode)
+			}
+
+			_, err = serveTournament(app.deleteRoundHandler, http.MethodDelete, "", tt.user, "round", "1")
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("deleteRoundHandler() error = %v, want %q", err, tt.wantCode)
+			}
+
+			if allowed := tt.wantCode == ""; (len(store.games) == 1) != allowed || (len(store.deletedRounds) == 1) != allowed {
+				t.Errorf("games = %d, deleted rounds = %v after %s", len(store.games), store.deletedRounds, tt.name)
+			}
+		})
+	}
+}
+
+func TestDeleteRoundUnknownTournament(t *testing.T) {
+
+	store := newTournamentStore(uuid.New())
+	store.deleted = true
+
+	app := &application{store: store}
+
+	_, err := serveTournament(app.deleteRoundHandler, http.MethodDelete, "", uuid.New(), "round", "1")
+	if code := errorCode(err); code != "tournament_not_found" {
+		t.Fatalf("deleteRoundHandler() error = %v, want tournament_not_found", err)
+	}
+
+	if len(store.deletedRounds) != 0 {
+		t.Errorf("deleted rounds = %v, want none", store.deletedRounds)
+	}
+}
+
+func TestAddMatchGameOrganizer(t *testing.T) {
+
+	organizer, other, admin := uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name     string
+		user     uuid.UUID
+		wantCode string
+	}{
+		{name: "organizer", user: organizer},
+		{name: "other organizer", user: other, wantCode: "forbidden"},
+		{name: "tournaments manager", user: admin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			store := newTournamentStore(organizer)
+			store.tournament.Format = formatKnockout
+			store.permissions[admin] = []string{permissionTournamentsWrite, permissionTournamentsManage}
+			store.players = []db.TournamentPlayer{
+				{ID: 1, TournamentID: 1, Name: "Juma", Status: db.PlayerRegistered},
+				{ID: 2, TournamentID: 1, Name: "Asha", Status: db.PlayerRegistered},
+			}
+			// the match's only game is drawn
+			store.games = []db.TournamentGame{
+				{ID: 1, TournamentID: 1, Round: 1, Board: 1, Game: 1, Kind: db.GameRegular, WhiteID: 1, BlackID: sql.NullInt64{Int64: 2, Valid: true}, Result: db.ResultDraw},
+			}
+
+			app := &application{store: store, validator: newValidator()}
+
+			_, err := serveTournament(app.addMatchGameHandler, http.MethodPost, `{"kind":"armageddon"}`, tt.user, "round", "1", "board", "1")
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("addMatchGameHandler() error = %v, want %q", err, tt.wantCode)
+			}
+
+			if allowed := tt.wantCode == ""; (len(store.games) == 2) != allowed {
+				t.Errorf("games = %d after %s", len(store.games), tt.name)
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 func (app *application) routes() *echo.Echo {
 
 	e := echo.New()
+	e.HTTPErrorHandler = app.httpErrorHandler
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 
@@ -23,8 +24,17 @@ func (app *application) routes() *echo.Echo {
 	e.Use(middleware.CORSWithConfig(DefaultCORSConfig))
 
 	e.GET("/ping", app.pingHandler)
-	e.POST("/login", app.createAuthTokenHandler)
+	e.POST("/login", app.createAuthTokenHandler, app.rateLimit("login"))
+	e.POST("/tokens/refresh", app.refreshTokenHandler, app.rateLimit("refresh"))
 	e.GET("/lichess/leaderboard", app.leaderboardHandler)
+	e.GET("/lichess/leaderboard/status", app.leaderboardStatusHandler)
+	e.GET("/lichess/leaderboard/movement", app.leaderboardMovementHandler)
+	e.GET("/lichess/leaderboard/deltas", app.leaderboardDeltasHandler)
+	e.GET("/lichess/players/:username/history", app.ratingHistoryHandler)
+	e.GET("/lichess/team/events", app.getLichessMembershipEventsHandler)
+	e.GET("/lichess/oauth/callback", app.lichessCallbackHandler)
+	e.GET("/chesscom/leaderboard", app.chesscomLeaderboardHandler)
+	e.GET("/leaderboard/combined", app.combinedLeaderboardHandler)
 
 	// for chessbot
 	b := e.Group("/bot")
@@ -38,23 +48,65 @@ func (app *application) routes() *echo.Echo {
 
 	b.GET("/lichess/members", app.getLichessTeamMemberHandler)
 	b.POST("/lichess/members", app.insertLichessTeamMemberHandler)
+	b.POST("/lichess/members/sync", app.syncLichessTeamHandler)
 	b.POST("/telegram/bot/users", app.insertTgUserHandler)
 	b.PUT("/telegram/bot/users", app.updateTgUserHandler)
 	b.GET("/telegram/bot/users/active", app.getActiveTgUserHandler)
+	b.GET("/sms/messages", app.getSmsMessagesHandler)
+	b.POST("/sms/nextsms/delivery-reports", app.nextsmsDeliveryReportHandler)
+	b.GET("/jobs", app.getJobsHandler)
+	b.POST("/jobs/:id/retry", app.retryJobHandler)
 
 	// user management
-	e.POST("/users", app.registerUserHandler)
-	e.POST("/users/activate", app.activateUserHandler)
-	e.POST("/users/resend/activation", app.resendactivationHandler)
-	e.POST("/users/forgot-password", app.forgotPasswordUserHandler)
-	e.POST("/users/change-password", app.changePasswordUserHandler)
+	e.POST("/users", app.registerUserHandler, app.rateLimit("register"))
+	e.POST("/users/activate", app.activateUserHandler, app.rateLimit("activate"))
+	e.POST("/users/resend/activation", app.resendactivationHandler, app.rateLimit("resend-activation"))
+	e.POST("/users/forgot-password", app.forgotPasswordUserHandler, app.rateLimit("forgot-password"))
+	e.POST("/users/change-password", app.changePasswordUserHandler, app.rateLimit("change-password"))
+	e.GET("/users/:username", app.getUserProfileHandler)
 
-	//TODO add ability to change phone number
+	e.GET("/tournaments", app.listTournamentsHandler)
+	e.GET("/tournaments/:id", app.getTournamentHandler)
+	e.GET("/tournaments/:id/rounds", app.listRoundsHandler)
+	e.GET("/tournaments/:id/rounds/:round", app.getRoundHandler)
+	e.GET("/tournaments/:id/standings", app.standingsHandler)
+	e.GET("/tournaments/:id/export/:format", app.exportTournamentHandler)
 
 	g := e.Group("/auth")
 	g.Use(app.authenticate)
 
-	g.PUT("/users/:id", app.updateUserHandler)
+	g.GET("/me", app.getMeHandler)
+	g.PUT("/users/:id", app.updateUserHandler, app.requireSelfOrPermission(permissionUsersWrite))
+	g.POST("/users/phone", app.changePhoneNumberHandler, app.rateLimit("change-phone"))
+	g.POST("/users/phone/confirm", app.confirmPhoneNumberHandler, app.rateLimit("confirm-phone"))
+	g.POST("/lichess/link", app.startLichessLinkHandler)
+	g.DELETE("/lichess/link", app.unlinkLichessHandler)
+
+	// role management
+	g.GET("/roles", app.getRolesHandler)
+	g.GET("/users/:id/roles", app.getUserRolesHandler, app.requireSelfOrPermission(permissionRolesWrite))
+	g.POST("/users/:id/roles", app.grantUserRoleHandler, app.requirePermission(permissionRolesWrite))
+	g.DELETE("/users/:id/roles/:role", app.revokeUserRoleHandler, app.requirePermission(permissionRolesWrite))
+
+	// tournaments
+	g.POST("/tournaments", app.createTournamentHandler, app.requirePermission(permissionTournamentsWrite))
+	g.POST("/tournaments/import", app.importTournamentHandler, app.requirePermission(permissionTournamentsWrite))
+	g.PUT("/tournaments/:id", app.updateTournamentHandler, app.requirePermission(permissionTournamentsWrite))
+	g.DELETE("/tournaments/:id", app.deleteTournamentHandler, app.requirePermission(permissionTournamentsWrite))
+	g.POST("/tournaments/:id/players", app.addTournamentPlayerHandler, app.requirePermission(permissionTournamentsWrite))
+	g.DELETE("/tournaments/:id/players/:player", app.removeTournamentPlayerHandler, app.requirePermission(permissionTournamentsWrite))
+	g.POST("/tournaments/:id/rounds", app.pairRoundHandler, app.requirePermission(permissionTournamentsWrite))
+	g.DELETE("/tournaments/:id/rounds/:round", app.deleteRoundHandler, app.requirePermission(permissionTournamentsWrite))
+	g.POST("/tournaments/:id/rounds/:round/boards/:board/games", app.addMatchGameHandler, app.requirePermission(permissionTournamentsWrite))
+	g.PUT("/tournaments/:id/games/:game/result", app.reportResultHandler, app.requirePermission(permissionResultsWrite))
+	g.GET("/tournaments/:id/result-changes", app.listResultChangesHandler, app.requirePermission(permissionResultsWrite))
+	g.POST("/tournaments/:id/registration", app.registerTournamentHandler)
+	g.DELETE("/tournaments/:id/registration", app.withdrawTournamentHandler)
+
+	g.POST("/logout", app.logoutHandler)
+	g.POST("/logout/all", app.logoutEverywhereHandler)
+	g.GET("/sessions", app.listSessionsHandler)
+	g.DELETE("/sessions/:id", app.revokeSessionHandler)
 
 	return e
 
@@ -39,6 +39,7 @@ func (app *application) serve() error {
 		}
 
 		slog.Info("completing background tasks", "address", srv.Addr)
+		app.stopWorkers()
 		app.wg.Wait()
 
 		shutdownError <- nil
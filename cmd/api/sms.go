@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+	"api.swahilichess.com/internal/jobs"
+	"api.swahilichess.com/internal/nextsms"
+	"api.swahilichess.com/internal/sms"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	smsStatusQueued = "QUEUED"
+	smsStatusFailed = "FAILED"
+)
+
+const jobSendSMS = "send_sms"
+
+// texts of the passcode messages, %d is the code
+const (
+	activationSMS    = "Code: %d \nUse it to activate your swahilichess account."
+	passwordResetSMS = "Code: %d \nUse it to reset password for your swahilichess account."
+	phoneChangeSMS   = "Code: %d \nUse it to confirm this number for your swahilichess account."
+)
+
+// smsJob is the payload of a send_sms job, the reference is fixed when the
+// job is queued so every retry updates the same sms_messages row.
+type smsJob struct {
+	Reference uuid.UUID `json:"reference"`
+	To        string    `json:"to"`
+	Text      string    `json:"text"`
+}
+
+// enqueueSMS stores the message in the job queue through q, which may be the
+// transaction the message belongs to. A worker sends it and retries until
+// the provider accepts it or, when set, expiresAt has passed.
+func (app *application) enqueueSMS(ctx context.Context, q db.Querier, msg string, recipient_phone string, expiresAt time.Time) error {
+
+	job := smsJob{
+		Reference: uuid.New(),
+		To:        recipient_phone,
+		Text:      msg,
+	}
+
+	_, err := app.jobs.EnqueueWith(ctx, q, jobSendSMS, job, jobs.Options{ExpiresAt: expiresAt})
+	return err
+}
+
+// sendPasscode issues a passcode and queues the sms carrying it through q, the
+// sms is dropped once the passcode expires. format gets the code as its only
+// argument.
+func (app *application) sendPasscode(ctx context.Context, q db.Querier, user_id uuid.UUID, purpose string, target string, recipient_phone string, format string) error {
+
+	code, expiry, err := app.otp.IssueWith(ctx, q, user_id, purpose, target)
+	if err != nil {
+		return err
+	}
+
+	return app.enqueueSMS(ctx, q, fmt.Sprintf(format, code), recipient_phone, expiry)
+}
+
+func (app *application) sendSMSJob(ctx context.Context, payload json.RawMessage) error {
+
+	var job smsJob
+	err := json.Unmarshal(payload, &job)
+	if err != nil {
+		return err
+	}
+
+	// jobs queued before messages had a reference
+	if job.Reference == uuid.Nil {
+		job.Reference = uuid.New()
+	}
+
+	return app.sendSMS(ctx, job.Reference, job.Text, job.To)
+}
+
+// sendSMS sends msg through the configured provider and records the outcome
+// in sms_messages under reference.
+func (app *application) sendSMS(ctx context.Context, reference uuid.UUID, msg string, recipient_phone string) error {
+
+	err := app.store.CreateSmsMessage(ctx, db.CreateSmsMessageParams{
+		Reference: reference,
+		Provider:  app.sms.Name(),
+		Recipient: recipient_phone,
+		Status:    smsStatusQueued,
+	})
+	if err != nil {
+		return err
+	}
+
+	res, sendErr := app.sms.Send(ctx, sms.Message{
+		Reference: reference.String(),
+		To:        recipient_phone,
+		Text:      msg,
+	})
+
+	args := db.UpdateSmsMessageResultParams{
+		Reference:         reference,
+		ProviderMessageID: res.MessageID,
+		Status:            res.Status,
+		StatusDescription: res.Description,
+		SmsCount:          int32(res.SMSCount),
+		Cost:              res.Cost,
+	}
+
+	if sendErr != nil {
+		args.Status = smsStatusFailed
+		args.Error = sendErr.Error()
+	}
+
+	err = app.store.UpdateSmsMessageResult(ctx, args)
+	if err != nil {
+		slog.Error("failed to record sms result", "reference", reference, "error", err.Error())
+	}
+
+	return sendErr
+}
+
+func (app *application) getSmsMessagesHandler(c echo.Context) error {
+
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	offset, err := strconv.Atoi(c.QueryParam("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	messages, err := app.store.GetSmsMessages(c.Request().Context(), db.GetSmsMessagesParams{
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return errInternal("failed to get sms messages", err)
+	}
+
+	return c.JSON(http.StatusOK, messages)
+
+}
+
+// nextsmsDeliveryReportHandler receives delivery reports pushed by NextSMS.
+func (app *application) nextsmsDeliveryReportHandler(c echo.Context) error {
+
+	var input struct {
+		Results []nextsms.Message `json:"results"`
+	}
+
+	if err := c.Bind(&input); err != nil {
+		return errBind(err)
+	}
+
+	for _, report := range input.Results {
+
+		res := report.Result()
+
+		args := db.UpdateSmsMessageStatusParams{
+			Provider:          "nextsms",
+			ProviderMessageID: res.MessageID,
+			Status:            res.Status,
+			StatusDescription: res.Description,
+		}
+
+		rows, err := app.store.UpdateSmsMessageStatus(c.Request().Context(), args)
+		if err != nil {
+			return errInternal("failed to update sms status", err)
+		}
+
+		if rows == 0 {
+			slog.Warn("delivery report for unknown sms", "message_id", res.MessageID)
+		}
+	}
+
+	return c.JSON(http.StatusOK, nil)
+
+}
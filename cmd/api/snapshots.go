@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+	"api.swahilichess.com/internal/lichess"
+	"api.swahilichess.com/internal/validator"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultMovementDays = 7
+	defaultHistoryDays  = 90
+	maxHistoryDays      = 3650
+)
+
+type ratingChange struct {
+	Username       string `json:"username"`
+	Rank           int32  `json:"rank"`
+	PreviousRank   *int32 `json:"previous_rank"`
+	Movement       int32  `json:"movement"`
+	Rating         int32  `json:"rating"`
+	PreviousRating *int32 `json:"previous_rating"`
+	RatingDelta    int32  `json:"rating_delta"`
+	Games          int32  `json:"games"`
+	Provisional    bool   `json:"provisional"`
+}
+
+type ratingChanges struct {
+	Variant string         `json:"variant"`
+	From    time.Time      `json:"from"`
+	To      time.Time      `json:"to"`
+	Players []ratingChange `json:"players"`
+}
+
+// snapshotLeaderboard stores the members' ratings for every variant, unless
+// a snapshot was already taken within the snapshot interval.
+func (app *application) snapshotLeaderboard(ctx context.Context, members []lichess.User) error {
+
+	var entries []db.CreateLeaderboardEntryParams
+
+	for _, variant := range lichessVariants {
+		for _, s := range rankVariant(members, variant, leaderboardFilter{}) {
+			entries = append(entries, db.CreateLeaderboardEntryParams{
+				Username: s.Username,
+				Variant:  variant,
+				Rank:     int32(s.Rank),
+				Rating:   int32(s.Rating),
+				Games:    int32(s.Games),
+				Rd:       int32(s.Rd),
+				Prog:     int32(s.Prog),
+				Prov:     s.Provisional,
+			})
+		}
+	}
+
+	_, err := app.store.CreateLeaderboardSnapshotTx(ctx, db.CreateLeaderboardSnapshotTxParams{
+		Entries:     entries,
+		MinInterval: app.config.Leaderboard.SnapshotInterval,
+	})
+	if errors.Is(err, db.ErrSnapshotTooRecent) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return app.pruneLeaderboardSnapshots(ctx)
+}
+
+// pruneLeaderboardSnapshots deletes the snapshots older than the retention,
+// along with their entries.
+func (app *application) pruneLeaderboardSnapshots(ctx context.Context) error {
+
+	retention := app.config.Leaderboard.SnapshotRetention
+	if retention <= 0 {
+		return nil
+	}
+
+	rows, err := app.store.DeleteLeaderboardSnapshotsBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return fmt.Errorf("failed to delete old leaderboard snapshots: %w", err)
+	}
+
+	if rows > 0 {
+		slog.Info("deleted old leaderboard snapshots", "count", rows)
+	}
+
+	return nil
+}
+
+// leaderboardMovementHandler compares the latest ranking with the one from
+// ?days ago, a positive movement means the player climbed.
+func (app *application) leaderboardMovementHandler(c echo.Context) error {
+
+	changes, err := app.ratingChanges(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, changes)
+
+}
+
+// leaderboardDeltasHandler lists the rating gained by each player over the
+// period, biggest gains first. Players without an earlier rating are left out.
+func (app *application) leaderboardDeltasHandler(c echo.Context) error {
+
+	changes, err := app.ratingChanges(c)
+	if err != nil {
+		return err
+	}
+
+	players := []ratingChange{}
+	for _, p := range changes.Players {
+		if p.PreviousRating != nil {
+			players = append(players, p)
+		}
+	}
+
+	sort.SliceStable(players, func(i, j int) bool {
+		return players[i].RatingDelta > players[j].RatingDelta
+	})
+
+	changes.Players = players
+
+	return c.JSON(http.StatusOK, changes)
+
+}
+
+func (app *application) ratingHistoryHandler(c echo.Context) error {
+
+	variant := c.QueryParam("variant")
+	if variant == "" {
+		variant = "rapid"
+	}
+	if !validator.In(variant, lichessVariants...) {
+		return errBadRequest("unknown_variant", "unknown variant")
+	}
+
+	days, err := queryDays(c, defaultHistoryDays)
+	if err != nil {
+		return err
+	}
+
+	args := db.GetRatingHistoryParams{
+		Username: c.Param("username"),
+		Variant:  variant,
+		TakenAt:  time.Now().AddDate(0, 0, -days),
+	}
+
+	history, err := app.store.GetRatingHistory(c.Request().Context(), args)
+	if err != nil {
+		return errInternal("failed to get rating history", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"username": args.Username,
+		"variant":  variant,
+		"history":  history,
+	})
+
+}
+
+func (app *application) ratingChanges(c echo.Context) (*ratingChanges, error) {
+
+	variant := c.QueryParam("variant")
+	if variant == "" {
+		variant = "rapid"
+	}
+	if !validator.In(variant, lichessVariants...) {
+		return nil, errBadRequest("unknown_variant", "unknown variant")
+	}
+
+	days, err := queryDays(c, defaultMovementDays)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := c.Request().Context()
+
+	latest, err := app.store.GetLatestLeaderboardSnapshot(ctx)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, errNotFound("snapshot_not_found", "no leaderboard snapshots yet")
+		default:
+			return nil, errInternal("failed to get latest leaderboard snapshot", err)
+		}
+	}
+
+	// fall back to the oldest snapshot when history doesn't go back far enough
+	previous, err := app.store.GetLeaderboardSnapshotBefore(ctx, latest.TakenAt.AddDate(0, 0, -days))
+	if errors.Is(err, sql.ErrNoRows) {
+		previous, err = app.store.GetFirstLeaderboardSnapshot(ctx)
+	}
+	if err != nil {
+		return nil, errInternal("failed to get previous leaderboard snapshot", err)
+	}
+
+	rows, err := app.store.GetLeaderboardChanges(ctx, db.GetLeaderboardChangesParams{
+		PreviousSnapshotID: previous.ID,
+		SnapshotID:         latest.ID,
+		Variant:            variant,
+	})
+	if err != nil {
+		return nil, errInternal("failed to get leaderboard changes", err)
+	}
+
+	players := make([]ratingChange, 0, len(rows))
+	for _, row := range rows {
+		p := ratingChange{
+			Username:    row.Username,
+			Rank:        row.Rank,
+			Rating:      row.Rating,
+			Games:       row.Games,
+			Provisional: row.Prov,
+		}
+
+		if row.PreviousRank.Valid {
+			p.PreviousRank = &row.PreviousRank.Int32
+			p.Movement = row.PreviousRank.Int32 - row.Rank
+		}
+
+		if row.PreviousRating.Valid {
+			p.PreviousRating = &row.PreviousRating.Int32
+			p.RatingDelta = row.Rating - row.PreviousRating.Int32
+		}
+
+		players = append(players, p)
+	}
+
+	return &ratingChanges{
+		Variant: variant,
+		From:    previous.TakenAt,
+		To:      latest.TakenAt,
+		Players: players,
+	}, nil
+}
+
+func queryDays(c echo.Context, fallback int) (int, error) {
+
+	param := c.QueryParam("days")
+	if param == "" {
+		return fallback, nil
+	}
+
+	days, err := strconv.Atoi(param)
+	if err != nil || days <= 0 || days > maxHistoryDays {
+		return 0, errBadRequest("invalid_days", "days must be between 1 and 3650")
+	}
+
+	return days, nil
+}
@@ -1,18 +1,19 @@
 package main
 
 import (
-	"log/slog"
 	"net/http"
+
 	db "api.swahilichess.com/internal/db/sqlc"
 	"github.com/labstack/echo/v4"
 )
 
+const duplicate_tg_user = `pq: duplicate key value violates unique constraint "tgbot_users_pkey"`
+
 func (app *application) getActiveTgUserHandler(c echo.Context) error {
 
 	tgActiveUsers, err := app.store.GetActiveTgBotUsers(c.Request().Context())
 	if err != nil {
-		slog.Error("failed to get active tg users on db", "error", err)
-		c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		return errInternal("failed to get active tg users on db", err)
 	}
 
 	return c.JSON(http.StatusOK, tgActiveUsers)
@@ -23,51 +24,52 @@ func (app *application) insertTgUserHandler(c echo.Context) error {
 
 	var input struct {
 		ID       int64 `json:"id"`
-        Isactive bool  `json:"isactive"`
+		Isactive bool  `json:"isactive"`
 	}
 
 	if err := c.Bind(&input); err != nil {
-		c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return errBind(err)
 	}
 
 	args := db.InsertTgBotUsersParams{
-		ID: input.ID,
+		ID:       input.ID,
 		Isactive: input.Isactive,
 	}
-    
-	err := app.store.InsertTgBotUsers(c.Request().Context(), args)
 
+	err := app.store.InsertTgBotUsers(c.Request().Context(), args)
 	if err != nil {
-		slog.Error("failed to insert tg users on db", "error", err)
-		c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()}) // real error is needed
+		switch {
+		case err.Error() == duplicate_tg_user:
+			return errConflict("tg_user_exists", "telegram user already exists")
+		default:
+			return errInternal("failed to insert tg users on db", err)
+		}
 	}
 
 	return c.JSON(http.StatusOK, nil)
 
-
 }
 
 func (app *application) updateTgUserHandler(c echo.Context) error {
 
-		var input struct {
-			ID       int64 `json:"id"`
-	        Isactive bool  `json:"isactive"`
-        }
+	var input struct {
+		ID       int64 `json:"id"`
+		Isactive bool  `json:"isactive"`
+	}
 
 	if err := c.Bind(&input); err != nil {
-		c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return errBind(err)
 	}
 
 	args := db.UpdateTgBotUsersParams{
-		ID: input.ID,
+		ID:       input.ID,
 		Isactive: input.Isactive,
 	}
-    
+
 	err := app.store.UpdateTgBotUsers(c.Request().Context(), args)
 
 	if err != nil {
-		slog.Error("failed to update tg users on db", "error", err)
-		c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		return errInternal("failed to update tg users on db", err)
 	}
 
 	return c.JSON(http.StatusOK, nil)
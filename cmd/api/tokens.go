@@ -5,9 +5,11 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 
 	db "api.swahilichess.com/internal/db/sqlc"
 	"api.swahilichess.com/internal/token"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -21,13 +23,23 @@ func (app *application) createAuthTokenHandler(c echo.Context) error {
 	}
 
 	if err := c.Bind(&input); err != nil {
-		c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return errBind(err)
 	}
 
 	if err := app.validator.Struct(input); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return errValidation(err)
 	}
 
+	if input.PhoneNumber == "" && input.Username == "" {
+		return errBadRequest("missing_identifier", "phone number or username is required")
+	}
+
+	phoneNumber, err := normalizePhoneNumber(input.PhoneNumber)
+	if err != nil {
+		return errBadRequest("invalid_phone_number", "invalid phone number")
+	}
+	input.PhoneNumber = phoneNumber
+
 	args := db.GetUserByUsernameOrPhoneParams{
 		PhoneNumber: input.PhoneNumber,
 		Username:    input.Username,
@@ -38,16 +50,20 @@ func (app *application) createAuthTokenHandler(c echo.Context) error {
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid phonenumber or username"})
+			return app.unknownAccount(c, "login", errBadRequest("invalid_credentials", "invalid phonenumber or username"))
 
 		default:
-			slog.Error("failed to get username or phone number", "error", err.Error())
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return errInternal("failed to get username or phone number", err)
 		}
 	}
 
+	key := lockoutKey("login", user.ID)
+	if locked, err := app.checkLockout(c, key); locked {
+		return err
+	}
+
 	if !user.Activated {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "user is not activated"})
+		return errBadRequest("user_not_activated", "user is not activated")
 	}
 
 	err = bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(input.Password))
@@ -55,28 +71,166 @@ func (app *application) createAuthTokenHandler(c echo.Context) error {
 	if err != nil {
 		switch {
 		case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid password"})
+			app.recordFailure(c, key)
+			return errBadRequest("invalid_credentials", "invalid password")
 
 		default:
-			slog.Error("failed comparing hash ", "Error", err.Error())
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return errInternal("failed comparing hash", err)
 		}
 	}
 
-	token, expiry, err := token.New(user.ID, app.store, token.ScopeAuthentication)
+	app.resetFailures(c, key)
+
+	pair, err := token.NewPair(c.Request().Context(), user.ID, app.store, tokenMetadata(c))
+	if err != nil {
+		return errInternal("failed to create token", err)
+	}
+
+	return c.JSON(200, tokenResponse(pair))
+
+}
+
+func (app *application) refreshTokenHandler(c echo.Context) error {
+
+	var input struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+
+	if err := c.Bind(&input); err != nil {
+		return errBind(err)
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		return errValidation(err)
+	}
+
+	pair, err := token.Rotate(c.Request().Context(), input.RefreshToken, app.store)
+	if err != nil {
+		switch {
+		case errors.Is(err, token.ErrInvalidToken):
+			return errUnauthorized("invalid_refresh_token", "invalid or expired refresh token")
+
+		case errors.Is(err, token.ErrTokenReused):
+			slog.Warn("refresh token reused, token family revoked")
+			return errUnauthorized("invalid_refresh_token", "invalid or expired refresh token")
+
+		default:
+			return errInternal("failed to rotate refresh token", err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, tokenResponse(pair))
+
+}
+
+type tokenPairResponse struct {
+	Token         string `json:"token"`
+	Expiry        int64  `json:"expiry"`
+	RefreshToken  string `json:"refresh_token"`
+	RefreshExpiry int64  `json:"refresh_expiry"`
+}
+
+func tokenResponse(pair *token.Pair) tokenPairResponse {
+	return tokenPairResponse{
+		Token:         pair.AccessToken,
+		Expiry:        pair.AccessExpiry.Unix(),
+		RefreshToken:  pair.RefreshToken,
+		RefreshExpiry: pair.RefreshExpiry.Unix(),
+	}
+}
+
+func tokenMetadata(c echo.Context) token.Metadata {
+	return token.Metadata{
+		UserAgent: c.Request().UserAgent(),
+		IP:        c.RealIP(),
+	}
+}
+
+func (app *application) logoutHandler(c echo.Context) error {
+
+	user := app.contextGetUser(c)
+
+	err := app.store.DeleteTokensByFamily(c.Request().Context(), user.FamilyID)
+	if err != nil {
+		return errInternal("failed to revoke session tokens", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"success": "logged out"})
+
+}
+
+func (app *application) logoutEverywhereHandler(c echo.Context) error {
+
+	user := app.contextGetUser(c)
+
+	err := app.store.DeleteTokensByUser(c.Request().Context(), user.ID)
+	if err != nil {
+		return errInternal("failed to revoke user tokens", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"success": "logged out from all sessions"})
+
+}
+
+func (app *application) listSessionsHandler(c echo.Context) error {
+
+	user := app.contextGetUser(c)
+
+	sessions, err := app.store.GetSessionsByUser(c.Request().Context(), user.ID)
+	if err != nil {
+		return errInternal("failed to get user sessions", err)
+	}
+
+	type session struct {
+		ID         uuid.UUID `json:"id"`
+		CreatedAt  time.Time `json:"created_at"`
+		Expiry     time.Time `json:"expiry"`
+		LastUsedAt time.Time `json:"last_used_at"`
+		UserAgent  string    `json:"user_agent"`
+		IP         string    `json:"ip"`
+		Current    bool      `json:"current"`
+	}
+
+	res := make([]session, 0, len(sessions))
+	for _, s := range sessions {
+		res = append(res, session{
+			ID:         s.FamilyID,
+			CreatedAt:  s.CreatedAt,
+			Expiry:     s.Expiry,
+			LastUsedAt: s.LastUsedAt,
+			UserAgent:  s.UserAgent,
+			IP:         s.Ip,
+			Current:    s.FamilyID == user.FamilyID,
+		})
+	}
+
+	return c.JSON(http.StatusOK, res)
+
+}
+
+func (app *application) revokeSessionHandler(c echo.Context) error {
+
+	user := app.contextGetUser(c)
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return errBadRequest("invalid_id", "invalid uuid")
+	}
+
+	args := db.DeleteSessionParams{
+		FamilyID: id,
+		UserID:   user.ID,
+	}
+
+	rows, err := app.store.DeleteSession(c.Request().Context(), args)
 	if err != nil {
-		slog.Error("failed to create token", "error", err.Error())
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		return errInternal("failed to revoke session", err)
 	}
 
-	res := struct {
-		Token  string `json:"token"`
-		Expiry int64  `json:"expiry"`
-	}{
-		Token:  token,
-		Expiry: expiry.Unix(),
+	if rows == 0 {
+		return errNotFound("session_not_found", "session not found")
 	}
 
-	return c.JSON(200, res)
+	return c.JSON(http.StatusOK, map[string]string{"success": "session revoked"})
 
 }
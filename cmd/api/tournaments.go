@@ -0,0 +1,566 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+	"api.swahilichess.com/internal/validator"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	formatSwiss            = "swiss"
+	formatRoundRobin       = "round_robin"
+	formatDoubleRoundRobin = "double_round_robin"
+	formatKnockout         = "knockout"
+)
+
+var tournamentStatuses = []string{db.TournamentScheduled, db.TournamentOngoing, db.TournamentFinished, db.TournamentCancelled}
+
+// timeControlRx matches "minutes+increment" time controls such as 10+5.
+var timeControlRx = regexp.MustCompile(`^(\d{1,3})\+(\d{1,3})$`)
+
+type tournamentInput struct {
+	Name                 string     `json:"name" validate:"required,min=3,max=100"`
+	Format               string     `json:"format" validate:"required,oneof=swiss round_robin double_round_robin knockout"`
+	TimeControl          string     `json:"time_control" validate:"required"`
+	Rounds               int32      `json:"rounds" validate:"min=0,max=30"`
+	Online               bool       `json:"online"`
+	Venue                string     `json:"venue" validate:"max=200"`
+	StartsAt             time.Time  `json:"starts_at" validate:"required"`
+	RegistrationOpensAt  *time.Time `json:"registration_opens_at"`
+	RegistrationClosesAt *time.Time `json:"registration_closes_at"`
+	MaxPlayers           int32      `json:"max_players" validate:"min=0,max=1000"`
+	Status               string     `json:"status" validate:"omitempty,oneof=scheduled ongoing finished cancelled"`
+	Tiebreaks            []string   `json:"tiebreaks" validate:"max=6,unique,dive,oneof=buchholz buchholz_cut1 sonneborn_berger direct_encounter wins progressive"`
+}
+
+func (app *application) listTournamentsHandler(c echo.Context) error {
+
+	status := c.QueryParam("status")
+	if status != "" && !validator.In(status, tournamentStatuses...) {
+		return errBadRequest("invalid_status", "unknown tournament status")
+	}
+
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	offset, err := strconv.Atoi(c.QueryParam("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	tournaments, err := app.store.ListTournaments(c.Request().Context(), db.ListTournamentsParams{
+		Status: status,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return errInternal("failed to list tournaments", err)
+	}
+
+	return c.JSON(http.StatusOK, tournaments)
+
+}
+
+func (app *application) getTournamentHandler(c echo.Context) error {
+
+	tournament, err := app.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	players, err := app.store.GetTournamentPlayers(c.Request().Context(), tournament.ID)
+	if err != nil {
+		return errInternal("failed to get tournament players", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"tournament": tournament,
+		"players":    players,
+	})
+
+}
+
+func (app *application) createTournamentHandler(c echo.Context) error {
+
+	var input tournamentInput
+
+	if err := c.Bind(&input); err != nil {
+		return errBind(err)
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		return errValidation(err)
+	}
+
+	opensAt, closesAt, err := checkTournament(input, time.Now(), input.StartsAt)
+	if err != nil {
+		return err
+	}
+
+	if input.Tiebreaks == nil {
+		input.Tiebreaks = []string{}
+	}
+
+	user := app.contextGetUser(c)
+
+	tournament, err := app.store.CreateTournament(c.Request().Context(), db.CreateTournamentParams{
+		Name:                 input.Name,
+		Format:               input.Format,
+		TimeControl:          input.TimeControl,
+		Rounds:               input.Rounds,
+		Online:               input.Online,
+		Venue:                input.Venue,
+		StartsAt:             input.StartsAt,
+		RegistrationOpensAt:  opensAt,
+		RegistrationClosesAt: closesAt,
+		MaxPlayers:           input.MaxPlayers,
+		CreatedBy:            uuid.NullUUID{UUID: user.ID, Valid: true},
+		Tiebreaks:            input.Tiebreaks,
+	})
+	if err != nil {
+		return errInternal("failed to create tournament", err)
+	}
+
+	return c.JSON(http.StatusCreated, tournament)
+
+}
+
+// updateTournamentHandler replaces the tournament's settings, registration
+// dates and tiebreaks left out keep their current value. The format, rounds,
+// time control and player limit are locked once a round is paired.
+func (app *application) updateTournamentHandler(c echo.Context) error {
+
+	tournament, err := app.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	err = app.requireOrganizer(c, tournament)
+	if err != nil {
+		return err
+	}
+
+	var input tournamentInput
+
+	if err := c.Bind(&input); err != nil {
+		return errBind(err)
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		return errValidation(err)
+	}
+
+	opensAt, closesAt, err := checkTournament(input, tournament.RegistrationOpensAt, tournament.RegistrationClosesAt)
+	if err != nil {
+		return err
+	}
+
+	if input.Format != tournament.Format || input.Rounds != tournament.Rounds ||
+		input.TimeControl != tournament.TimeControl || input.MaxPlayers != tournament.MaxPlayers {
+
+		last, err := app.store.GetLastRound(c.Request().Context(), tournament.ID)
+		if err != nil {
+			return errInternal("failed to get last round", err)
+		}
+
+		if last > 0 {
+			return errBadRequest("tournament_paired", "format, rounds, time control and max players can't change once a round is paired")
+		}
+	}
+
+	if input.Status == "" {
+		input.Status = tournament.Status
+	}
+
+	if input.Tiebreaks == nil {
+		input.Tiebreaks = append([]string{}, tournament.Tiebreaks...)
+	}
+
+	tournament, err = app.store.UpdateTournament(c.Request().Context(), db.UpdateTournamentParams{
+		Name:                 input.Name,
+		Format:               input.Format,
+		TimeControl:          input.TimeControl,
+		Rounds:               input.Rounds,
+		Online:               input.Online,
+		Venue:                input.Venue,
+		StartsAt:             input.StartsAt,
+		RegistrationOpensAt:  opensAt,
+		RegistrationClosesAt: closesAt,
+		MaxPlayers:           input.MaxPlayers,
+		Status:               input.Status,
+		Tiebreaks:            input.Tiebreaks,
+		ID:                   tournament.ID,
+	})
+	if err != nil {
+		return errInternal("failed to update tournament", err)
+	}
+
+	return c.JSON(http.StatusOK, tournament)
+
+}
+
+// deleteTournamentHandler deletes a tournament that hasn't started, the others
+// are kept for their results and have to be cancelled instead.
+func (app *application) deleteTournamentHandler(c echo.Context) error {
+
+	tournament, err := app.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	err = app.requireOrganizer(c, tournament)
+	if err != nil {
+		return err
+	}
+
+	if tournament.Status != db.TournamentScheduled {
+		return errBadRequest("tournament_started", "only scheduled tournaments can be deleted, cancel it instead")
+	}
+
+	rows, err := app.store.DeleteTournament(c.Request().Context(), tournament.ID)
+	if err != nil {
+		return errInternal("failed to delete tournament", err)
+	}
+
+	if rows == 0 {
+		return errNotFound("tournament_not_found", "tournament not found")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"success": "tournament deleted"})
+
+}
+
+// registerTournamentHandler registers the authenticated user while the
+// registration window is open, their rating comes from the leaderboard data
+// of the tournament's time control.
+func (app *application) registerTournamentHandler(c echo.Context) error {
+
+	tournament, err := app.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if now.Before(tournament.RegistrationOpensAt) || now.After(tournament.RegistrationClosesAt) {
+		return errBadRequest("registration_closed", "registration is closed")
+	}
+
+	user := app.contextGetUser(c)
+
+	player, err := app.store.RegisterTournamentPlayerTx(c.Request().Context(), db.CreateTournamentPlayerParams{
+		TournamentID: tournament.ID,
+		UserID:       uuid.NullUUID{UUID: user.ID, Valid: true},
+		Name:         user.FullName,
+		Rating:       app.playerRating(tournament.TimeControl, user.LichessUsername, user.LichessID, user.ChesscomUsername),
+	})
+	if err != nil {
+		return registrationError(err)
+	}
+
+	return c.JSON(http.StatusCreated, player)
+
+}
+
+func (app *application) withdrawTournamentHandler(c echo.Context) error {
+
+	tournament, err := app.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	if tournament.Status == db.TournamentFinished || tournament.Status == db.TournamentCancelled {
+		return errBadRequest("tournament_closed", "tournament is over")
+	}
+
+	user := app.contextGetUser(c)
+
+	player, err := app.store.GetTournamentPlayerByUser(c.Request().Context(), db.GetTournamentPlayerByUserParams{
+		TournamentID: tournament.ID,
+		UserID:       uuid.NullUUID{UUID: user.ID, Valid: true},
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return errNotFound("player_not_found", "you are not registered")
+		default:
+			return errInternal("failed to get tournament player", err)
+		}
+	}
+
+	return app.withdrawPlayer(c, player)
+
+}
+
+// addTournamentPlayerHandler lets organizers enter a registered user by
+// username or a guest by name, outside the registration window.
+func (app *application) addTournamentPlayerHandler(c echo.Context) error {
+
+	tournament, err := app.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	err = app.requireOrganizer(c, tournament)
+	if err != nil {
+		return err
+	}
+
+	var input struct {
+		Username string `json:"username"`
+		Name     string `json:"name" validate:"required_without=Username,max=100"`
+		Rating   int32  `json:"rating" validate:"min=0,max=4000"`
+	}
+
+	if err := c.Bind(&input); err != nil {
+		return errBind(err)
+	}
+
+	if err := app.validator.Struct(input); err != nil {
+		return errValidation(err)
+	}
+
+	args := db.CreateTournamentPlayerParams{
+		TournamentID: tournament.ID,
+		Name:         input.Name,
+		Rating:       input.Rating,
+	}
+
+	if input.Username != "" {
+		user, err := app.store.GetUserByUsername(c.Request().Context(), input.Username)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return errNotFound("user_not_found", "user not found")
+			default:
+				return errInternal("failed to get user by username", err)
+			}
+		}
+
+		args.UserID = uuid.NullUUID{UUID: user.ID, Valid: true}
+		if args.Name == "" {
+			args.Name = user.FullName
+		}
+		if args.Rating == 0 {
+			args.Rating = app.playerRating(tournament.TimeControl, user.LichessUsername, user.LichessID, user.ChesscomUsername)
+		}
+	}
+
+	player, err := app.store.RegisterTournamentPlayerTx(c.Request().Context(), args)
+	if err != nil {
+		return registrationError(err)
+	}
+
+	return c.JSON(http.StatusCreated, player)
+
+}
+
+func (app *application) removeTournamentPlayerHandler(c echo.Context) error {
+
+	tournament, err := app.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	err = app.requireOrganizer(c, tournament)
+	if err != nil {
+		return err
+	}
+
+	playerID, err := paramID(c, "player")
+	if err != nil {
+		return err
+	}
+
+	player, err := app.store.GetTournamentPlayer(c.Request().Context(), db.GetTournamentPlayerParams{
+		TournamentID: tournament.ID,
+		ID:           playerID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return errNotFound("player_not_found", "player not found")
+		default:
+			return errInternal("failed to get tournament player", err)
+		}
+	}
+
+	return app.withdrawPlayer(c, player)
+
+}
+
+func (app *application) withdrawPlayer(c echo.Context, player db.TournamentPlayer) error {
+
+	rows, err := app.store.WithdrawTournamentPlayer(c.Request().Context(), db.WithdrawTournamentPlayerParams{
+		TournamentID: player.TournamentID,
+		ID:           player.ID,
+	})
+	if err != nil {
+		return errInternal("failed to withdraw tournament player", err)
+	}
+
+	if rows == 0 {
+		return errBadRequest("already_withdrawn", "player already withdrew")
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"success": "player withdrawn"})
+
+}
+
+// tournamentParam loads the tournament of the :id route parameter.
+func (app *application) tournamentParam(c echo.Context) (db.Tournament, error) {
+
+	id, err := paramID(c, "id")
+	if err != nil {
+		return db.Tournament{}, err
+	}
+
+	tournament, err := app.store.GetTournament(c.Request().Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return db.Tournament{}, errNotFound("tournament_not_found", "tournament not found")
+		default:
+			return db.Tournament{}, errInternal("failed to get tournament", err)
+		}
+	}
+
+	return tournament, nil
+}
+
+// requireOrganizer allows the user who created the tournament and the users
+// allowed to manage every tournament.
+func (app *application) requireOrganizer(c echo.Context, tournament db.Tournament) error {
+
+	user := app.contextGetUser(c)
+
+	if tournament.CreatedBy.Valid && tournament.CreatedBy.UUID == user.ID {
+		return nil
+	}
+
+	ok, err := app.userHasPermission(c, permissionTournamentsManage)
+	if err != nil {
+		return errInternal("failed to get user permissions", err)
+	}
+
+	if !ok {
+		return errForbidden()
+	}
+
+	return nil
+}
+
+// playerRating is the player's lichess rating in the time control's category,
+// their chess.com rating when lichess has none and 0 when neither has one.
+func (app *application) playerRating(timeControl string, lichessUsername string, lichessID sql.NullString, chesscomUsername string) int32 {
+
+	variant, ok := timeControlVariant(timeControl)
+	if !ok {
+		return 0
+	}
+
+	if lichessUsername != "" || lichessID.Valid {
+		if rating, ok := app.lichessAccount(lichessUsername, lichessID).Ratings[variant]; ok {
+			return int32(rating.Rating)
+		}
+	}
+
+	if chesscomUsername != "" {
+		if rating, ok := app.chesscomAccount(chesscomUsername).Ratings[variant]; ok {
+			return int32(rating.Rating)
+		}
+	}
+
+	return 0
+}
+
+// timeControlVariant is the lichess rating category of a time control,
+// decided like lichess does by the estimated duration of a 40 move game.
+func timeControlVariant(timeControl string) (string, bool) {
+
+	match := timeControlRx.FindStringSubmatch(timeControl)
+	if match == nil {
+		return "", false
+	}
+
+	minutes, _ := strconv.Atoi(match[1])
+	increment, _ := strconv.Atoi(match[2])
+
+	switch estimate := minutes*60 + 40*increment; {
+	case estimate == 0:
+		return "", false
+	case estimate < 30:
+		return "ultraBullet", true
+	case estimate < 180:
+		return "bullet", true
+	case estimate < 480:
+		return "blitz", true
+	case estimate < 1500:
+		return "rapid", true
+	default:
+		return "classical", true
+	}
+}
+
+// checkTournament validates what the struct tags can't and returns the
+// registration window, missing dates default to opensAt and closesAt.
+func checkTournament(input tournamentInput, opensAt time.Time, closesAt time.Time) (time.Time, time.Time, error) {
+
+	if _, ok := timeControlVariant(input.TimeControl); !ok {
+		return opensAt, closesAt, errBadRequest("invalid_time_control", "time control must be minutes+increment, e.g. 10+5")
+	}
+
+	if input.Format == formatSwiss && input.Rounds < 1 {
+		return opensAt, closesAt, errBadRequest("invalid_rounds", "swiss tournaments need at least one round")
+	}
+
+	if !input.Online && input.Venue == "" {
+		return opensAt, closesAt, errBadRequest("missing_venue", "venue is required for over the board tournaments")
+	}
+
+	if input.RegistrationOpensAt != nil {
+		opensAt = *input.RegistrationOpensAt
+	}
+
+	if input.RegistrationClosesAt != nil {
+		closesAt = *input.RegistrationClosesAt
+	}
+
+	if !opensAt.Before(closesAt) {
+		return opensAt, closesAt, errBadRequest("invalid_registration_window", "registration must open before it closes")
+	}
+
+	return opensAt, closesAt, nil
+}
+
+func registrationError(err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return errNotFound("tournament_not_found", "tournament not found")
+	case errors.Is(err, db.ErrRegistrationClosed):
+		return errBadRequest("registration_closed", "registration is closed")
+	case errors.Is(err, db.ErrTournamentFull):
+		return errBadRequest("tournament_full", "tournament is full")
+	case errors.Is(err, db.ErrAlreadyRegistered):
+		return errBadRequest("already_registered", "player is already registered")
+	default:
+		return errInternal("failed to register tournament player", err)
+	}
+}
+
+func paramID(c echo.Context, name string) (int64, error) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id < 1 {
+		return 0, errBadRequest("invalid_id", "invalid "+name)
+	}
+	return id, nil
+}
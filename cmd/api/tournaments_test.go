@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// tournamentStore keeps one tournament in memory, the other store methods are
+// not used by the update and delete handlers.
+type tournamentStore struct {
+	db.Store
+
+	tournament  db.Tournament
+	lastRound   int32
+	permissions map[uuid.UUID][]string
+	deleted     bool
+	players     []db.TournamentPlayer
+	withdrawn   []int64
+
+	games         []db.TournamentGame
+	deletedRounds []int32
+}
+
+func (s *tournamentStore) GetTournament(ctx context.Context, id int64) (db.Tournament, error) {
+	if s.deleted || id != s.tournament.ID {
+		return db.Tournament{}, sql.ErrNoRows
+	}
+	return s.tournament, nil
+}
+
+func (s *tournamentStore) GetLastRound(ctx context.Context, tournamentID int64) (int32, error) {
+	return s.lastRound, nil
+}
+
+func (s *tournamentStore) GetUserPermissions(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return s.permissions[userID], nil
+}
+
+func (s *tournamentStore) UpdateTournament(ctx context.Context, arg db.UpdateTournamentParams) (db.Tournament, error) {
+	s.tournament.Name = arg.Name
+	s.tournament.Format = arg.Format
+	s.tournament.TimeControl = arg.TimeControl
+	s.tournament.Rounds = arg.Rounds
+	s.tournament.MaxPlayers = arg.MaxPlayers
+	s.tournament.Status = arg.Status
+	return s.tournament, nil
+}
+
+func (s *tournamentStore) DeleteTournament(ctx context.Context, id int64) (int64, error) {
+	s.deleted = true
+	return 1, nil
+}
+
+func (s *tournamentStore) RegisterTournamentPlayerTx(ctx context.Context, arg db.CreateTournamentPlayerParams) (db.TournamentPlayer, error) {
+	player := db.TournamentPlayer{ID: int64(len(s.players) + 1), TournamentID: arg.TournamentID, Name: arg.Name, Rating: arg.Rating}
+	s.players = append(s.players, player)
+	return player, nil
+}
+
+func (s *tournamentStore) GetTournamentPlayer(ctx context.Context, arg db.GetTournamentPlayerParams) (db.TournamentPlayer, error) {
+	for _, p := range s.players {
+		if p.ID == arg.ID && p.TournamentID == arg.TournamentID {
+			return p, nil
+		}
+	}
+	return db.TournamentPlayer{}, sql.ErrNoRows
+}
+
+func (s *tournamentStore) WithdrawTournamentPlayer(ctx context.Context, arg db.WithdrawTournamentPlayerParams) (int64, error) {
+	s.withdrawn = append(s.withdrawn, arg.ID)
+	return 1, nil
+}
+
+func newTournamentStore(organizer uuid.UUID) *tournamentStore {
+
+	starts := time.Now().Add(7 * 24 * time.Hour)
+
+	return &tournamentStore{
+		tournament: db.Tournament{
+			ID:                   1,
+			Name:                 "Dar Open",
+			Format:               formatSwiss,
+			TimeControl:          "10+5",
+			Rounds:               5,
+			Online:               true,
+			StartsAt:             starts,
+			RegistrationOpensAt:  time.Now(),
+			RegistrationClosesAt: starts,
+			MaxPlayers:           50,
+			Status:               db.TournamentScheduled,
+			CreatedBy:            uuid.NullUUID{UUID: organizer, Valid: true},
+			Tiebreaks:            []string{},
+		},
+		permissions: make(map[uuid.UUID][]string),
+	}
+}
+
+// serveTournament runs the handler on tournament 1 as the user, params are
+// the other route parameters as name and value pairs.
+func serveTournament(handler echo.HandlerFunc, method string, body string, userID uuid.UUID, params ...string) (*httptest.ResponseRecorder, error) {
+
+	e := echo.New()
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	c := e.NewContext(req, rec)
+	names, values := []string{"id"}, []string{"1"}
+	for i := 0; i+1 < len(params); i += 2 {
+		names = append(names, params[i])
+		values = append(values, params[i+1])
+	}
+	c.SetParamNames(names...)
+	c.SetParamValues(values...)
+	c.Set("user", db.GetUserByTokenRow{ID: userID})
+
+	return rec, handler(c)
+}
+
+func updateBody(rounds int) string {
+	return `{"name":"Dar Open","format":"swiss","time_control":"10+5","rounds":` + strconv.Itoa(rounds) +
+		`,"online":true,"starts_at":"` + time.Now().Add(7*24*time.Hour).Format(time.RFC3339) + `","max_players":50}`
+}
+
+func TestUpdateTournament(t *testing.T) {
+
+	organizer, other, admin := uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name      string
+		user      uuid.UUID
+		lastRound int32
+		rounds    int
+		wantCode  string
+	}{
+		{name: "organizer", user: organizer, rounds: 7},
+		{name: "other organizer", user: other, rounds: 7, wantCode: "forbidden"},
+		{name: "tournaments manager", user: admin, rounds: 7},
+		{name: "rounds after pairing", user: organizer, lastRound: 1, rounds: 7, wantCode: "tournament_paired"},
+		{name: "same settings after pairing", user: organizer, lastRound: 1, rounds: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			store := newTournamentStore(organizer)
+			store.lastRound = tt.lastRound
+			store.permissions[admin] = []string{permissionTournamentsWrite, permissionTournamentsManage}
+
+			app := &application{store: store, validator: newValidator()}
+
+			_, err := serveTournament(app.updateTournamentHandler, http.MethodPut, updateBody(tt.rounds), tt.user)
+
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("updateTournamentHandler() error = %v, want %q", err, tt.wantCode)
+			}
+
+			if tt.wantCode == "" && store.tournament.Rounds != int32(tt.rounds) {
+				t.Errorf("rounds = %d, want %d", store.tournament.Rounds, tt.rounds)
+			}
+		})
+	}
+}
+
+func TestDeleteTournament(t *testing.T) {
+
+	organizer, other := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name     string
+		user     uuid.UUID
+		status   string
+		wantCode string
+	}{
+		{name: "scheduled", user: organizer, status: db.TournamentScheduled},
+		{name: "other organizer", user: other, status: db.TournamentScheduled, wantCode: "forbidden"},
+		{name: "ongoing", user: organizer, status: db.TournamentOngoing, wantCode: "tournament_started"},
+		{name: "finished", user: organizer, status: db.TournamentFinished, wantCode: "tournament_started"},
+		{name: "cancelled", user: organizer, status: db.TournamentCancelled, wantCode: "tournament_started"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			store := newTournamentStore(organizer)
+			store.tournament.Status = tt.status
+
+			app := &application{store: store}
+
+			_, err := serveTournament(app.deleteTournamentHandler, http.MethodDelete, "", tt.user)
+
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("deleteTournamentHandler() error = %v, want %q", err, tt.wantCode)
+			}
+
+			if store.deleted != (tt.wantCode == "") {
+				t.Errorf("deleted = %t, want %t", store.deleted, tt.wantCode == "")
+			}
+		})
+	}
+}
+
+func TestTournamentPlayers(t *testing.T) {
+
+	organizer, other, admin := uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name     string
+		user     uuid.UUID
+		wantCode string
+	}{
+		{name: "organizer", user: organizer},
+		{name: "other organizer", user: other, wantCode: "forbidden"},
+		{name: "tournaments manager", user: admin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			store := newTournamentStore(organizer)
+			store.permissions[admin] = []string{permissionTournamentsWrite, permissionTournamentsManage}
+			store.players = []db.TournamentPlayer{{ID: 1, TournamentID: 1, Name: "Juma"}}
+
+			app := &application{store: store, validator: newValidator()}
+
+			_, err := serveTournament(app.addTournamentPlayerHandler, http.MethodPost, `{"name":"Asha","rating":1500}`, tt.user)
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("addTournamentPlayerHandler() error = %v, want %q", err, tt.wantCode)
+			}
+
+			_, err = serveTournament(app.removeTournamentPlayerHandler, http.MethodDelete, "", tt.user, "player", "1")
+			if code := errorCode(err); code != tt.wantCode {
+				t.Fatalf("removeTournamentPlayerHandler() error = %v, want %q", err, tt.wantCode)
+			}
+
+			if allowed := tt.wantCode == ""; (len(store.players) == 2) != allowed || (len(store.withdrawn) == 1) != allowed {
+				t.Errorf("players = %d, withdrawn = %v after %s", len(store.players), store.withdrawn, tt.name)
+			}
+		})
+	}
+}
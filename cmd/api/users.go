@@ -2,28 +2,20 @@ package main
 
 import (
 	"context"
-	"crypto/sha256"
 	"database/sql"
 	"errors"
-	"fmt"
-	"io"
-	"log/slog"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strconv"
-	"strings"
+	"time"
 
 	db "api.swahilichess.com/internal/db/sqlc"
 	"api.swahilichess.com/internal/passcode"
+	"api.swahilichess.com/internal/phone"
 	"api.swahilichess.com/internal/token"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"golang.org/x/crypto/bcrypt"
 )
 
-const image_upload_path = "/var/www/lugano/images"
-const base_image_url = "https://images.swahilichess.com"
 const default_image = "https://images.swahilichess.com/pawn.png"
 const duplicate_phone = `pq: duplicate key value violates unique constraint "users_phone_number_key"`
 const duplicate_username = `pq: duplicate key value violates unique constraint "users_username_key"`
@@ -34,7 +26,7 @@ type input struct {
 	Fullname         string `json:"fullname" validate:"required,min=3"`
 	LichessUsername  string `json:"lichess_username"`
 	ChesscomUsername string `json:"chesscom_username"`
-	PhoneNumber      string `json:"phone_number"`
+	PhoneNumber      string `json:"phone_number" validate:"required"`
 	Photo            string `json:"photo"`
 }
 
@@ -49,99 +41,69 @@ func (app *application) registerUserHandler(c echo.Context) error {
 	inp.PhoneNumber = c.FormValue("phone_number")
 
 	if err := app.validator.Struct(inp); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return errValidation(err)
 	}
 
-	is_file_uploaded := true
-	image_url := default_image
-
-	file, err := c.FormFile("photo")
+	phoneNumber, err := phone.Parse(inp.PhoneNumber)
 	if err != nil {
-		if err == http.ErrMissingFile {
-			is_file_uploaded = false
-		}
-
-		if strings.Contains(strings.ToLower(err.Error()), "too large") {
-			return c.JSON(http.StatusRequestEntityTooLarge, "File too large")
-		}
-
-		if is_file_uploaded {
-			slog.Error("failed processing file upload", "error", err.Error())
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
-		}
+		return errBadRequest("invalid_phone_number", "invalid phone number")
+	}
+	inp.PhoneNumber = phoneNumber
 
+	file, err := formPhoto(c)
+	if err != nil {
+		return err
 	}
 
-	if is_file_uploaded {
-		src, err := file.Open()
+	image_url := default_image
+	if file != nil {
+		image_url, err = app.savePhoto(c.Request().Context(), file)
 		if err != nil {
 			return err
 		}
-		defer src.Close()
-
-		uniqueID := uuid.New()
-		imageExt := filepath.Ext(file.Filename)
-		image := fmt.Sprintf("%s%s", strings.Replace(uniqueID.String(), "-", "", -1), imageExt)
-		image_url = fmt.Sprintf("%s/%s", base_image_url, image)
-
-		dst, err := os.Create(fmt.Sprintf("%s/%s", image_upload_path, image))
-		if err != nil {
-			slog.Error("failed to create path for upload", "error", err.Error())
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
-		}
-		defer dst.Close()
-
-		if _, err = io.Copy(dst, src); err != nil {
-			slog.Error("failed to copy to path for upload", "error", err.Error())
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
-		}
 	}
 
 	password_hash, err := bcrypt.GenerateFromPassword([]byte(inp.Password), 6)
 	if err != nil {
-		slog.Error("Error hashing password ", "Error", err.Error())
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
-	}
-
-	passcode, hash := passcode.HashPasscode()
-
-	args := db.CreateUserParams{
-		Username:         inp.Username,
-		FullName:         inp.Fullname,
-		LichessUsername:  inp.LichessUsername,
-		ChesscomUsername: inp.ChesscomUsername,
-		PhoneNumber:      inp.PhoneNumber,
-		Photo:            image_url,
-		Passcode:         hash[:],
-		PasswordHash:     password_hash,
-		Activated:        false,
-		Enabled:          false,
-	}
-
-	user, err := app.store.CreateUser(c.Request().Context(), args)
+		return errInternal("error hashing password", err)
+	}
+
+	args := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:         inp.Username,
+			FullName:         inp.Fullname,
+			LichessUsername:  inp.LichessUsername,
+			ChesscomUsername: inp.ChesscomUsername,
+			PhoneNumber:      inp.PhoneNumber,
+			Photo:            image_url,
+			PasswordHash:     password_hash,
+			Activated:        false,
+			Enabled:          false,
+		},
+		Role: rolePlayer,
+		// the user is only created if the activation sms could be queued
+		AfterCreate: func(q db.Querier, user db.CreateUserRow) error {
+			return app.sendPasscode(c.Request().Context(), q, user.ID, passcode.PurposeActivation, "", user.PhoneNumber, activationSMS)
+		},
+	}
+
+	_, err = app.store.CreateUserTx(c.Request().Context(), args)
 	if err != nil {
+		app.deletePhoto(c.Request().Context(), image_url)
+
 		switch {
 		case err.Error() == duplicate_phone:
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "phone number already exists"})
+			return errBadRequest("phone_number_taken", "phone number already exists")
 
 		case err.Error() == duplicate_username:
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "username already exists"})
+			return errBadRequest("username_taken", "username already exists")
 
 		default:
-			slog.Error("failed to create user ", "error", err.Error())
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			return errInternal("failed to create user", err)
 		}
 
 	}
 
-	msg := fmt.Sprintf("Code: %d \nUse it to activate your swahilichess account.", passcode)
-	app.background(func() {
-		err = app.nextsms.SendSmS(msg, user.PhoneNumber)
-		if err != nil {
-			slog.Error("error sending sms", "error", err)
-		}
-	})
-
 	return c.JSON(http.StatusCreated, map[string]string{"success": "user created successful"})
 
 }
@@ -151,39 +113,56 @@ func (app *application) activateUserHandler(c echo.Context) error {
 	var input struct {
 		PhoneNumber string `json:"phone_number" `
 		Username    string `json:"username" `
-		Passcode    int32  `json:"passcode"`
+		Passcode    int32  `json:"passcode" validate:"required"`
 	}
 
 	if err := c.Bind(&input); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return errBind(err)
 	}
 
 	if err := app.validator.Struct(input); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return errValidation(err)
 	}
 
-	numStr := strconv.Itoa(int(input.Passcode))
-	data := []byte(numStr)
+	if input.PhoneNumber == "" && input.Username == "" {
+		return errBadRequest("missing_identifier", "phone number or username is required")
+	}
 
-	hash := sha256.Sum256(data)
+	phoneNumber, err := normalizePhoneNumber(input.PhoneNumber)
+	if err != nil {
+		return errBadRequest("invalid_phone_number", "invalid phone number")
+	}
+	input.PhoneNumber = phoneNumber
 
-	params := db.GetUserForResetOrActivationParams{
+	params := db.GetUserByUsernameOrPhoneParams{
 		PhoneNumber: input.PhoneNumber,
 		Username:    input.Username,
-		Passcode:    hash[:],
 	}
 
-	user, err := app.store.GetUserForResetOrActivation(c.Request().Context(), params)
+	user, err := app.store.GetUserByUsernameOrPhone(c.Request().Context(), params)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "passcode doesn't exist or user arleady activated"})
+			return app.unknownAccount(c, "passcode", errBadRequest("invalid_passcode", "invalid passcode"))
 		default:
-			slog.Error("failed to get user by passcode ", "error", err.Error())
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			return errInternal("failed to get user by phone or username", err)
 		}
 	}
 
+	key := lockoutKey("passcode", user.ID)
+	if locked, err := app.checkLockout(c, key); locked {
+		return err
+	}
+
+	if user.Activated {
+		return errBadRequest("user_already_activated", "user already activated")
+	}
+
+	_, err = app.otp.Verify(c.Request().Context(), user.ID, passcode.PurposeActivation, int(input.Passcode))
+	if err != nil {
+		return app.passcodeError(c, key, err)
+	}
+
 	args := db.UpdateUserByIdParams{
 		Username:         user.Username,
 		FullName:         user.FullName,
@@ -191,7 +170,6 @@ func (app *application) activateUserHandler(c echo.Context) error {
 		ChesscomUsername: user.ChesscomUsername,
 		PhoneNumber:      user.PhoneNumber,
 		Photo:            user.Photo,
-		Passcode:         []byte{},
 		PasswordHash:     user.PasswordHash,
 		Activated:        true,
 		Enabled:          true,
@@ -200,33 +178,25 @@ func (app *application) activateUserHandler(c echo.Context) error {
 
 	err = app.store.UpdateUserById(context.Background(), args)
 	if err != nil {
-		slog.Error("failed to update user on activate", "error", err.Error())
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		return errInternal("failed to update user on activate", err)
 	}
 
-	token, expiry, err := token.New(user.ID, app.store, token.ScopeAuthentication)
+	app.resetFailures(c, key)
 
-	if err != nil {
-		slog.Error("failed to create token", "error", err.Error())
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
-	}
+	pair, err := token.NewPair(c.Request().Context(), user.ID, app.store, tokenMetadata(c))
 
-	res := struct {
-		Token  string `json:"token"`
-		Expiry int64  `json:"expiry"`
-	}{
-		Token:  token,
-		Expiry: expiry.Unix(),
+	if err != nil {
+		return errInternal("failed to create token", err)
 	}
 
-	return c.JSON(200, res)
+	return c.JSON(200, tokenResponse(pair))
 }
 
 func (app *application) updateUserHandler(c echo.Context) error {
 
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid uuid"})
+		return errBadRequest("invalid_id", "invalid uuid")
 	}
 	password := c.FormValue("password")
 	fullname := c.FormValue("fullname")
@@ -235,8 +205,12 @@ func (app *application) updateUserHandler(c echo.Context) error {
 
 	user, err := app.store.GetUserById(context.Background(), id)
 	if err != nil {
-		slog.Error("failed to get user by id", "error", err.Error())
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return errNotFound("user_not_found", "user not found")
+		default:
+			return errInternal("failed to get user by id", err)
+		}
 	}
 
 	if fullname != "" {
@@ -248,61 +222,29 @@ func (app *application) updateUserHandler(c echo.Context) error {
 	if chesscomUsername != "" {
 		user.ChesscomUsername = chesscomUsername
 	}
-	is_file_uploaded := true
-
-	file, err := c.FormFile("photo")
-	if err != nil {
-		if err == http.ErrMissingFile {
-			is_file_uploaded = false
-		}
-
-		if strings.Contains(strings.ToLower(err.Error()), "too large") {
-			return c.JSON(http.StatusRequestEntityTooLarge, "File too large")
-		}
-
-		slog.Error("failed processing file upload", "error", err.Error())
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
-	}
-	image_url := ""
-
-	if is_file_uploaded {
-		src, err := file.Open()
-		if err != nil {
-			return err
+	if password != "" {
+		if len(password) < 6 {
+			return errBadRequest("password_too_short", "password short (less than 6)")
 		}
-		defer src.Close()
-
-		uniqueID := uuid.New()
-		imageExt := filepath.Ext(file.Filename)
-		image := fmt.Sprintf("%s%s", strings.Replace(uniqueID.String(), "-", "", -1), imageExt)
-		image_url = fmt.Sprintf("%s/%s", base_image_url, image)
-
-		dst, err := os.Create(fmt.Sprintf("%s/%s", image_upload_path, image))
+		password_hash, err := bcrypt.GenerateFromPassword([]byte(password), 6)
 		if err != nil {
-			slog.Error("failed to create path for upload", "error", err.Error())
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			return errInternal("error hashing password", err)
 		}
-		defer dst.Close()
+		user.PasswordHash = password_hash
 
-		if _, err = io.Copy(dst, src); err != nil {
-			slog.Error("failed to copy to path for upload", "error", err.Error())
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
-		}
+	}
 
-		user.Photo = image_url
+	file, err := formPhoto(c)
+	if err != nil {
+		return err
 	}
 
-	if password != "" {
-		if len(password) < 6 {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "password short (less than 6)"})
-		}
-		password_hash, err := bcrypt.GenerateFromPassword([]byte(password), 6)
+	previousPhoto := user.Photo
+	if file != nil {
+		user.Photo, err = app.savePhoto(c.Request().Context(), file)
 		if err != nil {
-			slog.Error("Error hashing password ", "Error", err.Error())
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			return err
 		}
-		user.PasswordHash = password_hash
-
 	}
 
 	args := db.UpdateUserByIdParams{
@@ -312,7 +254,6 @@ func (app *application) updateUserHandler(c echo.Context) error {
 		ChesscomUsername: user.ChesscomUsername,
 		PhoneNumber:      user.PhoneNumber,
 		Photo:            user.Photo,
-		Passcode:         user.Passcode,
 		PasswordHash:     user.PasswordHash,
 		Activated:        user.Activated,
 		Enabled:          user.Enabled,
@@ -321,8 +262,14 @@ func (app *application) updateUserHandler(c echo.Context) error {
 
 	err = app.store.UpdateUserById(context.Background(), args)
 	if err != nil {
-		slog.Error("failed to update user details", "error", err.Error())
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		if user.Photo != previousPhoto {
+			app.deletePhoto(c.Request().Context(), user.Photo)
+		}
+		return errInternal("failed to update user details", err)
+	}
+
+	if user.Photo != previousPhoto {
+		app.deletePhoto(c.Request().Context(), previousPhoto)
 	}
 
 	return c.JSON(http.StatusOK, map[string]string{"success": "user updated successfuly"})
@@ -337,9 +284,19 @@ func (app *application) forgotPasswordUserHandler(c echo.Context) error {
 	}
 
 	if err := c.Bind(&input); err != nil {
-		c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return errBind(err)
 	}
 
+	if input.PhoneNumber == "" && input.Username == "" {
+		return errBadRequest("missing_identifier", "phone number or username is required")
+	}
+
+	phoneNumber, err := normalizePhoneNumber(input.PhoneNumber)
+	if err != nil {
+		return errBadRequest("invalid_phone_number", "invalid phone number")
+	}
+	input.PhoneNumber = phoneNumber
+
 	params := db.GetUserByUsernameOrPhoneParams{
 		PhoneNumber: input.PhoneNumber,
 		Username:    input.Username,
@@ -349,47 +306,23 @@ func (app *application) forgotPasswordUserHandler(c echo.Context) error {
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "username or phone number doesn't exist "})
+			return errBadRequest("user_not_found", "username or phone number doesn't exist")
 		default:
-			slog.Error("failed to get user by phone or username ", "error", err.Error())
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			return errInternal("failed to get user by phone or username", err)
 		}
 	}
 
 	if !(user.Activated && user.Enabled) {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "user not enabled or activated"})
-	}
-
-	passcode, hash := passcode.HashPasscode()
-
-	args := db.UpdateUserByIdParams{
-		Username:         user.Username,
-		FullName:         user.FullName,
-		LichessUsername:  user.LichessUsername,
-		ChesscomUsername: user.ChesscomUsername,
-		PhoneNumber:      user.PhoneNumber,
-		Photo:            user.Photo,
-		Passcode:         hash[:],
-		PasswordHash:     user.PasswordHash,
-		Activated:        true,
-		Enabled:          true,
-		ID:               user.ID,
+		return errBadRequest("user_not_activated", "user not enabled or activated")
 	}
 
-	err = app.store.UpdateUserById(context.Background(), args)
+	err = app.store.ExecTx(c.Request().Context(), func(q db.Querier) error {
+		return app.sendPasscode(c.Request().Context(), q, user.ID, passcode.PurposePasswordReset, "", user.PhoneNumber, passwordResetSMS)
+	})
 	if err != nil {
-		slog.Error("failed to update user on forgot password", "error", err.Error())
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		return errInternal("failed to send password reset passcode", err)
 	}
 
-	msg := fmt.Sprintf("Code: %d \nUse it to reset password for your swahilichess account.", passcode)
-	app.background(func() {
-		err = app.nextsms.SendSmS(msg, user.PhoneNumber)
-		if err != nil {
-			slog.Error("error sending sms", "error", err)
-		}
-	})
-
 	return c.JSON(200, nil)
 }
 
@@ -398,43 +331,56 @@ func (app *application) changePasswordUserHandler(c echo.Context) error {
 	var input struct {
 		PhoneNumber string `json:"phone_number" `
 		Username    string `json:"username" `
-		Passcode    int32  `json:"passcode"`
+		Passcode    int32  `json:"passcode" validate:"required"`
 		Password    string `json:"password" validate:"required,min=6"`
 	}
 
 	if err := c.Bind(&input); err != nil {
-		c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return errBind(err)
 	}
 
 	if err := app.validator.Struct(input); err != nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return errValidation(err)
 	}
 
-	numStr := strconv.Itoa(int(input.Passcode))
-	data := []byte(numStr)
+	if input.PhoneNumber == "" && input.Username == "" {
+		return errBadRequest("missing_identifier", "phone number or username is required")
+	}
 
-	hash := sha256.Sum256(data)
+	phoneNumber, err := normalizePhoneNumber(input.PhoneNumber)
+	if err != nil {
+		return errBadRequest("invalid_phone_number", "invalid phone number")
+	}
+	input.PhoneNumber = phoneNumber
 
-	params := db.GetUserForResetOrActivationParams{
+	params := db.GetUserByUsernameOrPhoneParams{
 		PhoneNumber: input.PhoneNumber,
 		Username:    input.Username,
-		Passcode:    hash[:],
 	}
-	user, err := app.store.GetUserForResetOrActivation(c.Request().Context(), params)
+
+	user, err := app.store.GetUserByUsernameOrPhone(c.Request().Context(), params)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "passcode doesn't exist"})
+			return app.unknownAccount(c, "passcode", errBadRequest("invalid_passcode", "invalid passcode"))
 		default:
-			slog.Error("failed to get user by phone or username ", "error", err.Error())
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			return errInternal("failed to get user by phone or username", err)
 		}
 	}
 
+	key := lockoutKey("passcode", user.ID)
+	if locked, err := app.checkLockout(c, key); locked {
+		return err
+	}
+
+	_, err = app.otp.Verify(c.Request().Context(), user.ID, passcode.PurposePasswordReset, int(input.Passcode))
+	if err != nil {
+		return app.passcodeError(c, key, err)
+	}
+
 	password_hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), 6)
 	if err != nil {
-		slog.Error("Error hashing password ", "Error", err.Error())
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		return errInternal("error hashing password", err)
 	}
 
 	args := db.UpdateUserByIdParams{
@@ -444,26 +390,26 @@ func (app *application) changePasswordUserHandler(c echo.Context) error {
 		ChesscomUsername: user.ChesscomUsername,
 		PhoneNumber:      user.PhoneNumber,
 		Photo:            user.Photo,
-		Passcode:         []byte{},
 		PasswordHash:     password_hash,
 		Activated:        user.Activated,
 		Enabled:          user.Enabled,
 		ID:               user.ID,
 	}
 
-	err = app.store.UpdateUserById(context.Background(), args)
-	if err != nil {
-		slog.Error("failed to update password", "error", err.Error())
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
-	}
+	err = app.store.ExecTx(context.Background(), func(q db.Querier) error {
 
-	msg := "Password changed successfully"
-	app.background(func() {
-		err = app.nextsms.SendSmS(msg, user.PhoneNumber)
+		err := q.UpdateUserById(context.Background(), args)
 		if err != nil {
-			slog.Error("error sending sms", "error", err)
+			return err
 		}
+
+		return app.enqueueSMS(context.Background(), q, "Password changed successfully", user.PhoneNumber, time.Time{})
 	})
+	if err != nil {
+		return errInternal("failed to update password", err)
+	}
+
+	app.resetFailures(c, key)
 
 	return c.JSON(200, nil)
 }
@@ -476,9 +422,19 @@ func (app *application) resendactivationHandler(c echo.Context) error {
 	}
 
 	if err := c.Bind(&input); err != nil {
-		c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return errBind(err)
+	}
+
+	if input.PhoneNumber == "" && input.Username == "" {
+		return errBadRequest("missing_identifier", "phone number or username is required")
 	}
 
+	phoneNumber, err := normalizePhoneNumber(input.PhoneNumber)
+	if err != nil {
+		return errBadRequest("invalid_phone_number", "invalid phone number")
+	}
+	input.PhoneNumber = phoneNumber
+
 	params := db.GetUserByUsernameOrPhoneParams{
 		PhoneNumber: input.PhoneNumber,
 		Username:    input.Username,
@@ -488,46 +444,41 @@ func (app *application) resendactivationHandler(c echo.Context) error {
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "username or phone number doesn't exist "})
+			return errBadRequest("user_not_found", "username or phone number doesn't exist")
 		default:
-			slog.Error("failed to get user by phone or username ", "error", err.Error())
-			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			return errInternal("failed to get user by phone or username", err)
 		}
 	}
 
 	if user.Activated {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "user already activated"})
+		return errBadRequest("user_already_activated", "user already activated")
 	}
 
-	passcode, hash := passcode.HashPasscode()
-
-	args := db.UpdateUserByIdParams{
-		Username:         user.Username,
-		FullName:         user.FullName,
-		LichessUsername:  user.LichessUsername,
-		ChesscomUsername: user.ChesscomUsername,
-		PhoneNumber:      user.PhoneNumber,
-		Photo:            user.Photo,
-		Passcode:         hash[:],
-		PasswordHash:     user.PasswordHash,
-		Activated:        user.Activated,
-		Enabled:          user.Enabled,
-		ID:               user.ID,
-	}
-
-	err = app.store.UpdateUserById(context.Background(), args)
+	err = app.store.ExecTx(c.Request().Context(), func(q db.Querier) error {
+		return app.sendPasscode(c.Request().Context(), q, user.ID, passcode.PurposeActivation, "", user.PhoneNumber, activationSMS)
+	})
 	if err != nil {
-		slog.Error("failed to update user", "error", err.Error())
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		return errInternal("failed to send activation passcode", err)
 	}
 
-	msg := fmt.Sprintf("Code: %d \nUse it to activate your swahilichess account.", passcode)
-	app.background(func() {
-		err = app.nextsms.SendSmS(msg, user.PhoneNumber)
-		if err != nil {
-			slog.Error("error sending sms", "error", err)
-		}
-	})
-
 	return c.JSON(200, map[string]string{"success": "resent activation"})
 }
+
+// passcodeError maps a failed passcode verification to an error response,
+// counting wrong guesses towards the lockout.
+func (app *application) passcodeError(c echo.Context, key string, err error) error {
+	switch {
+	case errors.Is(err, passcode.ErrNotFound), errors.Is(err, passcode.ErrMismatch):
+		app.recordFailure(c, key)
+		return errBadRequest("invalid_passcode", "invalid passcode")
+
+	case errors.Is(err, passcode.ErrExpired):
+		return errBadRequest("passcode_expired", "passcode expired, request a new one")
+
+	case errors.Is(err, passcode.ErrTooManyAttempts):
+		return errBadRequest("passcode_attempts_exceeded", "too many attempts, request a new passcode")
+
+	default:
+		return errInternal("failed to verify passcode", err)
+	}
+}
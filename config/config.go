@@ -24,6 +24,75 @@ type Config struct {
 		MaxIdleTime  string
 	}
 
+	RateLimit struct {
+		Enabled       bool
+		Requests      int
+		Window        time.Duration
+		Routes        string
+		MaxFailures   int
+		LockoutBase   time.Duration
+		LockoutMax    time.Duration
+		FailureReset  time.Duration
+		PruneInterval time.Duration
+	}
+
+	OTP struct {
+		Secret      string
+		TTL         time.Duration
+		MaxAttempts int
+	}
+
+	Jobs struct {
+		Workers      int
+		PollInterval time.Duration
+		MaxAttempts  int
+		Retention    time.Duration
+	}
+
+	Lichess struct {
+		URL              string
+		Token            string
+		TeamID           string
+		TeamSyncInterval time.Duration
+		ClientID         string
+		RedirectURL      string
+		LinkRedirectURL  string
+	}
+
+	Chesscom struct {
+		URL             string
+		ClubID          string
+		RefreshInterval time.Duration
+		RefreshJitter   time.Duration
+	}
+
+	Leaderboard struct {
+		RefreshInterval   time.Duration
+		RefreshJitter     time.Duration
+		RefreshTimeout    time.Duration
+		SnapshotInterval  time.Duration
+		SnapshotRetention time.Duration
+	}
+
+	Storage struct {
+		Backend   string
+		LocalDir  string
+		PublicURL string
+		S3        struct {
+			Endpoint  string
+			Region    string
+			Bucket    string
+			AccessKey string
+			SecretKey string
+			PathStyle bool
+		}
+	}
+
+	SMS struct {
+		Provider string
+		FakeFile string
+	}
+
 	NextSmS struct {
 		Username string
 		Password string
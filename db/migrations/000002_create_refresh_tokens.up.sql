@@ -0,0 +1,11 @@
+-- Tokens issued together (an access token and the refresh tokens that rotate
+-- from the same login) share a family so the whole chain can be revoked.
+ALTER TABLE token ADD COLUMN IF NOT EXISTS family_id uuid NOT NULL DEFAULT uuid_generate_v4();
+ALTER TABLE token ADD COLUMN IF NOT EXISTS rotated bool NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS token_family_id_idx ON token (family_id);
+
+-- Long lived authentication tokens are replaced by short lived access tokens.
+-- Deleting them signs every user out when this migration is deployed, clients
+-- have to log in again to get a refresh token.
+DELETE FROM token WHERE scope = 'authentication';
@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS roles (
+    name text PRIMARY KEY,
+    description text NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    code text PRIMARY KEY,
+    description text NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role text NOT NULL REFERENCES roles ON DELETE CASCADE,
+    permission text NOT NULL REFERENCES permissions ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id uuid NOT NULL REFERENCES users ON DELETE CASCADE,
+    role text NOT NULL REFERENCES roles ON DELETE CASCADE,
+    granted_by uuid REFERENCES users ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, role)
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('player', 'Registered player'),
+    ('club_admin', 'Runs club events'),
+    ('arbiter', 'Records and corrects results'),
+    ('federation_admin', 'Manages users and roles');
+
+INSERT INTO permissions (code, description) VALUES
+    ('users:write', 'Update any user profile'),
+    ('roles:write', 'Grant and revoke roles'),
+    ('tournaments:write', 'Create and manage tournaments'),
+    ('results:write', 'Submit and correct game results');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('club_admin', 'tournaments:write'),
+    ('arbiter', 'results:write'),
+    ('federation_admin', 'users:write'),
+    ('federation_admin', 'roles:write'),
+    ('federation_admin', 'tournaments:write'),
+    ('federation_admin', 'results:write');
+
+INSERT INTO user_roles (user_id, role) SELECT id, 'player' FROM users;
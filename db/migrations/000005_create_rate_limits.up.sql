@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key text PRIMARY KEY,
+    hits int NOT NULL DEFAULT 0,
+    window_start timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS login_failures (
+    key text PRIMARY KEY,
+    failures int NOT NULL DEFAULT 0,
+    locked_until timestamp(0) with time zone,
+    last_failure_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
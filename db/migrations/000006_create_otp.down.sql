@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS passcode bytea NOT NULL DEFAULT '\x';
+
+DROP TABLE IF EXISTS otp;
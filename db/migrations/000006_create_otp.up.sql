@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS otp (
+    id bigserial PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES users ON DELETE CASCADE,
+    purpose text NOT NULL,
+    code_hash bytea NOT NULL,
+    attempts int NOT NULL DEFAULT 0,
+    max_attempts int NOT NULL,
+    expiry timestamp(0) with time zone NOT NULL,
+    consumed_at timestamp(0) with time zone,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS otp_user_id_purpose_idx ON otp (user_id, purpose);
+
+ALTER TABLE users DROP COLUMN IF EXISTS passcode;
@@ -0,0 +1,2 @@
+-- The value a passcode confirms, e.g. the new phone number of a phone change.
+ALTER TABLE otp ADD COLUMN IF NOT EXISTS target text NOT NULL DEFAULT '';
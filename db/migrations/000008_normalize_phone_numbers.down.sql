@@ -0,0 +1,2 @@
+-- Irreversible, see the up migration. Normalized phone numbers are kept, the
+-- original formatting is not recoverable.
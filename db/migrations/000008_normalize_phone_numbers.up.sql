@@ -0,0 +1,14 @@
+-- Irreversible: the down migration can't tell rewritten numbers from ones
+-- stored in E.164 already, so it leaves every number as it is.
+--
+-- Best effort rewrite of stored Tanzanian numbers to E.164, numbers that would
+-- collide with an already normalized one are left for manual cleanup. Only
+-- local 06/07 numbers and East African numbers missing their + are rewritten,
+-- numbers written with spaces, dashes or a 00 prefix are left as they are.
+UPDATE users SET phone_number = '+255' || substr(phone_number, 2)
+WHERE phone_number ~ '^0[67][0-9]{8}$'
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.phone_number = '+255' || substr(users.phone_number, 2));
+
+UPDATE users SET phone_number = '+' || phone_number
+WHERE phone_number ~ '^25[0-7][0-9]{8,9}$'
+AND NOT EXISTS (SELECT 1 FROM users u WHERE u.phone_number = '+' || users.phone_number);
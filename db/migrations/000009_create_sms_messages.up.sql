@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS sms_messages (
+    id bigserial PRIMARY KEY,
+    reference uuid UNIQUE NOT NULL,
+    provider text NOT NULL,
+    recipient text NOT NULL,
+    provider_message_id text NOT NULL DEFAULT '',
+    status text NOT NULL,
+    status_description text NOT NULL DEFAULT '',
+    sms_count int NOT NULL DEFAULT 0,
+    cost double precision NOT NULL DEFAULT 0,
+    error text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS sms_messages_provider_message_id_idx ON sms_messages (provider_message_id);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS leaderboard_snapshots (
+    id bigserial PRIMARY KEY,
+    taken_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS leaderboard_snapshots_taken_at_idx ON leaderboard_snapshots (taken_at);
+
+CREATE TABLE IF NOT EXISTS leaderboard_entries (
+    snapshot_id bigint NOT NULL REFERENCES leaderboard_snapshots ON DELETE CASCADE,
+    username citext NOT NULL,
+    variant text NOT NULL,
+    rank int NOT NULL,
+    rating int NOT NULL,
+    games int NOT NULL,
+    rd int NOT NULL,
+    prog int NOT NULL,
+    prov bool NOT NULL,
+    PRIMARY KEY (snapshot_id, variant, username)
+);
+
+CREATE INDEX IF NOT EXISTS leaderboard_entries_username_variant_idx ON leaderboard_entries (username, variant);
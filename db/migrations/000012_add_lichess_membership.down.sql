@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS lichess_membership_events;
+
+ALTER TABLE lichess DROP COLUMN IF EXISTS left_at;
@@ -0,0 +1,11 @@
+ALTER TABLE lichess ADD COLUMN IF NOT EXISTS left_at timestamp(0) with time zone;
+
+CREATE TABLE IF NOT EXISTS lichess_membership_events (
+    id bigserial PRIMARY KEY,
+    lichess_id text NOT NULL,
+    username text NOT NULL,
+    event text NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS lichess_membership_events_lichess_id_idx ON lichess_membership_events (lichess_id);
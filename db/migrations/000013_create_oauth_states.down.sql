@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS oauth_states;
+
+ALTER TABLE users DROP COLUMN IF EXISTS lichess_id;
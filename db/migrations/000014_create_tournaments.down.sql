@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS tournament_players;
+DROP TABLE IF EXISTS tournaments;
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS tournaments (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    format text NOT NULL,
+    time_control text NOT NULL,
+    rounds integer NOT NULL DEFAULT 0,
+    online bool NOT NULL DEFAULT false,
+    venue text NOT NULL DEFAULT '',
+    starts_at timestamp(0) with time zone NOT NULL,
+    registration_opens_at timestamp(0) with time zone NOT NULL,
+    registration_closes_at timestamp(0) with time zone NOT NULL,
+    max_players integer NOT NULL DEFAULT 0,
+    status text NOT NULL DEFAULT 'scheduled',
+    created_by uuid REFERENCES users ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS tournaments_starts_at_idx ON tournaments (starts_at);
+
+-- user_id is empty for guests added by the organizers
+CREATE TABLE IF NOT EXISTS tournament_players (
+    id bigserial PRIMARY KEY,
+    tournament_id bigint NOT NULL REFERENCES tournaments ON DELETE CASCADE,
+    user_id uuid REFERENCES users ON DELETE SET NULL,
+    name text NOT NULL,
+    rating integer NOT NULL DEFAULT 0,
+    status text NOT NULL DEFAULT 'registered',
+    registered_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (tournament_id, user_id)
+);
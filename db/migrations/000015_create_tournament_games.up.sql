@@ -0,0 +1,13 @@
+-- black_id is empty for a bye, result stays empty until the game is reported
+CREATE TABLE IF NOT EXISTS tournament_games (
+    id bigserial PRIMARY KEY,
+    tournament_id bigint NOT NULL REFERENCES tournaments ON DELETE CASCADE,
+    round integer NOT NULL,
+    board integer NOT NULL,
+    white_id bigint NOT NULL REFERENCES tournament_players ON DELETE CASCADE,
+    black_id bigint REFERENCES tournament_players ON DELETE CASCADE,
+    result text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    updated_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (tournament_id, round, board)
+);
@@ -0,0 +1,7 @@
+DELETE FROM tournament_games WHERE game > 1;
+
+ALTER TABLE tournament_games DROP CONSTRAINT IF EXISTS tournament_games_tournament_id_round_board_game_key;
+ALTER TABLE tournament_games ADD CONSTRAINT tournament_games_tournament_id_round_board_key UNIQUE (tournament_id, round, board);
+
+ALTER TABLE tournament_games DROP COLUMN IF EXISTS kind;
+ALTER TABLE tournament_games DROP COLUMN IF EXISTS game;
@@ -0,0 +1,7 @@
+-- knockout boards hold a match: the regular game then tiebreak or armageddon
+-- games until it is decided
+ALTER TABLE tournament_games ADD COLUMN IF NOT EXISTS game integer NOT NULL DEFAULT 1;
+ALTER TABLE tournament_games ADD COLUMN IF NOT EXISTS kind text NOT NULL DEFAULT 'regular';
+
+ALTER TABLE tournament_games DROP CONSTRAINT IF EXISTS tournament_games_tournament_id_round_board_key;
+ALTER TABLE tournament_games ADD CONSTRAINT tournament_games_tournament_id_round_board_game_key UNIQUE (tournament_id, round, board, game);
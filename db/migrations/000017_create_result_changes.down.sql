@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS result_changes;
+
+ALTER TABLE tournaments DROP COLUMN IF EXISTS tiebreaks;
@@ -0,0 +1,17 @@
+-- tiebreaks lists the tiebreak names in the order they apply
+ALTER TABLE tournaments ADD COLUMN IF NOT EXISTS tiebreaks text[] NOT NULL DEFAULT '{}';
+
+-- every result entered or corrected on a game, old_result is empty for the
+-- first report
+CREATE TABLE IF NOT EXISTS result_changes (
+    id bigserial PRIMARY KEY,
+    tournament_id bigint NOT NULL REFERENCES tournaments ON DELETE CASCADE,
+    game_id bigint NOT NULL REFERENCES tournament_games ON DELETE CASCADE,
+    old_result text NOT NULL,
+    new_result text NOT NULL,
+    reason text NOT NULL DEFAULT '',
+    changed_by uuid REFERENCES users ON DELETE SET NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS result_changes_tournament_id_idx ON result_changes (tournament_id, created_at);
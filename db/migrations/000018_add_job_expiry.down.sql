@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS jobs_finished_updated_at_idx;
+
+ALTER TABLE jobs DROP COLUMN IF EXISTS expires_at;
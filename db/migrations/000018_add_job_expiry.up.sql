@@ -0,0 +1,5 @@
+-- Jobs that are useless after a point, such as an SMS carrying a passcode,
+-- stop being retried once it passes.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS expires_at timestamp(0) with time zone;
+
+CREATE INDEX IF NOT EXISTS jobs_finished_updated_at_idx ON jobs (updated_at) WHERE status IN ('done', 'dead');
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS jobs_dedupe_key_idx;
+
+ALTER TABLE jobs DROP COLUMN IF EXISTS dedupe_key;
@@ -0,0 +1,5 @@
+-- Jobs queued by every replica, such as the scheduled team sync, carry a key
+-- so only the first replica's job is stored.
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS dedupe_key text;
+
+CREATE UNIQUE INDEX IF NOT EXISTS jobs_dedupe_key_idx ON jobs (dedupe_key) WHERE dedupe_key IS NOT NULL;
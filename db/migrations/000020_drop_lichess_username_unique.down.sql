@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS lichess_username_idx;
+
+-- fails if two members share a username since the up migration
+ALTER TABLE lichess ADD CONSTRAINT lichess_username_key UNIQUE (username);
@@ -0,0 +1,5 @@
+-- Members are identified by lichess_id, a username can move to another id
+-- when an account is closed and the name taken by a new one.
+ALTER TABLE lichess DROP CONSTRAINT IF EXISTS lichess_username_key;
+
+CREATE INDEX IF NOT EXISTS lichess_username_idx ON lichess (username);
@@ -0,0 +1,5 @@
+INSERT INTO permissions (code, description) VALUES
+    ('tournaments:manage', 'Manage tournaments organized by others');
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('federation_admin', 'tournaments:manage');
@@ -0,0 +1,20 @@
+-- the changes of deleted tournaments, games and users can't be kept with the
+-- foreign keys
+DELETE FROM result_changes rc
+WHERE NOT EXISTS (SELECT 1 FROM tournaments t WHERE t.id = rc.tournament_id)
+   OR NOT EXISTS (SELECT 1 FROM tournament_games g WHERE g.id = rc.game_id);
+
+UPDATE result_changes rc SET changed_by = NULL
+WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.id = rc.changed_by);
+
+ALTER TABLE result_changes ADD CONSTRAINT result_changes_tournament_id_fkey
+    FOREIGN KEY (tournament_id) REFERENCES tournaments ON DELETE CASCADE;
+ALTER TABLE result_changes ADD CONSTRAINT result_changes_game_id_fkey
+    FOREIGN KEY (game_id) REFERENCES tournament_games ON DELETE CASCADE;
+ALTER TABLE result_changes ADD CONSTRAINT result_changes_changed_by_fkey
+    FOREIGN KEY (changed_by) REFERENCES users ON DELETE SET NULL;
+
+ALTER TABLE result_changes DROP COLUMN IF EXISTS override;
+ALTER TABLE result_changes DROP COLUMN IF EXISTS game;
+ALTER TABLE result_changes DROP COLUMN IF EXISTS board;
+ALTER TABLE result_changes DROP COLUMN IF EXISTS round;
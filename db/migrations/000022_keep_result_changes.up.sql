@@ -0,0 +1,16 @@
+-- result changes are the audit trail of a tournament's results, they keep
+-- the game's position and outlive the tournament, its games and the user
+ALTER TABLE result_changes ADD COLUMN IF NOT EXISTS round integer NOT NULL DEFAULT 0;
+ALTER TABLE result_changes ADD COLUMN IF NOT EXISTS board integer NOT NULL DEFAULT 0;
+ALTER TABLE result_changes ADD COLUMN IF NOT EXISTS game integer NOT NULL DEFAULT 1;
+
+-- override is set on corrections made after a later round was paired or the
+-- tournament finished
+ALTER TABLE result_changes ADD COLUMN IF NOT EXISTS override boolean NOT NULL DEFAULT false;
+
+UPDATE result_changes rc SET round = g.round, board = g.board, game = g.game
+FROM tournament_games g WHERE g.id = rc.game_id;
+
+ALTER TABLE result_changes DROP CONSTRAINT IF EXISTS result_changes_tournament_id_fkey;
+ALTER TABLE result_changes DROP CONSTRAINT IF EXISTS result_changes_game_id_fkey;
+ALTER TABLE result_changes DROP CONSTRAINT IF EXISTS result_changes_changed_by_fkey;
@@ -0,0 +1,182 @@
+package chesscom
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultURL = "https://api.chess.com"
+
+// chess.com blocks requests without a descriptive user agent.
+const userAgent = "swahilichess-api (+https://swahilichess.com)"
+
+var ErrNotFound = errors.New("chesscom: not found")
+
+// StatusError is returned when chess.com answers with an unexpected status.
+type StatusError struct {
+	Code int
+	Path string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("chesscom: %s returned %d %s", e.Path, e.Code, http.StatusText(e.Code))
+}
+
+type Rating struct {
+	Rating int   `json:"rating"`
+	Date   int64 `json:"date"`
+	Rd     int   `json:"rd"`
+}
+
+type Record struct {
+	Win  int `json:"win"`
+	Loss int `json:"loss"`
+	Draw int `json:"draw"`
+}
+
+type GameStats struct {
+	Last   Rating  `json:"last"`
+	Best   *Rating `json:"best,omitempty"`
+	Record Record  `json:"record"`
+}
+
+// Games is the number of games the rating is based on.
+func (g GameStats) Games() int {
+	return g.Record.Win + g.Record.Loss + g.Record.Draw
+}
+
+type Stats struct {
+	Rapid  *GameStats `json:"chess_rapid,omitempty"`
+	Blitz  *GameStats `json:"chess_blitz,omitempty"`
+	Bullet *GameStats `json:"chess_bullet,omitempty"`
+	Daily  *GameStats `json:"chess_daily,omitempty"`
+}
+
+// Variant returns the stats of rapid, blitz, bullet or daily, nil when the
+// player has none.
+func (s Stats) Variant(variant string) *GameStats {
+	switch variant {
+	case "rapid":
+		return s.Rapid
+	case "blitz":
+		return s.Blitz
+	case "bullet":
+		return s.Bullet
+	case "daily":
+		return s.Daily
+	default:
+		return nil
+	}
+}
+
+type ClubMember struct {
+	Username string `json:"username"`
+	Joined   int64  `json:"joined"`
+}
+
+type Client struct {
+	baseURL    string
+	http       *http.Client
+	MaxRetries int
+	RetryWait  time.Duration
+}
+
+func New(baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+		MaxRetries: 2,
+		RetryWait:  5 * time.Second,
+	}
+}
+
+func (c *Client) Stats(ctx context.Context, username string) (Stats, error) {
+
+	var stats Stats
+	err := c.get(ctx, fmt.Sprintf("/pub/player/%s/stats", url.PathEscape(strings.ToLower(username))), &stats)
+
+	return stats, err
+}
+
+// ClubMembers returns everyone in the club, whatever their activity.
+func (c *Client) ClubMembers(ctx context.Context, clubID string) ([]ClubMember, error) {
+
+	var res struct {
+		Weekly  []ClubMember `json:"weekly"`
+		Monthly []ClubMember `json:"monthly"`
+		AllTime []ClubMember `json:"all_time"`
+	}
+
+	err := c.get(ctx, fmt.Sprintf("/pub/club/%s/members", url.PathEscape(clubID)), &res)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]ClubMember, 0, len(res.Weekly)+len(res.Monthly)+len(res.AllTime))
+	members = append(members, res.Weekly...)
+	members = append(members, res.Monthly...)
+	members = append(members, res.AllTime...)
+
+	return members, nil
+}
+
+// get decodes the JSON response into v, retrying when rate limited.
+func (c *Client) get(ctx context.Context, path string, v any) error {
+
+	for attempt := 0; ; attempt++ {
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("User-Agent", userAgent)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			err = json.NewDecoder(resp.Body).Decode(v)
+			resp.Body.Close()
+			return err
+
+		case resp.StatusCode == http.StatusTooManyRequests && attempt < c.MaxRetries:
+			wait := c.RetryWait
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+				wait = time.Duration(seconds) * time.Second
+			}
+			resp.Body.Close()
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+
+		case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusGone:
+			resp.Body.Close()
+			return ErrNotFound
+
+		default:
+			resp.Body.Close()
+			return &StatusError{Code: resp.StatusCode, Path: path}
+		}
+	}
+}
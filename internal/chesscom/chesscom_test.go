@@ -0,0 +1,110 @@
+package chesscom_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"api.swahilichess.com/internal/chesscom"
+	"api.swahilichess.com/internal/chesscom/chesscomtest"
+)
+
+func TestStats(t *testing.T) {
+
+	srv := chesscomtest.NewServer()
+	defer srv.Close()
+
+	srv.SetStats("juma", chesscom.Stats{
+		Blitz: &chesscom.GameStats{
+			Last:   chesscom.Rating{Rating: 1650, Rd: 60},
+			Record: chesscom.Record{Win: 10, Loss: 5, Draw: 2},
+		},
+	})
+
+	// usernames are case insensitive on chess.com
+	stats, err := srv.Client().Stats(context.Background(), "Juma")
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+
+	blitz := stats.Variant("blitz")
+	if blitz == nil || blitz.Last.Rating != 1650 || blitz.Games() != 17 {
+		t.Errorf("Stats().Variant(blitz) = %+v, want a rating of 1650 over 17 games", blitz)
+	}
+
+	for _, variant := range []string{"rapid", "bullet", "daily", "chess960"} {
+		if got := stats.Variant(variant); got != nil {
+			t.Errorf("Stats().Variant(%s) = %+v, want nil", variant, got)
+		}
+	}
+}
+
+func TestStatsNotFound(t *testing.T) {
+
+	srv := chesscomtest.NewServer()
+	defer srv.Close()
+
+	_, err := srv.Client().Stats(context.Background(), "nobody")
+	if !errors.Is(err, chesscom.ErrNotFound) {
+		t.Fatalf("Stats() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestClubMembers(t *testing.T) {
+
+	srv := chesscomtest.NewServer()
+	defer srv.Close()
+
+	srv.SetClubMembers("swahilichess",
+		chesscom.ClubMember{Username: "juma", Joined: 1700000000},
+		chesscom.ClubMember{Username: "asha", Joined: 1710000000},
+	)
+
+	members, err := srv.Client().ClubMembers(context.Background(), "swahilichess")
+	if err != nil {
+		t.Fatalf("ClubMembers() error = %v", err)
+	}
+
+	if len(members) != 2 || members[0].Username != "juma" || members[1].Joined != 1710000000 {
+		t.Errorf("ClubMembers() = %+v", members)
+	}
+}
+
+func TestRateLimitRetried(t *testing.T) {
+
+	srv := chesscomtest.NewServer()
+	defer srv.Close()
+
+	srv.SetStats("juma", chesscom.Stats{})
+	srv.RateLimit(2)
+
+	_, err := srv.Client().Stats(context.Background(), "juma")
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+
+	if got := srv.Requests(); got != 3 {
+		t.Errorf("Stats() sent %d requests, want 3", got)
+	}
+}
+
+func TestRateLimitRetriesExhausted(t *testing.T) {
+
+	srv := chesscomtest.NewServer()
+	defer srv.Close()
+
+	srv.SetStats("juma", chesscom.Stats{})
+	srv.RateLimit(3)
+
+	_, err := srv.Client().Stats(context.Background(), "juma")
+
+	var statusErr *chesscom.StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Stats() error = %v, want a 429 StatusError", err)
+	}
+
+	if got := srv.Requests(); got != 3 {
+		t.Errorf("Stats() sent %d requests, want 3", got)
+	}
+}
@@ -0,0 +1,133 @@
+// Package chesscomtest runs a fake chess.com published data API on an
+// httptest server so code using the chesscom client can run offline.
+package chesscomtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"api.swahilichess.com/internal/chesscom"
+)
+
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	stats       map[string]chesscom.Stats
+	clubs       map[string][]chesscom.ClubMember
+	rateLimited int
+	requests    int
+}
+
+// NewServer starts the fake, close it with Close.
+func NewServer() *Server {
+
+	s := &Server{
+		stats: make(map[string]chesscom.Stats),
+		clubs: make(map[string][]chesscom.ClubMember),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /pub/player/{username}/stats", s.handleStats)
+	mux.HandleFunc("GET /pub/club/{club}/members", s.handleClubMembers)
+
+	s.Server = httptest.NewServer(s.limit(mux))
+
+	return s
+}
+
+// Client returns a chesscom client pointed at the fake that doesn't wait
+// before retrying rate limited requests.
+func (s *Server) Client() *chesscom.Client {
+	c := chesscom.New(s.URL)
+	c.RetryWait = 0
+	return c
+}
+
+func (s *Server) SetStats(username string, stats chesscom.Stats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats[strings.ToLower(username)] = stats
+}
+
+// SetClubMembers replaces the members of the club, they are all reported
+// under all_time.
+func (s *Server) SetClubMembers(clubID string, members ...chesscom.ClubMember) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clubs[clubID] = members
+}
+
+// RateLimit answers the next n requests with 429.
+func (s *Server) RateLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimited = n
+}
+
+// Requests returns how many requests the fake received, rate limited ones included.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		limited := s.rateLimited > 0
+		if limited {
+			s.rateLimited--
+		}
+		s.mu.Unlock()
+
+		if limited {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	stats, ok := s.stats[strings.ToLower(r.PathValue("username"))]
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	writeJSON(w, stats)
+}
+
+func (s *Server) handleClubMembers(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	members, ok := s.clubs[r.PathValue("club")]
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	writeJSON(w, map[string][]chesscom.ClubMember{
+		"weekly":   {},
+		"monthly":  {},
+		"all_time": members,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,41 @@
+-- name: CreateTournamentGame :one
+INSERT INTO tournament_games (tournament_id, round, board, game, kind, white_id, black_id, result)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetTournamentGames :many
+SELECT * FROM tournament_games
+WHERE tournament_id = $1
+ORDER BY round, board, game;
+
+-- name: GetRoundGames :many
+SELECT * FROM tournament_games
+WHERE tournament_id = $1 AND round = $2
+ORDER BY board, game;
+
+-- name: GetLastRound :one
+SELECT COALESCE(MAX(round), 0)::integer FROM tournament_games
+WHERE tournament_id = $1;
+
+-- name: CountPendingGames :one
+SELECT count(*) FROM tournament_games
+WHERE tournament_id = $1 AND result = '';
+
+-- name: DeleteRoundGames :execrows
+DELETE FROM tournament_games
+WHERE tournament_id = $1 AND round = $2;
+
+-- name: GetTournamentGame :one
+SELECT * FROM tournament_games
+WHERE tournament_id = $1 AND id = $2;
+
+-- name: GetTournamentGameForUpdate :one
+SELECT * FROM tournament_games
+WHERE tournament_id = $1 AND id = $2
+FOR UPDATE;
+
+-- name: UpdateGameResult :one
+UPDATE tournament_games
+SET result = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING *;
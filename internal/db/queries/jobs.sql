@@ -0,0 +1,57 @@
+-- name: EnqueueJob :one
+-- no row is returned when a job with the same dedupe key exists
+INSERT INTO jobs (kind, payload, max_attempts, run_at, expires_at, dedupe_key)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (dedupe_key) WHERE dedupe_key IS NOT NULL DO NOTHING
+RETURNING id;
+
+-- name: ClaimJobs :many
+UPDATE jobs
+SET status = 'running', locked_at = NOW(), attempts = attempts + 1, updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM jobs
+    WHERE status = 'pending' AND run_at <= NOW()
+    ORDER BY run_at, id
+    FOR UPDATE SKIP LOCKED
+    LIMIT $1
+)
+RETURNING *;
+
+-- name: CompleteJob :exec
+-- the payload may hold secrets such as a passcode, a done job doesn't need it
+UPDATE jobs
+SET status = 'done', payload = '{}', locked_at = NULL, last_error = '', updated_at = NOW()
+WHERE id = $1;
+
+-- name: RetryJob :exec
+UPDATE jobs
+SET status = 'pending', locked_at = NULL, run_at = $2, last_error = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: KillJob :exec
+-- like a done job, a dead one is never run again and keeps no payload
+UPDATE jobs
+SET status = 'dead', payload = '{}', locked_at = NULL, last_error = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: RequeueStaleJobs :execrows
+UPDATE jobs
+SET status = 'pending', locked_at = NULL, updated_at = NOW()
+WHERE status = 'running' AND locked_at < NOW() - sqlc.arg(stale_seconds)::int * INTERVAL '1 second';
+
+-- name: ReviveJob :execrows
+UPDATE jobs
+SET status = 'pending', attempts = 0, run_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND status = 'dead' AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: GetJobsByStatus :many
+SELECT id, kind, status, attempts, max_attempts, run_at, locked_at, last_error, created_at, updated_at, expires_at, dedupe_key
+FROM jobs
+WHERE status = $1
+ORDER BY id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: DeleteFinishedJobs :execrows
+DELETE FROM jobs
+WHERE status IN ('done', 'dead')
+AND updated_at < NOW() - sqlc.arg(retention_seconds)::int * INTERVAL '1 second';
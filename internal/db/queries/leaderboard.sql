@@ -0,0 +1,50 @@
+-- name: CreateLeaderboardSnapshot :one
+INSERT INTO leaderboard_snapshots DEFAULT VALUES
+RETURNING *;
+
+-- name: CreateLeaderboardEntry :exec
+INSERT INTO leaderboard_entries (snapshot_id, username, variant, rank, rating, games, rd, prog, prov)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- name: GetLatestLeaderboardSnapshot :one
+SELECT * FROM leaderboard_snapshots
+ORDER BY taken_at DESC, id DESC
+LIMIT 1;
+
+-- name: GetLeaderboardSnapshotBefore :one
+SELECT * FROM leaderboard_snapshots
+WHERE taken_at <= $1
+ORDER BY taken_at DESC, id DESC
+LIMIT 1;
+
+-- name: GetFirstLeaderboardSnapshot :one
+SELECT * FROM leaderboard_snapshots
+ORDER BY taken_at, id
+LIMIT 1;
+
+-- name: GetLeaderboardChanges :many
+SELECT cur.username, cur.rank, cur.rating, cur.games, cur.prov,
+       prev.rank AS previous_rank, prev.rating AS previous_rating
+FROM leaderboard_entries cur
+LEFT JOIN leaderboard_entries prev
+    ON prev.snapshot_id = sqlc.arg(previous_snapshot_id)
+    AND prev.variant = cur.variant
+    AND prev.username = cur.username
+WHERE cur.snapshot_id = sqlc.arg(snapshot_id) AND cur.variant = sqlc.arg(variant)
+ORDER BY cur.rank;
+
+-- name: GetRatingHistory :many
+SELECT s.taken_at, e.rank, e.rating, e.games, e.rd, e.prov
+FROM leaderboard_entries e
+JOIN leaderboard_snapshots s ON s.id = e.snapshot_id
+WHERE e.username = $1 AND e.variant = $2 AND s.taken_at >= $3
+ORDER BY s.taken_at;
+
+-- name: LockLeaderboardSnapshots :exec
+-- held until the transaction ends, so replicas take snapshots one at a time
+SELECT pg_advisory_xact_lock(hashtext('leaderboard_snapshots'));
+
+-- name: DeleteLeaderboardSnapshotsBefore :execrows
+-- the entries of the snapshots are removed by the cascade
+DELETE FROM leaderboard_snapshots
+WHERE taken_at < $1;
@@ -1,5 +1,25 @@
 -- name: GetLichessTeamMembers :many
-SELECT lichess_id from lichess;
+SELECT lichess_id from lichess WHERE left_at IS NULL;
+
+-- name: GetLichessTeamMemberIds :many
+SELECT lichess_id FROM lichess;
+
+-- name: GetAllLichessTeamMembers :many
+SELECT * FROM lichess;
 
 -- name: InsertLichessTeamMember :exec
-INSERT INTO lichess(lichess_id, username) VALUES ($1, $2);
\ No newline at end of file
+INSERT INTO lichess(lichess_id, username) VALUES ($1, $2)
+ON CONFLICT (lichess_id) DO UPDATE SET username = EXCLUDED.username, left_at = NULL;
+
+-- name: MarkLichessTeamMemberLeft :exec
+UPDATE lichess SET left_at = NOW()
+WHERE lichess_id = $1 AND left_at IS NULL;
+
+-- name: CreateLichessMembershipEvent :exec
+INSERT INTO lichess_membership_events (lichess_id, username, event)
+VALUES ($1, $2, $3);
+
+-- name: GetLichessMembershipEvents :many
+SELECT * FROM lichess_membership_events
+ORDER BY id DESC
+LIMIT $1 OFFSET $2;
@@ -0,0 +1,11 @@
+-- name: CreateOAuthState :exec
+INSERT INTO oauth_states (hash, user_id, provider, code_verifier, expiry)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ConsumeOAuthState :one
+DELETE FROM oauth_states
+WHERE hash = $1 AND provider = $2 AND expiry > $3
+RETURNING user_id, code_verifier;
+
+-- name: DeleteExpiredOAuthStates :exec
+DELETE FROM oauth_states WHERE expiry <= $1;
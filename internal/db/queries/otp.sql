@@ -0,0 +1,21 @@
+-- name: CreateOtp :one
+INSERT INTO otp (user_id, purpose, code_hash, max_attempts, expiry, target)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
+
+-- name: GetActiveOtp :one
+SELECT * FROM otp
+WHERE user_id = $1 AND purpose = $2 AND consumed_at IS NULL
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: InvalidateOtps :exec
+UPDATE otp SET consumed_at = NOW()
+WHERE user_id = $1 AND purpose = $2 AND consumed_at IS NULL;
+
+-- name: ClaimOtpAttempt :one
+UPDATE otp SET attempts = attempts + 1
+WHERE id = $1 AND attempts < max_attempts
+RETURNING attempts;
+
+-- name: ConsumeOtp :execrows
+UPDATE otp SET consumed_at = NOW() WHERE id = $1 AND consumed_at IS NULL;
@@ -0,0 +1,44 @@
+-- name: HitRateLimit :one
+INSERT INTO rate_limits (key, hits, window_start)
+VALUES (sqlc.arg(key), 1, NOW())
+ON CONFLICT (key) DO UPDATE
+SET
+    hits = CASE
+        WHEN rate_limits.window_start <= NOW() - sqlc.arg(window_seconds)::int * INTERVAL '1 second' THEN 1
+        ELSE rate_limits.hits + 1
+    END,
+    window_start = CASE
+        WHEN rate_limits.window_start <= NOW() - sqlc.arg(window_seconds)::int * INTERVAL '1 second' THEN NOW()
+        ELSE rate_limits.window_start
+    END
+RETURNING hits, window_start;
+
+-- name: GetLoginFailure :one
+SELECT * FROM login_failures WHERE key = $1;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_failures (key, failures, last_failure_at)
+VALUES (sqlc.arg(key), 1, NOW())
+ON CONFLICT (key) DO UPDATE
+SET
+    failures = CASE
+        WHEN login_failures.last_failure_at <= NOW() - sqlc.arg(reset_seconds)::int * INTERVAL '1 second' THEN 1
+        ELSE login_failures.failures + 1
+    END,
+    last_failure_at = NOW()
+RETURNING failures;
+
+-- name: LockLogin :exec
+UPDATE login_failures SET locked_until = $2 WHERE key = $1;
+
+-- name: ResetLoginFailures :exec
+DELETE FROM login_failures WHERE key = $1;
+
+-- name: DeleteStaleRateLimits :execrows
+DELETE FROM rate_limits
+WHERE window_start < NOW() - sqlc.arg(window_seconds)::int * INTERVAL '1 second';
+
+-- name: DeleteStaleLoginFailures :execrows
+DELETE FROM login_failures
+WHERE last_failure_at < NOW() - sqlc.arg(reset_seconds)::int * INTERVAL '1 second'
+AND (locked_until IS NULL OR locked_until < NOW());
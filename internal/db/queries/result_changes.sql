@@ -0,0 +1,12 @@
+-- name: CreateResultChange :exec
+INSERT INTO result_changes (tournament_id, game_id, round, board, game, old_result, new_result, reason, changed_by, override)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+
+-- name: GetResultChanges :many
+SELECT rc.id, rc.game_id, rc.round, rc.board, rc.game, rc.old_result, rc.new_result,
+       rc.reason, rc.override, u.username AS changed_by, rc.created_at
+FROM result_changes rc
+LEFT JOIN users u ON u.id = rc.changed_by
+WHERE rc.tournament_id = $1
+ORDER BY rc.created_at DESC, rc.id DESC
+LIMIT $2 OFFSET $3;
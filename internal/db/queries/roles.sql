@@ -0,0 +1,21 @@
+-- name: GetRoles :many
+SELECT * FROM roles ORDER BY name;
+
+-- name: GetUserRoles :many
+SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role;
+
+-- name: GetUserPermissions :many
+SELECT DISTINCT role_permissions.permission
+FROM user_roles
+INNER JOIN role_permissions
+ON user_roles.role = role_permissions.role
+WHERE user_roles.user_id = $1
+ORDER BY role_permissions.permission;
+
+-- name: AddUserRole :exec
+INSERT INTO user_roles (user_id, role, granted_by)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, role) DO NOTHING;
+
+-- name: RemoveUserRole :execrows
+DELETE FROM user_roles WHERE user_id = $1 AND role = $2;
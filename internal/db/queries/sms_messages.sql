@@ -0,0 +1,31 @@
+-- name: CreateSmsMessage :exec
+-- a retried job sends with the same reference and reuses its row
+INSERT INTO sms_messages (reference, provider, recipient, status)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (reference) DO UPDATE
+SET provider = EXCLUDED.provider, status = EXCLUDED.status, error = '', updated_at = NOW();
+
+-- name: UpdateSmsMessageResult :exec
+UPDATE sms_messages
+SET
+    provider_message_id = $2,
+    status = $3,
+    status_description = $4,
+    sms_count = $5,
+    cost = $6,
+    error = $7,
+    updated_at = NOW()
+WHERE reference = $1;
+
+-- name: UpdateSmsMessageStatus :execrows
+UPDATE sms_messages
+SET
+    status = $3,
+    status_description = $4,
+    updated_at = NOW()
+WHERE provider = $1 AND provider_message_id = $2;
+
+-- name: GetSmsMessages :many
+SELECT * FROM sms_messages
+ORDER BY id DESC
+LIMIT $1 OFFSET $2;
@@ -1,6 +1,37 @@
 -- name: CreateToken :exec
-INSERT INTO token (hash, user_id, expiry, scope)
-VALUES ($1, $2, $3, $4);
+INSERT INTO token (hash, user_id, expiry, scope, family_id, user_agent, ip)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
 
 -- name: DeleteToken :exec
 DELETE FROM token WHERE token.hash = $1 and user_id = $2;
+
+-- name: GetToken :one
+SELECT * FROM token WHERE hash = $1 AND scope = $2;
+
+-- name: RotateToken :execrows
+UPDATE token SET rotated = true WHERE hash = $1 AND rotated = false;
+
+-- name: DeleteTokensByFamily :exec
+DELETE FROM token WHERE family_id = $1;
+
+-- name: DeleteTokensByUser :exec
+DELETE FROM token WHERE user_id = $1;
+
+-- name: DeleteSession :execrows
+DELETE FROM token WHERE family_id = $1 AND user_id = $2;
+
+-- name: TouchToken :exec
+UPDATE token SET last_used_at = NOW()
+WHERE hash = $1 AND last_used_at < NOW() - INTERVAL '1 minute';
+
+-- name: GetSessionsByUser :many
+SELECT family_id,
+       MIN(created_at)::timestamptz AS created_at,
+       MAX(expiry)::timestamptz AS expiry,
+       MAX(last_used_at)::timestamptz AS last_used_at,
+       user_agent,
+       ip
+FROM token
+WHERE user_id = $1 AND expiry > NOW()
+GROUP BY family_id, user_agent, ip
+ORDER BY last_used_at DESC;
@@ -0,0 +1,78 @@
+-- name: CreateTournament :one
+INSERT INTO tournaments (
+    name, format, time_control, rounds, online, venue, starts_at,
+    registration_opens_at, registration_closes_at, max_players, created_by, tiebreaks
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING *;
+
+-- name: GetTournament :one
+SELECT * FROM tournaments WHERE id = $1;
+
+-- name: GetTournamentForUpdate :one
+SELECT * FROM tournaments WHERE id = $1 FOR UPDATE;
+
+-- name: ListTournaments :many
+SELECT * FROM tournaments
+WHERE status = $1 OR $1 = ''
+ORDER BY starts_at DESC, id DESC
+LIMIT $2 OFFSET $3;
+
+-- name: UpdateTournament :one
+UPDATE tournaments
+SET
+    name = $1,
+    format = $2,
+    time_control = $3,
+    rounds = $4,
+    online = $5,
+    venue = $6,
+    starts_at = $7,
+    registration_opens_at = $8,
+    registration_closes_at = $9,
+    max_players = $10,
+    status = $11,
+    tiebreaks = $12,
+    updated_at = NOW()
+WHERE id = $13
+RETURNING *;
+
+-- name: DeleteTournament :execrows
+DELETE FROM tournaments WHERE id = $1;
+
+-- name: CountActiveTournamentPlayers :one
+SELECT count(*) FROM tournament_players
+WHERE tournament_id = $1 AND status = 'registered';
+
+-- name: CreateTournamentPlayer :one
+INSERT INTO tournament_players (tournament_id, user_id, name, rating)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (tournament_id, user_id) DO UPDATE
+SET name = EXCLUDED.name, rating = EXCLUDED.rating, status = 'registered', updated_at = NOW()
+RETURNING *;
+
+-- name: GetTournamentPlayer :one
+SELECT * FROM tournament_players WHERE tournament_id = $1 AND id = $2;
+
+-- name: GetTournamentPlayerByUser :one
+SELECT * FROM tournament_players WHERE tournament_id = $1 AND user_id = $2;
+
+-- name: GetTournamentPlayers :many
+SELECT * FROM tournament_players
+WHERE tournament_id = $1
+ORDER BY status, rating DESC, name;
+
+-- name: WithdrawTournamentPlayer :execrows
+UPDATE tournament_players
+SET status = 'withdrawn', updated_at = NOW()
+WHERE tournament_id = $1 AND id = $2 AND status = 'registered';
+
+-- name: SetTournamentStatus :exec
+UPDATE tournaments
+SET status = $1, updated_at = NOW()
+WHERE id = $2;
+
+-- name: SetTournamentRounds :exec
+UPDATE tournaments
+SET rounds = $1, updated_at = NOW()
+WHERE id = $2;
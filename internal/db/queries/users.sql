@@ -7,39 +7,28 @@ INSERT INTO users
      chesscom_username,
      phone_number,
      photo,
-     passcode,
      password_hash, 
      activated,
      enabled
     )
-VALUES ($1, $2, $3 ,$4, $5, $6, $7, $8,$9, $10) RETURNING id, phone_number;
-
--- name: GetUserForResetOrActivation :one
-SELECT id, username, full_name, lichess_username, chesscom_username,
-phone_number, photo, passcode, password_hash, enabled, activated, created_at
-FROM users
-WHERE 
-   (phone_number = $1 OR $1 = '' ) 
-     AND 
-   (username = $2 OR $2 = '') 
-     AND
-   (passcode = $3);
+VALUES ($1, $2, $3 ,$4, $5, $6, $7, $8, $9) RETURNING id, phone_number;
 
 -- name: GetUserById :one
 SELECT id, username, full_name, lichess_username, chesscom_username,
-phone_number, photo, passcode, password_hash, enabled, activated, created_at
+phone_number, photo, password_hash, enabled, activated, created_at, lichess_id
 FROM users
 WHERE id = $1;
 
 -- name: GetUserByUsername :one
 SELECT id, username, full_name, lichess_username, chesscom_username,
-phone_number, photo, passcode, password_hash, enabled, activated, created_at
+phone_number, photo, password_hash, enabled, activated, created_at, lichess_id
 FROM users
 WHERE username = $1;
 
 -- name: GetUserByToken :one
 SELECT users.id, users.username, users.full_name, users.lichess_username, 
-users.chesscom_username, users.phone_number,users.photo, users.passcode, users.password_hash, users.activated,users.enabled, users.created_at
+users.chesscom_username, users.phone_number,users.photo, users.password_hash, users.activated,users.enabled, users.created_at,
+users.lichess_id, token.family_id
 FROM users
 INNER JOIN token
 ON users.id = token.user_id
@@ -53,15 +42,15 @@ SET
     username = $1, 
     full_name = $2, 
     lichess_username = $3, 
+    lichess_id = CASE WHEN lichess_username = $3 THEN lichess_id END,
     chesscom_username = $4, 
     phone_number = $5, 
     photo = $6,
-    passcode = $7,
-    password_hash = $8, 
-    activated = $9,
-    enabled = $10
+    password_hash = $7, 
+    activated = $8,
+    enabled = $9
 WHERE 
-    id = $11;
+    id = $10;
 
 -- name: GetUserByUsernameOrPhone :one
 SELECT * FROM users 
@@ -71,8 +60,30 @@ WHERE
     (username = $2 OR $2 = '');
     
 
+-- name: UpdateUserPhoneNumber :exec
+UPDATE users SET phone_number = $1 WHERE id = $2;
+
 -- name: DeleteUserById :exec
 DELETE FROM users WHERE id = $1;
 
 
 
+
+-- name: GetChesscomUsernames :many
+SELECT chesscom_username FROM users
+WHERE chesscom_username <> '' AND activated;
+
+-- name: GetLinkedAccounts :many
+SELECT username, full_name, photo, lichess_id, chesscom_username
+FROM users
+WHERE activated AND enabled AND (lichess_id IS NOT NULL OR chesscom_username <> '');
+
+-- name: LinkLichessAccount :exec
+UPDATE users SET lichess_id = $1, lichess_username = $2 WHERE id = $3;
+
+-- name: UnlinkLichessAccount :exec
+UPDATE users SET lichess_id = NULL WHERE id = $1;
+
+-- name: GetLinkedLichessAccounts :many
+SELECT lichess_id, username FROM users
+WHERE lichess_id IS NOT NULL AND activated AND enabled;
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var ErrUnknownPlayer = errors.New("game refers to an unknown player")
+
+// ImportGame is a game of an imported tournament, White and Black are indexes
+// in the imported players and Black is -1 for a bye.
+type ImportGame struct {
+	Round  int32
+	Board  int32
+	White  int
+	Black  int
+	Result string
+}
+
+// ImportTournamentParams holds a tournament run elsewhere, Status is the
+// status it gets once its players and games are stored.
+type ImportTournamentParams struct {
+	Tournament CreateTournamentParams
+	Players    []CreateTournamentPlayerParams
+	Games      []ImportGame
+	Status     string
+}
+
+// ImportTournamentTx creates the tournament with its players and games in one
+// go, so a report that fails half way leaves nothing behind.
+func (store *SQLStore) ImportTournamentTx(ctx context.Context, arg ImportTournamentParams) (Tournament, error) {
+
+	var tournament Tournament
+
+	err := store.execTx(ctx, func(q *Queries) error {
+
+		var err error
+
+		tournament, err = q.CreateTournament(ctx, arg.Tournament)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]int64, len(arg.Players))
+		for i, p := range arg.Players {
+			p.TournamentID = tournament.ID
+			player, err := q.CreateTournamentPlayer(ctx, p)
+			if err != nil {
+				return err
+			}
+			ids[i] = player.ID
+		}
+
+		for _, g := range arg.Games {
+
+			if g.White < 0 || g.White >= len(ids) || g.Black < -1 || g.Black >= len(ids) {
+				return ErrUnknownPlayer
+			}
+
+			var black sql.NullInt64
+			if g.Black >= 0 {
+				black = sql.NullInt64{Int64: ids[g.Black], Valid: true}
+			}
+
+			_, err := q.CreateTournamentGame(ctx, CreateTournamentGameParams{
+				TournamentID: tournament.ID,
+				Round:        g.Round,
+				Board:        g.Board,
+				Game:         1,
+				Kind:         GameRegular,
+				WhiteID:      ids[g.White],
+				BlackID:      black,
+				Result:       g.Result,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if arg.Status != "" && arg.Status != tournament.Status {
+			err := q.SetTournamentStatus(ctx, SetTournamentStatusParams{
+				Status: arg.Status,
+				ID:     tournament.ID,
+			})
+			if err != nil {
+				return err
+			}
+			tournament.Status = arg.Status
+		}
+
+		return nil
+	})
+
+	return tournament, err
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrSnapshotTooRecent = errors.New("a snapshot was taken within the interval")
+
+// CreateLeaderboardSnapshotTxParams holds the entries of a snapshot, it is
+// only taken when the latest one is at least MinInterval old.
+type CreateLeaderboardSnapshotTxParams struct {
+	Entries     []CreateLeaderboardEntryParams
+	MinInterval time.Duration
+}
+
+// CreateLeaderboardSnapshotTx stores a snapshot and all of its entries, the
+// snapshot id of each entry is filled in. The interval is checked under an
+// advisory lock so replicas refreshing at the same time store one snapshot.
+func (store *SQLStore) CreateLeaderboardSnapshotTx(ctx context.Context, arg CreateLeaderboardSnapshotTxParams) (LeaderboardSnapshot, error) {
+
+	var snapshot LeaderboardSnapshot
+
+	err := store.execTx(ctx, func(q *Queries) error {
+
+		err := q.LockLeaderboardSnapshots(ctx)
+		if err != nil {
+			return err
+		}
+
+		latest, err := q.GetLatestLeaderboardSnapshot(ctx)
+		switch {
+		case err == nil:
+			if time.Since(latest.TakenAt) < arg.MinInterval {
+				return ErrSnapshotTooRecent
+			}
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+
+		snapshot, err = q.CreateLeaderboardSnapshot(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range arg.Entries {
+			entry.SnapshotID = snapshot.ID
+			err = q.CreateLeaderboardEntry(ctx, entry)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return snapshot, err
+}
@@ -9,8 +9,124 @@ import (
 	"context"
 )
 
+const createLichessMembershipEvent = `-- name: CreateLichessMembershipEvent :exec
+INSERT INTO lichess_membership_events (lichess_id, username, event)
+VALUES ($1, $2, $3)
+`
+
+type CreateLichessMembershipEventParams struct {
+	LichessID string `json:"lichess_id"`
+	Username  string `json:"username"`
+	Event     string `json:"event"`
+}
+
+func (q *Queries) CreateLichessMembershipEvent(ctx context.Context, arg CreateLichessMembershipEventParams) error {
+	_, err := q.db.ExecContext(ctx, createLichessMembershipEvent, arg.LichessID, arg.Username, arg.Event)
+	return err
+}
+
+const getAllLichessTeamMembers = `-- name: GetAllLichessTeamMembers :many
+SELECT id, lichess_id, username, created_at, left_at FROM lichess
+`
+
+func (q *Queries) GetAllLichessTeamMembers(ctx context.Context) ([]Lichess, error) {
+	rows, err := q.db.QueryContext(ctx, getAllLichessTeamMembers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Lichess{}
+	for rows.Next() {
+		var i Lichess
+		if err := rows.Scan(
+			&i.ID,
+			&i.LichessID,
+			&i.Username,
+			&i.CreatedAt,
+			&i.LeftAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLichessMembershipEvents = `-- name: GetLichessMembershipEvents :many
+SELECT id, lichess_id, username, event, created_at FROM lichess_membership_events
+ORDER BY id DESC
+LIMIT $1 OFFSET $2
+`
+
+type GetLichessMembershipEventsParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) GetLichessMembershipEvents(ctx context.Context, arg GetLichessMembershipEventsParams) ([]LichessMembershipEvent, error) {
+	rows, err := q.db.QueryContext(ctx, getLichessMembershipEvents, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []LichessMembershipEvent{}
+	for rows.Next() {
+		var i LichessMembershipEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.LichessID,
+			&i.Username,
+			&i.Event,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLichessTeamMemberIds = `-- name: GetLichessTeamMemberIds :many
+SELECT lichess_id FROM lichess
+`
+
+func (q *Queries) GetLichessTeamMemberIds(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getLichessTeamMemberIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var lichess_id string
+		if err := rows.Scan(&lichess_id); err != nil {
+			return nil, err
+		}
+		items = append(items, lichess_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getLichessTeamMembers = `-- name: GetLichessTeamMembers :many
-SELECT lichess_id from lichess
+SELECT lichess_id from lichess WHERE left_at IS NULL
 `
 
 func (q *Queries) GetLichessTeamMembers(ctx context.Context) ([]string, error) {
@@ -38,6 +154,7 @@ func (q *Queries) GetLichessTeamMembers(ctx context.Context) ([]string, error) {
 
 const insertLichessTeamMember = `-- name: InsertLichessTeamMember :exec
 INSERT INTO lichess(lichess_id, username) VALUES ($1, $2)
+ON CONFLICT (lichess_id) DO UPDATE SET username = EXCLUDED.username, left_at = NULL
 `
 
 type InsertLichessTeamMemberParams struct {
@@ -49,3 +166,13 @@ func (q *Queries) InsertLichessTeamMember(ctx context.Context, arg InsertLichess
 	_, err := q.db.ExecContext(ctx, insertLichessTeamMember, arg.LichessID, arg.Username)
 	return err
 }
+
+const markLichessTeamMemberLeft = `-- name: MarkLichessTeamMemberLeft :exec
+UPDATE lichess SET left_at = NOW()
+WHERE lichess_id = $1 AND left_at IS NULL
+`
+
+func (q *Queries) MarkLichessTeamMemberLeft(ctx context.Context, lichessID string) error {
+	_, err := q.db.ExecContext(ctx, markLichessTeamMemberLeft, lichessID)
+	return err
+}
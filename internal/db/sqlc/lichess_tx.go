@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+)
+
+const (
+	MembershipJoined = "joined"
+	MembershipLeft   = "left"
+)
+
+type SyncLichessTeamResult struct {
+	Joined []string `json:"joined"`
+	Left   []string `json:"left"`
+}
+
+// SyncLichessTeamTx makes the lichess table match the team roster. New and
+// returning members are marked as joined, members missing from the roster as
+// left, and every change is recorded as a membership event.
+func (store *SQLStore) SyncLichessTeamTx(ctx context.Context, roster []InsertLichessTeamMemberParams) (SyncLichessTeamResult, error) {
+
+	result := SyncLichessTeamResult{
+		Joined: []string{},
+		Left:   []string{},
+	}
+
+	err := store.execTx(ctx, func(q *Queries) error {
+
+		existing, err := q.GetAllLichessTeamMembers(ctx)
+		if err != nil {
+			return err
+		}
+
+		known := make(map[string]Lichess, len(existing))
+		for _, member := range existing {
+			known[member.LichessID] = member
+		}
+
+		inRoster := make(map[string]bool, len(roster))
+
+		for _, member := range roster {
+			inRoster[member.LichessID] = true
+
+			current, ok := known[member.LichessID]
+			joined := !ok || current.LeftAt.Valid
+
+			if !joined && current.Username == member.Username {
+				continue
+			}
+
+			err = q.InsertLichessTeamMember(ctx, member)
+			if err != nil {
+				return err
+			}
+
+			if joined {
+				err = q.CreateLichessMembershipEvent(ctx, CreateLichessMembershipEventParams{
+					LichessID: member.LichessID,
+					Username:  member.Username,
+					Event:     MembershipJoined,
+				})
+				if err != nil {
+					return err
+				}
+				result.Joined = append(result.Joined, member.Username)
+			}
+		}
+
+		for _, member := range existing {
+			if member.LeftAt.Valid || inRoster[member.LichessID] {
+				continue
+			}
+
+			err = q.MarkLichessTeamMemberLeft(ctx, member.LichessID)
+			if err != nil {
+				return err
+			}
+
+			err = q.CreateLichessMembershipEvent(ctx, CreateLichessMembershipEventParams{
+				LichessID: member.LichessID,
+				Username:  member.Username,
+				Event:     MembershipLeft,
+			})
+			if err != nil {
+				return err
+			}
+			result.Left = append(result.Left, member.Username)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
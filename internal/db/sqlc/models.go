@@ -5,41 +5,221 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type Job struct {
+	ID          int64           `json:"id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int32           `json:"attempts"`
+	MaxAttempts int32           `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LockedAt    sql.NullTime    `json:"locked_at"`
+	LastError   string          `json:"last_error"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	ExpiresAt   sql.NullTime    `json:"expires_at"`
+	DedupeKey   sql.NullString  `json:"dedupe_key"`
+}
+
+type LeaderboardEntry struct {
+	SnapshotID int64  `json:"snapshot_id"`
+	Username   string `json:"username"`
+	Variant    string `json:"variant"`
+	Rank       int32  `json:"rank"`
+	Rating     int32  `json:"rating"`
+	Games      int32  `json:"games"`
+	Rd         int32  `json:"rd"`
+	Prog       int32  `json:"prog"`
+	Prov       bool   `json:"prov"`
+}
+
+type LeaderboardSnapshot struct {
+	ID      int64     `json:"id"`
+	TakenAt time.Time `json:"taken_at"`
+}
+
 type Lichess struct {
-	ID        int32     `json:"id"`
+	ID        int32        `json:"id"`
+	LichessID string       `json:"lichess_id"`
+	Username  string       `json:"username"`
+	CreatedAt time.Time    `json:"created_at"`
+	LeftAt    sql.NullTime `json:"left_at"`
+}
+
+type LichessMembershipEvent struct {
+	ID        int64     `json:"id"`
 	LichessID string    `json:"lichess_id"`
 	Username  string    `json:"username"`
+	Event     string    `json:"event"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type LoginFailure struct {
+	Key           string       `json:"key"`
+	Failures      int32        `json:"failures"`
+	LockedUntil   sql.NullTime `json:"locked_until"`
+	LastFailureAt time.Time    `json:"last_failure_at"`
+}
+
+type OauthState struct {
+	Hash         []byte    `json:"hash"`
+	UserID       uuid.UUID `json:"user_id"`
+	Provider     string    `json:"provider"`
+	CodeVerifier string    `json:"code_verifier"`
+	Expiry       time.Time `json:"expiry"`
+}
+
+type Otp struct {
+	ID          int64        `json:"id"`
+	UserID      uuid.UUID    `json:"user_id"`
+	Purpose     string       `json:"purpose"`
+	CodeHash    []byte       `json:"code_hash"`
+	Attempts    int32        `json:"attempts"`
+	MaxAttempts int32        `json:"max_attempts"`
+	Expiry      time.Time    `json:"expiry"`
+	ConsumedAt  sql.NullTime `json:"consumed_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Target      string       `json:"target"`
+}
+
+type Permission struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+type RateLimit struct {
+	Key         string    `json:"key"`
+	Hits        int32     `json:"hits"`
+	WindowStart time.Time `json:"window_start"`
+}
+
+type ResultChange struct {
+	ID           int64         `json:"id"`
+	TournamentID int64         `json:"tournament_id"`
+	GameID       int64         `json:"game_id"`
+	OldResult    string        `json:"old_result"`
+	NewResult    string        `json:"new_result"`
+	Reason       string        `json:"reason"`
+	ChangedBy    uuid.NullUUID `json:"changed_by"`
+	CreatedAt    time.Time     `json:"created_at"`
+	Round        int32         `json:"round"`
+	Board        int32         `json:"board"`
+	Game         int32         `json:"game"`
+	Override     bool          `json:"override"`
+}
+
+type Role struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type RolePermission struct {
+	Role       string `json:"role"`
+	Permission string `json:"permission"`
+}
+
+type SmsMessage struct {
+	ID                int64     `json:"id"`
+	Reference         uuid.UUID `json:"reference"`
+	Provider          string    `json:"provider"`
+	Recipient         string    `json:"recipient"`
+	ProviderMessageID string    `json:"provider_message_id"`
+	Status            string    `json:"status"`
+	StatusDescription string    `json:"status_description"`
+	SmsCount          int32     `json:"sms_count"`
+	Cost              float64   `json:"cost"`
+	Error             string    `json:"error"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
 type TgbotUser struct {
 	ID       int64 `json:"id"`
 	Isactive bool  `json:"isactive"`
 }
 
 type Token struct {
-	Hash   []byte    `json:"hash"`
-	UserID uuid.UUID `json:"user_id"`
-	Expiry time.Time `json:"expiry"`
-	Scope  string    `json:"scope"`
+	Hash       []byte    `json:"hash"`
+	UserID     uuid.UUID `json:"user_id"`
+	Expiry     time.Time `json:"expiry"`
+	Scope      string    `json:"scope"`
+	FamilyID   uuid.UUID `json:"family_id"`
+	Rotated    bool      `json:"rotated"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	UserAgent  string    `json:"user_agent"`
+	Ip         string    `json:"ip"`
+}
+
+type Tournament struct {
+	ID                   int64         `json:"id"`
+	Name                 string        `json:"name"`
+	Format               string        `json:"format"`
+	TimeControl          string        `json:"time_control"`
+	Rounds               int32         `json:"rounds"`
+	Online               bool          `json:"online"`
+	Venue                string        `json:"venue"`
+	StartsAt             time.Time     `json:"starts_at"`
+	RegistrationOpensAt  time.Time     `json:"registration_opens_at"`
+	RegistrationClosesAt time.Time     `json:"registration_closes_at"`
+	MaxPlayers           int32         `json:"max_players"`
+	Status               string        `json:"status"`
+	CreatedBy            uuid.NullUUID `json:"created_by"`
+	CreatedAt            time.Time     `json:"created_at"`
+	UpdatedAt            time.Time     `json:"updated_at"`
+	Tiebreaks            []string      `json:"tiebreaks"`
+}
+
+type TournamentGame struct {
+	ID           int64         `json:"id"`
+	TournamentID int64         `json:"tournament_id"`
+	Round        int32         `json:"round"`
+	Board        int32         `json:"board"`
+	WhiteID      int64         `json:"white_id"`
+	BlackID      sql.NullInt64 `json:"black_id"`
+	Result       string        `json:"result"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+	Game         int32         `json:"game"`
+	Kind         string        `json:"kind"`
+}
+
+type TournamentPlayer struct {
+	ID           int64         `json:"id"`
+	TournamentID int64         `json:"tournament_id"`
+	UserID       uuid.NullUUID `json:"user_id"`
+	Name         string        `json:"name"`
+	Rating       int32         `json:"rating"`
+	Status       string        `json:"status"`
+	RegisteredAt time.Time     `json:"registered_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
 }
 
 type User struct {
-	ID               uuid.UUID `json:"id"`
-	Username         string    `json:"username"`
-	FullName         string    `json:"full_name"`
-	LichessUsername  string    `json:"lichess_username"`
-	ChesscomUsername string    `json:"chesscom_username"`
-	PhoneNumber      string    `json:"phone_number"`
-	PasswordHash     []byte    `json:"password_hash"`
-	Passcode         []byte    `json:"passcode"`
-	Activated        bool      `json:"activated"`
-	Enabled          bool      `json:"enabled"`
-	Photo            string    `json:"photo"`
-	CreatedAt        time.Time `json:"created_at"`
+	ID               uuid.UUID      `json:"id"`
+	Username         string         `json:"username"`
+	FullName         string         `json:"full_name"`
+	LichessUsername  string         `json:"lichess_username"`
+	ChesscomUsername string         `json:"chesscom_username"`
+	PhoneNumber      string         `json:"phone_number"`
+	PasswordHash     []byte         `json:"-"`
+	Activated        bool           `json:"activated"`
+	Enabled          bool           `json:"enabled"`
+	Photo            string         `json:"photo"`
+	CreatedAt        time.Time      `json:"created_at"`
+	LichessID        sql.NullString `json:"lichess_id"`
+}
+
+type UserRole struct {
+	UserID    uuid.UUID     `json:"user_id"`
+	Role      string        `json:"role"`
+	GrantedBy uuid.NullUUID `json:"granted_by"`
+	CreatedAt time.Time     `json:"created_at"`
 }
@@ -6,26 +6,116 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Querier interface {
+	// a retried job sends with the same reference and reuses its row
+	// held until the transaction ends, so replicas take snapshots one at a time
+	// no row is returned when a job with the same dedupe key exists
+	// the entries of the snapshots are removed by the cascade
+	// the payload may hold secrets such as a passcode, a done job doesn't need it
+	AddUserRole(ctx context.Context, arg AddUserRoleParams) error
+	ClaimJobs(ctx context.Context, limit int32) ([]Job, error)
+	ClaimOtpAttempt(ctx context.Context, id int64) (int32, error)
+	CompleteJob(ctx context.Context, id int64) error
+	ConsumeOAuthState(ctx context.Context, arg ConsumeOAuthStateParams) (ConsumeOAuthStateRow, error)
+	ConsumeOtp(ctx context.Context, id int64) (int64, error)
+	CountActiveTournamentPlayers(ctx context.Context, tournamentID int64) (int64, error)
+	CountPendingGames(ctx context.Context, tournamentID int64) (int64, error)
+	CreateLeaderboardEntry(ctx context.Context, arg CreateLeaderboardEntryParams) error
+	CreateLeaderboardSnapshot(ctx context.Context) (LeaderboardSnapshot, error)
+	CreateLichessMembershipEvent(ctx context.Context, arg CreateLichessMembershipEventParams) error
+	CreateOAuthState(ctx context.Context, arg CreateOAuthStateParams) error
+	CreateOtp(ctx context.Context, arg CreateOtpParams) (Otp, error)
+	CreateResultChange(ctx context.Context, arg CreateResultChangeParams) error
+	CreateSmsMessage(ctx context.Context, arg CreateSmsMessageParams) error
 	CreateToken(ctx context.Context, arg CreateTokenParams) error
+	CreateTournament(ctx context.Context, arg CreateTournamentParams) (Tournament, error)
+	CreateTournamentGame(ctx context.Context, arg CreateTournamentGameParams) (TournamentGame, error)
+	CreateTournamentPlayer(ctx context.Context, arg CreateTournamentPlayerParams) (TournamentPlayer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (CreateUserRow, error)
+	DeleteExpiredOAuthStates(ctx context.Context, expiry time.Time) error
+	DeleteFinishedJobs(ctx context.Context, retentionSeconds int32) (int64, error)
+	DeleteLeaderboardSnapshotsBefore(ctx context.Context, takenAt time.Time) (int64, error)
+	DeleteRoundGames(ctx context.Context, arg DeleteRoundGamesParams) (int64, error)
+	DeleteSession(ctx context.Context, arg DeleteSessionParams) (int64, error)
+	DeleteStaleLoginFailures(ctx context.Context, resetSeconds int32) (int64, error)
+	DeleteStaleRateLimits(ctx context.Context, windowSeconds int32) (int64, error)
 	DeleteToken(ctx context.Context, arg DeleteTokenParams) error
+	DeleteTokensByFamily(ctx context.Context, familyID uuid.UUID) error
+	DeleteTokensByUser(ctx context.Context, userID uuid.UUID) error
+	DeleteTournament(ctx context.Context, id int64) (int64, error)
 	DeleteUserById(ctx context.Context, id uuid.UUID) error
+	EnqueueJob(ctx context.Context, arg EnqueueJobParams) (int64, error)
+	GetActiveOtp(ctx context.Context, arg GetActiveOtpParams) (Otp, error)
 	GetActiveTgBotUsers(ctx context.Context) ([]int64, error)
+	GetAllLichessTeamMembers(ctx context.Context) ([]Lichess, error)
+	GetChesscomUsernames(ctx context.Context) ([]string, error)
+	GetFirstLeaderboardSnapshot(ctx context.Context) (LeaderboardSnapshot, error)
+	GetJobsByStatus(ctx context.Context, arg GetJobsByStatusParams) ([]GetJobsByStatusRow, error)
+	GetLastRound(ctx context.Context, tournamentID int64) (int32, error)
+	GetLatestLeaderboardSnapshot(ctx context.Context) (LeaderboardSnapshot, error)
+	GetLeaderboardChanges(ctx context.Context, arg GetLeaderboardChangesParams) ([]GetLeaderboardChangesRow, error)
+	GetLeaderboardSnapshotBefore(ctx context.Context, takenAt time.Time) (LeaderboardSnapshot, error)
+	GetLichessMembershipEvents(ctx context.Context, arg GetLichessMembershipEventsParams) ([]LichessMembershipEvent, error)
+	GetLichessTeamMemberIds(ctx context.Context) ([]string, error)
 	GetLichessTeamMembers(ctx context.Context) ([]string, error)
+	GetLinkedAccounts(ctx context.Context) ([]GetLinkedAccountsRow, error)
+	GetLinkedLichessAccounts(ctx context.Context) ([]GetLinkedLichessAccountsRow, error)
+	GetLoginFailure(ctx context.Context, key string) (LoginFailure, error)
+	GetRatingHistory(ctx context.Context, arg GetRatingHistoryParams) ([]GetRatingHistoryRow, error)
+	GetResultChanges(ctx context.Context, arg GetResultChangesParams) ([]GetResultChangesRow, error)
+	GetRoles(ctx context.Context) ([]Role, error)
+	GetRoundGames(ctx context.Context, arg GetRoundGamesParams) ([]TournamentGame, error)
+	GetSessionsByUser(ctx context.Context, userID uuid.UUID) ([]GetSessionsByUserRow, error)
+	GetSmsMessages(ctx context.Context, arg GetSmsMessagesParams) ([]SmsMessage, error)
+	GetToken(ctx context.Context, arg GetTokenParams) (Token, error)
+	GetTournament(ctx context.Context, id int64) (Tournament, error)
+	GetTournamentForUpdate(ctx context.Context, id int64) (Tournament, error)
+	GetTournamentGame(ctx context.Context, arg GetTournamentGameParams) (TournamentGame, error)
+	GetTournamentGameForUpdate(ctx context.Context, arg GetTournamentGameForUpdateParams) (TournamentGame, error)
+	GetTournamentGames(ctx context.Context, tournamentID int64) ([]TournamentGame, error)
+	GetTournamentPlayer(ctx context.Context, arg GetTournamentPlayerParams) (TournamentPlayer, error)
+	GetTournamentPlayerByUser(ctx context.Context, arg GetTournamentPlayerByUserParams) (TournamentPlayer, error)
+	GetTournamentPlayers(ctx context.Context, tournamentID int64) ([]TournamentPlayer, error)
 	GetUserById(ctx context.Context, id uuid.UUID) (GetUserByIdRow, error)
 	GetUserByToken(ctx context.Context, arg GetUserByTokenParams) (GetUserByTokenRow, error)
 	GetUserByUsername(ctx context.Context, username string) (GetUserByUsernameRow, error)
 	GetUserByUsernameOrPhone(ctx context.Context, arg GetUserByUsernameOrPhoneParams) (User, error)
-	GetUserForResetOrActivation(ctx context.Context, arg GetUserForResetOrActivationParams) (GetUserForResetOrActivationRow, error)
+	GetUserPermissions(ctx context.Context, userID uuid.UUID) ([]string, error)
+	GetUserRoles(ctx context.Context, userID uuid.UUID) ([]string, error)
+	HitRateLimit(ctx context.Context, arg HitRateLimitParams) (HitRateLimitRow, error)
 	InsertLichessTeamMember(ctx context.Context, arg InsertLichessTeamMemberParams) error
 	InsertTgBotUsers(ctx context.Context, arg InsertTgBotUsersParams) error
+	InvalidateOtps(ctx context.Context, arg InvalidateOtpsParams) error
+	KillJob(ctx context.Context, arg KillJobParams) error
+	LinkLichessAccount(ctx context.Context, arg LinkLichessAccountParams) error
+	ListTournaments(ctx context.Context, arg ListTournamentsParams) ([]Tournament, error)
+	LockLeaderboardSnapshots(ctx context.Context) error
+	LockLogin(ctx context.Context, arg LockLoginParams) error
+	MarkLichessTeamMemberLeft(ctx context.Context, lichessID string) error
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (int32, error)
+	RemoveUserRole(ctx context.Context, arg RemoveUserRoleParams) (int64, error)
+	RequeueStaleJobs(ctx context.Context, staleSeconds int32) (int64, error)
+	ResetLoginFailures(ctx context.Context, key string) error
+	RetryJob(ctx context.Context, arg RetryJobParams) error
+	ReviveJob(ctx context.Context, id int64) (int64, error)
+	RotateToken(ctx context.Context, hash []byte) (int64, error)
+	SetTournamentRounds(ctx context.Context, arg SetTournamentRoundsParams) error
+	SetTournamentStatus(ctx context.Context, arg SetTournamentStatusParams) error
+	TouchToken(ctx context.Context, hash []byte) error
+	UnlinkLichessAccount(ctx context.Context, id uuid.UUID) error
+	UpdateGameResult(ctx context.Context, arg UpdateGameResultParams) (TournamentGame, error)
+	UpdateSmsMessageResult(ctx context.Context, arg UpdateSmsMessageResultParams) error
+	UpdateSmsMessageStatus(ctx context.Context, arg UpdateSmsMessageStatusParams) (int64, error)
 	UpdateTgBotUsers(ctx context.Context, arg UpdateTgBotUsersParams) error
+	UpdateTournament(ctx context.Context, arg UpdateTournamentParams) (Tournament, error)
 	UpdateUserById(ctx context.Context, arg UpdateUserByIdParams) error
+	UpdateUserPhoneNumber(ctx context.Context, arg UpdateUserPhoneNumberParams) error
+	WithdrawTournamentPlayer(ctx context.Context, arg WithdrawTournamentPlayerParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidResult  = errors.New("result doesn't fit the game")
+	ErrReasonRequired = errors.New("a correction needs a reason")
+	ErrResultLocked   = errors.New("result is locked by a later round or the tournament's end")
+)
+
+type ReportResultParams struct {
+	TournamentID int64
+	GameID       int64
+	Result       string
+	Reason       string
+	ChangedBy    uuid.NullUUID
+	Override     bool
+}
+
+// ReportResultTx sets the game's result and records the change in
+// result_changes, correcting a reported result needs a reason. Reporting the
+// result the game already has changes nothing. Results of finished
+// tournaments and corrections once a later round is paired need Override,
+// results of cancelled tournaments can't change. The tournament row stays
+// locked until commit so no round is paired meanwhile.
+func (store *SQLStore) ReportResultTx(ctx context.Context, arg ReportResultParams) (TournamentGame, error) {
+
+	var game TournamentGame
+
+	err := store.execTx(ctx, func(q *Queries) error {
+
+		tournament, err := q.GetTournamentForUpdate(ctx, arg.TournamentID)
+		if err != nil {
+			return err
+		}
+
+		if tournament.Status == TournamentCancelled {
+			return ErrTournamentClosed
+		}
+
+		game, err = q.GetTournamentGameForUpdate(ctx, GetTournamentGameForUpdateParams{
+			TournamentID: arg.TournamentID,
+			ID:           arg.GameID,
+		})
+		if err != nil {
+			return err
+		}
+
+		if !ValidResult(arg.Result, !game.BlackID.Valid) {
+			return ErrInvalidResult
+		}
+
+		if game.Result == arg.Result {
+			return nil
+		}
+
+		if game.Result != ResultPending && arg.Reason == "" {
+			return ErrReasonRequired
+		}
+
+		last, err := q.GetLastRound(ctx, arg.TournamentID)
+		if err != nil {
+			return err
+		}
+
+		locked := resultLocked(tournament.Status, game, last)
+		if locked && !arg.Override {
+			return ErrResultLocked
+		}
+
+		err = q.CreateResultChange(ctx, CreateResultChangeParams{
+			TournamentID: arg.TournamentID,
+			GameID:       game.ID,
+			Round:        game.Round,
+			Board:        game.Board,
+			Game:         game.Game,
+			OldResult:    game.Result,
+			NewResult:    arg.Result,
+			Reason:       arg.Reason,
+			ChangedBy:    arg.ChangedBy,
+			Override:     locked,
+		})
+		if err != nil {
+			return err
+		}
+
+		game, err = q.UpdateGameResult(ctx, UpdateGameResultParams{
+			Result: arg.Result,
+			ID:     game.ID,
+		})
+		return err
+	})
+
+	return game, err
+}
+
+// resultLocked reports whether changing the game's result needs an override,
+// last is the tournament's last paired round.
+func resultLocked(status string, game TournamentGame, last int32) bool {
+	if status == TournamentFinished {
+		return true
+	}
+	return game.Result != ResultPending && game.Round < last
+}
+
+// ValidResult reports whether result can be entered for a game, bye games
+// only take bye results.
+func ValidResult(result string, bye bool) bool {
+	switch result {
+	case ResultBye, ResultHalfBye, ResultZeroBye:
+		return bye
+	case ResultWhiteWins, ResultBlackWins, ResultDraw, ResultWhiteForfeit, ResultBlackForfeit, ResultDoubleForfeit:
+		return !bye
+	default:
+		return false
+	}
+}
@@ -0,0 +1,65 @@
+package db
+
+import "testing"
+
+func TestValidResult(t *testing.T) {
+
+	tests := []struct {
+		result string
+		bye    bool
+		want   bool
+	}{
+		{result: ResultWhiteWins, want: true},
+		{result: ResultBlackWins, want: true},
+		{result: ResultDraw, want: true},
+		{result: ResultWhiteForfeit, want: true},
+		{result: ResultBlackForfeit, want: true},
+		{result: ResultDoubleForfeit, want: true},
+		{result: ResultBye, want: false},
+		{result: ResultHalfBye, want: false},
+		{result: ResultZeroBye, want: false},
+		{result: ResultBye, bye: true, want: true},
+		{result: ResultHalfBye, bye: true, want: true},
+		{result: ResultZeroBye, bye: true, want: true},
+		{result: ResultWhiteWins, bye: true, want: false},
+		{result: ResultDoubleForfeit, bye: true, want: false},
+		{result: ResultPending, want: false},
+		{result: ResultPending, bye: true, want: false},
+		{result: "½-½", want: false},
+		{result: "1-1", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidResult(tt.result, tt.bye); got != tt.want {
+			t.Errorf("ValidResult(%q, %t) = %t, want %t", tt.result, tt.bye, got, tt.want)
+		}
+	}
+}
+
+func TestResultLocked(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		status string
+		result string
+		round  int32
+		last   int32
+		want   bool
+	}{
+		{name: "first report", status: TournamentOngoing, result: ResultPending, round: 2, last: 2, want: false},
+		{name: "correction in the last round", status: TournamentOngoing, result: ResultDraw, round: 2, last: 2, want: false},
+		{name: "correction after a later round", status: TournamentOngoing, result: ResultDraw, round: 1, last: 2, want: true},
+		{name: "late report of an earlier round", status: TournamentOngoing, result: ResultPending, round: 1, last: 2, want: false},
+		{name: "first report when finished", status: TournamentFinished, result: ResultPending, round: 2, last: 2, want: true},
+		{name: "correction when finished", status: TournamentFinished, result: ResultDraw, round: 2, last: 2, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := TournamentGame{Round: tt.round, Result: tt.result}
+			if got := resultLocked(tt.status, game, tt.last); got != tt.want {
+				t.Errorf("resultLocked() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
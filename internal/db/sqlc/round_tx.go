@@ -0,0 +1,220 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// results of tournament games, from white's point of view. Forfeits are
+// written +- and -+, -- when neither player showed up. A bye game only has
+// white, who scores a point, half a point or nothing.
+const (
+	ResultPending       = ""
+	ResultWhiteWins     = "1-0"
+	ResultBlackWins     = "0-1"
+	ResultDraw          = "1/2-1/2"
+	ResultWhiteForfeit  = "+-"
+	ResultBlackForfeit  = "-+"
+	ResultDoubleForfeit = "--"
+	ResultBye           = "bye"
+	ResultHalfBye       = "half-bye"
+	ResultZeroBye       = "zero-bye"
+)
+
+const (
+	GameRegular    = "regular"
+	GameTiebreak   = "tiebreak"
+	GameArmageddon = "armageddon"
+)
+
+var (
+	ErrTournamentClosed = errors.New("tournament is over")
+	ErrRoundInProgress  = errors.New("previous round has unreported games")
+	ErrRoundConflict    = errors.New("round was changed concurrently")
+	ErrRoundReported    = errors.New("round has reported results")
+	ErrNotLastRound     = errors.New("round is not the last round")
+	ErrMatchNotFound    = errors.New("match not found")
+	ErrMatchInProgress  = errors.New("match has unreported games")
+)
+
+// CreateRoundParams holds the games of the next round, or of every round from
+// Round on when a whole schedule is drawn. Rounds, when set, replaces the
+// tournament's number of rounds.
+type CreateRoundParams struct {
+	TournamentID int64
+	Round        int32
+	Rounds       int32
+	Games        []CreateTournamentGameParams
+}
+
+// CreateRoundTx stores the games of the tournament's next round once every
+// game of the previous round is reported, and starts the tournament with its
+// first round. The tournament row stays locked until commit so the same round
+// can't be paired twice.
+func (store *SQLStore) CreateRoundTx(ctx context.Context, arg CreateRoundParams) ([]TournamentGame, error) {
+
+	games := make([]TournamentGame, 0, len(arg.Games))
+
+	err := store.execTx(ctx, func(q *Queries) error {
+
+		tournament, err := q.GetTournamentForUpdate(ctx, arg.TournamentID)
+		if err != nil {
+			return err
+		}
+
+		if tournament.Status == TournamentFinished || tournament.Status == TournamentCancelled {
+			return ErrTournamentClosed
+		}
+
+		last, err := q.GetLastRound(ctx, arg.TournamentID)
+		if err != nil {
+			return err
+		}
+		if last != arg.Round-1 {
+			return ErrRoundConflict
+		}
+
+		pending, err := q.CountPendingGames(ctx, arg.TournamentID)
+		if err != nil {
+			return err
+		}
+		if pending > 0 {
+			return ErrRoundInProgress
+		}
+
+		for _, g := range arg.Games {
+			game, err := q.CreateTournamentGame(ctx, g)
+			if err != nil {
+				return err
+			}
+			games = append(games, game)
+		}
+
+		if arg.Rounds > 0 {
+			err := q.SetTournamentRounds(ctx, SetTournamentRoundsParams{
+				Rounds: arg.Rounds,
+				ID:     arg.TournamentID,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if tournament.Status == TournamentScheduled {
+			return q.SetTournamentStatus(ctx, SetTournamentStatusParams{
+				Status: TournamentOngoing,
+				ID:     arg.TournamentID,
+			})
+		}
+
+		return nil
+	})
+
+	return games, err
+}
+
+// DeleteRoundTx removes the tournament's last round while none of its games
+// are reported, so organizers can pair it again after fixing the field.
+func (store *SQLStore) DeleteRoundTx(ctx context.Context, arg DeleteRoundGamesParams) error {
+
+	return store.execTx(ctx, func(q *Queries) error {
+
+		tournament, err := q.GetTournamentForUpdate(ctx, arg.TournamentID)
+		if err != nil {
+			return err
+		}
+
+		if tournament.Status == TournamentFinished || tournament.Status == TournamentCancelled {
+			return ErrTournamentClosed
+		}
+
+		last, err := q.GetLastRound(ctx, arg.TournamentID)
+		if err != nil {
+			return err
+		}
+		if last != arg.Round {
+			return ErrNotLastRound
+		}
+
+		games, err := q.GetRoundGames(ctx, GetRoundGamesParams(arg))
+		if err != nil {
+			return err
+		}
+		for _, g := range games {
+			if g.Result != ResultPending && g.Result != ResultBye {
+				return ErrRoundReported
+			}
+		}
+
+		_, err = q.DeleteRoundGames(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		if arg.Round == 1 {
+			return q.SetTournamentStatus(ctx, SetTournamentStatusParams{
+				Status: TournamentScheduled,
+				ID:     arg.TournamentID,
+			})
+		}
+
+		return nil
+	})
+}
+
+// AddMatchGameTx adds a tiebreak or armageddon game after the reported games
+// of a match of the tournament's last round, arg.Game is set to the next
+// game number.
+func (store *SQLStore) AddMatchGameTx(ctx context.Context, arg CreateTournamentGameParams) (TournamentGame, error) {
+
+	var game TournamentGame
+
+	err := store.execTx(ctx, func(q *Queries) error {
+
+		tournament, err := q.GetTournamentForUpdate(ctx, arg.TournamentID)
+		if err != nil {
+			return err
+		}
+
+		if tournament.Status == TournamentFinished || tournament.Status == TournamentCancelled {
+			return ErrTournamentClosed
+		}
+
+		last, err := q.GetLastRound(ctx, arg.TournamentID)
+		if err != nil {
+			return err
+		}
+		if last != arg.Round {
+			return ErrNotLastRound
+		}
+
+		games, err := q.GetRoundGames(ctx, GetRoundGamesParams{
+			TournamentID: arg.TournamentID,
+			Round:        arg.Round,
+		})
+		if err != nil {
+			return err
+		}
+
+		arg.Game = 0
+		for _, g := range games {
+			if g.Board != arg.Board {
+				continue
+			}
+			if g.Result == ResultPending {
+				return ErrMatchInProgress
+			}
+			arg.Game = max(arg.Game, g.Game)
+		}
+
+		if arg.Game == 0 {
+			return ErrMatchNotFound
+		}
+		arg.Game++
+
+		game, err = q.CreateTournamentGame(ctx, arg)
+		return err
+	})
+
+	return game, err
+}
@@ -1,11 +1,23 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 )
 
 type Store interface {
 	Querier
+	CreateLeaderboardSnapshotTx(ctx context.Context, arg CreateLeaderboardSnapshotTxParams) (LeaderboardSnapshot, error)
+	SyncLichessTeamTx(ctx context.Context, roster []InsertLichessTeamMemberParams) (SyncLichessTeamResult, error)
+	RegisterTournamentPlayerTx(ctx context.Context, arg CreateTournamentPlayerParams) (TournamentPlayer, error)
+	CreateRoundTx(ctx context.Context, arg CreateRoundParams) ([]TournamentGame, error)
+	DeleteRoundTx(ctx context.Context, arg DeleteRoundGamesParams) error
+	AddMatchGameTx(ctx context.Context, arg CreateTournamentGameParams) (TournamentGame, error)
+	ReportResultTx(ctx context.Context, arg ReportResultParams) (TournamentGame, error)
+	ImportTournamentTx(ctx context.Context, arg ImportTournamentParams) (Tournament, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserRow, error)
+	ExecTx(ctx context.Context, fn func(Querier) error) error
 }
 
 type SQLStore struct {
@@ -19,3 +31,30 @@ func NewStore(db *sql.DB) Store {
 		Queries: New(db),
 	}
 }
+
+// execTx runs fn inside a transaction, rolling it back when fn fails.
+func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
+
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = fn(New(tx))
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ExecTx runs fn inside a transaction for writes that span packages, such as
+// a row and the job that follows it up.
+func (store *SQLStore) ExecTx(ctx context.Context, fn func(Querier) error) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		return fn(q)
+	})
+}
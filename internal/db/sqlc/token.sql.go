@@ -13,15 +13,18 @@ import (
 )
 
 const createToken = `-- name: CreateToken :exec
-INSERT INTO token (hash, user_id, expiry, scope)
-VALUES ($1, $2, $3, $4)
+INSERT INTO token (hash, user_id, expiry, scope, family_id, user_agent, ip)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 `
 
 type CreateTokenParams struct {
-	Hash   []byte    `json:"hash"`
-	UserID uuid.UUID `json:"user_id"`
-	Expiry time.Time `json:"expiry"`
-	Scope  string    `json:"scope"`
+	Hash      []byte    `json:"hash"`
+	UserID    uuid.UUID `json:"user_id"`
+	Expiry    time.Time `json:"expiry"`
+	Scope     string    `json:"scope"`
+	FamilyID  uuid.UUID `json:"family_id"`
+	UserAgent string    `json:"user_agent"`
+	Ip        string    `json:"ip"`
 }
 
 func (q *Queries) CreateToken(ctx context.Context, arg CreateTokenParams) error {
@@ -30,10 +33,30 @@ func (q *Queries) CreateToken(ctx context.Context, arg CreateTokenParams) error
 		arg.UserID,
 		arg.Expiry,
 		arg.Scope,
+		arg.FamilyID,
+		arg.UserAgent,
+		arg.Ip,
 	)
 	return err
 }
 
+const deleteSession = `-- name: DeleteSession :execrows
+DELETE FROM token WHERE family_id = $1 AND user_id = $2
+`
+
+type DeleteSessionParams struct {
+	FamilyID uuid.UUID `json:"family_id"`
+	UserID   uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) DeleteSession(ctx context.Context, arg DeleteSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteSession, arg.FamilyID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteToken = `-- name: DeleteToken :exec
 DELETE FROM token WHERE token.hash = $1 and user_id = $2
 `
@@ -47,3 +70,122 @@ func (q *Queries) DeleteToken(ctx context.Context, arg DeleteTokenParams) error
 	_, err := q.db.ExecContext(ctx, deleteToken, arg.Hash, arg.UserID)
 	return err
 }
+
+const deleteTokensByFamily = `-- name: DeleteTokensByFamily :exec
+DELETE FROM token WHERE family_id = $1
+`
+
+func (q *Queries) DeleteTokensByFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteTokensByFamily, familyID)
+	return err
+}
+
+const deleteTokensByUser = `-- name: DeleteTokensByUser :exec
+DELETE FROM token WHERE user_id = $1
+`
+
+func (q *Queries) DeleteTokensByUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteTokensByUser, userID)
+	return err
+}
+
+const getSessionsByUser = `-- name: GetSessionsByUser :many
+SELECT family_id,
+       MIN(created_at)::timestamptz AS created_at,
+       MAX(expiry)::timestamptz AS expiry,
+       MAX(last_used_at)::timestamptz AS last_used_at,
+       user_agent,
+       ip
+FROM token
+WHERE user_id = $1 AND expiry > NOW()
+GROUP BY family_id, user_agent, ip
+ORDER BY last_used_at DESC
+`
+
+type GetSessionsByUserRow struct {
+	FamilyID   uuid.UUID `json:"family_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Expiry     time.Time `json:"expiry"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	UserAgent  string    `json:"user_agent"`
+	Ip         string    `json:"ip"`
+}
+
+func (q *Queries) GetSessionsByUser(ctx context.Context, userID uuid.UUID) ([]GetSessionsByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getSessionsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetSessionsByUserRow{}
+	for rows.Next() {
+		var i GetSessionsByUserRow
+		if err := rows.Scan(
+			&i.FamilyID,
+			&i.CreatedAt,
+			&i.Expiry,
+			&i.LastUsedAt,
+			&i.UserAgent,
+			&i.Ip,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getToken = `-- name: GetToken :one
+SELECT hash, user_id, expiry, scope, family_id, rotated, created_at, last_used_at, user_agent, ip FROM token WHERE hash = $1 AND scope = $2
+`
+
+type GetTokenParams struct {
+	Hash  []byte `json:"hash"`
+	Scope string `json:"scope"`
+}
+
+func (q *Queries) GetToken(ctx context.Context, arg GetTokenParams) (Token, error) {
+	row := q.db.QueryRowContext(ctx, getToken, arg.Hash, arg.Scope)
+	var i Token
+	err := row.Scan(
+		&i.Hash,
+		&i.UserID,
+		&i.Expiry,
+		&i.Scope,
+		&i.FamilyID,
+		&i.Rotated,
+		&i.CreatedAt,
+		&i.LastUsedAt,
+		&i.UserAgent,
+		&i.Ip,
+	)
+	return i, err
+}
+
+const rotateToken = `-- name: RotateToken :execrows
+UPDATE token SET rotated = true WHERE hash = $1 AND rotated = false
+`
+
+func (q *Queries) RotateToken(ctx context.Context, hash []byte) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rotateToken, hash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const touchToken = `-- name: TouchToken :exec
+UPDATE token SET last_used_at = NOW()
+WHERE hash = $1 AND last_used_at < NOW() - INTERVAL '1 minute'
+`
+
+func (q *Queries) TouchToken(ctx context.Context, hash []byte) error {
+	_, err := q.db.ExecContext(ctx, touchToken, hash)
+	return err
+}
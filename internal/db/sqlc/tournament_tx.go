@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+const (
+	TournamentScheduled = "scheduled"
+	TournamentOngoing   = "ongoing"
+	TournamentFinished  = "finished"
+	TournamentCancelled = "cancelled"
+)
+
+const (
+	PlayerRegistered = "registered"
+	PlayerWithdrawn  = "withdrawn"
+)
+
+var (
+	ErrRegistrationClosed = errors.New("tournament is not taking registrations")
+	ErrTournamentFull     = errors.New("tournament is full")
+	ErrAlreadyRegistered  = errors.New("player is already registered")
+)
+
+// RegisterTournamentPlayerTx adds the player while the tournament is
+// scheduled and has room. The tournament row stays locked until commit so
+// concurrent registrations can't go over max_players.
+func (store *SQLStore) RegisterTournamentPlayerTx(ctx context.Context, arg CreateTournamentPlayerParams) (TournamentPlayer, error) {
+
+	var player TournamentPlayer
+
+	err := store.execTx(ctx, func(q *Queries) error {
+
+		tournament, err := q.GetTournamentForUpdate(ctx, arg.TournamentID)
+		if err != nil {
+			return err
+		}
+
+		if tournament.Status != TournamentScheduled {
+			return ErrRegistrationClosed
+		}
+
+		if arg.UserID.Valid {
+			existing, err := q.GetTournamentPlayerByUser(ctx, GetTournamentPlayerByUserParams{
+				TournamentID: arg.TournamentID,
+				UserID:       arg.UserID,
+			})
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			if err == nil && existing.Status == PlayerRegistered {
+				return ErrAlreadyRegistered
+			}
+		}
+
+		if tournament.MaxPlayers > 0 {
+			count, err := q.CountActiveTournamentPlayers(ctx, arg.TournamentID)
+			if err != nil {
+				return err
+			}
+			if count >= int64(tournament.MaxPlayers) {
+				return ErrTournamentFull
+			}
+		}
+
+		player, err = q.CreateTournamentPlayer(ctx, arg)
+		return err
+	})
+
+	return player, err
+}
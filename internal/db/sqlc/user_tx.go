@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+)
+
+// CreateUserTxParams holds a new user and the role they start with.
+// AfterCreate, when set, runs in the same transaction once the user exists.
+type CreateUserTxParams struct {
+	CreateUserParams
+	Role        string
+	AfterCreate func(q Querier, user CreateUserRow) error
+}
+
+// CreateUserTx creates the user together with their first role, so a user is
+// never left without one.
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserRow, error) {
+
+	var user CreateUserRow
+
+	err := store.execTx(ctx, func(q *Queries) error {
+
+		var err error
+		user, err = q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		err = q.AddUserRole(ctx, AddUserRoleParams{UserID: user.ID, Role: arg.Role})
+		if err != nil {
+			return err
+		}
+
+		if arg.AfterCreate != nil {
+			return arg.AfterCreate(q, user)
+		}
+
+		return nil
+	})
+
+	return user, err
+}
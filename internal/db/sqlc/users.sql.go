@@ -7,6 +7,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -21,12 +22,11 @@ INSERT INTO users
      chesscom_username,
      phone_number,
      photo,
-     passcode,
      password_hash, 
      activated,
      enabled
     )
-VALUES ($1, $2, $3 ,$4, $5, $6, $7, $8,$9, $10) RETURNING id, phone_number
+VALUES ($1, $2, $3 ,$4, $5, $6, $7, $8, $9) RETURNING id, phone_number
 `
 
 type CreateUserParams struct {
@@ -36,8 +36,7 @@ type CreateUserParams struct {
 	ChesscomUsername string `json:"chesscom_username"`
 	PhoneNumber      string `json:"phone_number"`
 	Photo            string `json:"photo"`
-	Passcode         []byte `json:"passcode"`
-	PasswordHash     []byte `json:"password_hash"`
+	PasswordHash     []byte `json:"-"`
 	Activated        bool   `json:"activated"`
 	Enabled          bool   `json:"enabled"`
 }
@@ -55,7 +54,6 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (CreateU
 		arg.ChesscomUsername,
 		arg.PhoneNumber,
 		arg.Photo,
-		arg.Passcode,
 		arg.PasswordHash,
 		arg.Activated,
 		arg.Enabled,
@@ -74,26 +72,130 @@ func (q *Queries) DeleteUserById(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const getChesscomUsernames = `-- name: GetChesscomUsernames :many
+SELECT chesscom_username FROM users
+WHERE chesscom_username <> '' AND activated
+`
+
+func (q *Queries) GetChesscomUsernames(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getChesscomUsernames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var chesscom_username string
+		if err := rows.Scan(&chesscom_username); err != nil {
+			return nil, err
+		}
+		items = append(items, chesscom_username)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLinkedAccounts = `-- name: GetLinkedAccounts :many
+SELECT username, full_name, photo, lichess_id, chesscom_username
+FROM users
+WHERE activated AND enabled AND (lichess_id IS NOT NULL OR chesscom_username <> '')
+`
+
+type GetLinkedAccountsRow struct {
+	Username         string         `json:"username"`
+	FullName         string         `json:"full_name"`
+	Photo            string         `json:"photo"`
+	LichessID        sql.NullString `json:"lichess_id"`
+	ChesscomUsername string         `json:"chesscom_username"`
+}
+
+func (q *Queries) GetLinkedAccounts(ctx context.Context) ([]GetLinkedAccountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLinkedAccounts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetLinkedAccountsRow{}
+	for rows.Next() {
+		var i GetLinkedAccountsRow
+		if err := rows.Scan(
+			&i.Username,
+			&i.FullName,
+			&i.Photo,
+			&i.LichessID,
+			&i.ChesscomUsername,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLinkedLichessAccounts = `-- name: GetLinkedLichessAccounts :many
+SELECT lichess_id, username FROM users
+WHERE lichess_id IS NOT NULL AND activated AND enabled
+`
+
+type GetLinkedLichessAccountsRow struct {
+	LichessID sql.NullString `json:"lichess_id"`
+	Username  string         `json:"username"`
+}
+
+func (q *Queries) GetLinkedLichessAccounts(ctx context.Context) ([]GetLinkedLichessAccountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getLinkedLichessAccounts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetLinkedLichessAccountsRow{}
+	for rows.Next() {
+		var i GetLinkedLichessAccountsRow
+		if err := rows.Scan(&i.LichessID, &i.Username); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserById = `-- name: GetUserById :one
 SELECT id, username, full_name, lichess_username, chesscom_username,
-phone_number, photo, passcode, password_hash, enabled, activated, created_at
+phone_number, photo, password_hash, enabled, activated, created_at, lichess_id
 FROM users
 WHERE id = $1
 `
 
 type GetUserByIdRow struct {
-	ID               uuid.UUID `json:"id"`
-	Username         string    `json:"username"`
-	FullName         string    `json:"full_name"`
-	LichessUsername  string    `json:"lichess_username"`
-	ChesscomUsername string    `json:"chesscom_username"`
-	PhoneNumber      string    `json:"phone_number"`
-	Photo            string    `json:"photo"`
-	Passcode         []byte    `json:"passcode"`
-	PasswordHash     []byte    `json:"password_hash"`
-	Enabled          bool      `json:"enabled"`
-	Activated        bool      `json:"activated"`
-	CreatedAt        time.Time `json:"created_at"`
+	ID               uuid.UUID      `json:"id"`
+	Username         string         `json:"username"`
+	FullName         string         `json:"full_name"`
+	LichessUsername  string         `json:"lichess_username"`
+	ChesscomUsername string         `json:"chesscom_username"`
+	PhoneNumber      string         `json:"phone_number"`
+	Photo            string         `json:"photo"`
+	PasswordHash     []byte         `json:"-"`
+	Enabled          bool           `json:"enabled"`
+	Activated        bool           `json:"activated"`
+	CreatedAt        time.Time      `json:"created_at"`
+	LichessID        sql.NullString `json:"lichess_id"`
 }
 
 func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (GetUserByIdRow, error) {
@@ -107,18 +209,19 @@ func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (GetUserByIdRow
 		&i.ChesscomUsername,
 		&i.PhoneNumber,
 		&i.Photo,
-		&i.Passcode,
 		&i.PasswordHash,
 		&i.Enabled,
 		&i.Activated,
 		&i.CreatedAt,
+		&i.LichessID,
 	)
 	return i, err
 }
 
 const getUserByToken = `-- name: GetUserByToken :one
 SELECT users.id, users.username, users.full_name, users.lichess_username, 
-users.chesscom_username, users.phone_number,users.photo, users.passcode, users.password_hash, users.activated,users.enabled, users.created_at
+users.chesscom_username, users.phone_number,users.photo, users.password_hash, users.activated,users.enabled, users.created_at,
+users.lichess_id, token.family_id
 FROM users
 INNER JOIN token
 ON users.id = token.user_id
@@ -134,18 +237,19 @@ type GetUserByTokenParams struct {
 }
 
 type GetUserByTokenRow struct {
-	ID               uuid.UUID `json:"id"`
-	Username         string    `json:"username"`
-	FullName         string    `json:"full_name"`
-	LichessUsername  string    `json:"lichess_username"`
-	ChesscomUsername string    `json:"chesscom_username"`
-	PhoneNumber      string    `json:"phone_number"`
-	Photo            string    `json:"photo"`
-	Passcode         []byte    `json:"passcode"`
-	PasswordHash     []byte    `json:"password_hash"`
-	Activated        bool      `json:"activated"`
-	Enabled          bool      `json:"enabled"`
-	CreatedAt        time.Time `json:"created_at"`
+	ID               uuid.UUID      `json:"id"`
+	Username         string         `json:"username"`
+	FullName         string         `json:"full_name"`
+	LichessUsername  string         `json:"lichess_username"`
+	ChesscomUsername string         `json:"chesscom_username"`
+	PhoneNumber      string         `json:"phone_number"`
+	Photo            string         `json:"photo"`
+	PasswordHash     []byte         `json:"-"`
+	Activated        bool           `json:"activated"`
+	Enabled          bool           `json:"enabled"`
+	CreatedAt        time.Time      `json:"created_at"`
+	LichessID        sql.NullString `json:"lichess_id"`
+	FamilyID         uuid.UUID      `json:"family_id"`
 }
 
 func (q *Queries) GetUserByToken(ctx context.Context, arg GetUserByTokenParams) (GetUserByTokenRow, error) {
@@ -159,35 +263,36 @@ func (q *Queries) GetUserByToken(ctx context.Context, arg GetUserByTokenParams)
 		&i.ChesscomUsername,
 		&i.PhoneNumber,
 		&i.Photo,
-		&i.Passcode,
 		&i.PasswordHash,
 		&i.Activated,
 		&i.Enabled,
 		&i.CreatedAt,
+		&i.LichessID,
+		&i.FamilyID,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
 SELECT id, username, full_name, lichess_username, chesscom_username,
-phone_number, photo, passcode, password_hash, enabled, activated, created_at
+phone_number, photo, password_hash, enabled, activated, created_at, lichess_id
 FROM users
 WHERE username = $1
 `
 
 type GetUserByUsernameRow struct {
-	ID               uuid.UUID `json:"id"`
-	Username         string    `json:"username"`
-	FullName         string    `json:"full_name"`
-	LichessUsername  string    `json:"lichess_username"`
-	ChesscomUsername string    `json:"chesscom_username"`
-	PhoneNumber      string    `json:"phone_number"`
-	Photo            string    `json:"photo"`
-	Passcode         []byte    `json:"passcode"`
-	PasswordHash     []byte    `json:"password_hash"`
-	Enabled          bool      `json:"enabled"`
-	Activated        bool      `json:"activated"`
-	CreatedAt        time.Time `json:"created_at"`
+	ID               uuid.UUID      `json:"id"`
+	Username         string         `json:"username"`
+	FullName         string         `json:"full_name"`
+	LichessUsername  string         `json:"lichess_username"`
+	ChesscomUsername string         `json:"chesscom_username"`
+	PhoneNumber      string         `json:"phone_number"`
+	Photo            string         `json:"photo"`
+	PasswordHash     []byte         `json:"-"`
+	Enabled          bool           `json:"enabled"`
+	Activated        bool           `json:"activated"`
+	CreatedAt        time.Time      `json:"created_at"`
+	LichessID        sql.NullString `json:"lichess_id"`
 }
 
 func (q *Queries) GetUserByUsername(ctx context.Context, username string) (GetUserByUsernameRow, error) {
@@ -201,17 +306,17 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (GetUs
 		&i.ChesscomUsername,
 		&i.PhoneNumber,
 		&i.Photo,
-		&i.Passcode,
 		&i.PasswordHash,
 		&i.Enabled,
 		&i.Activated,
 		&i.CreatedAt,
+		&i.LichessID,
 	)
 	return i, err
 }
 
 const getUserByUsernameOrPhone = `-- name: GetUserByUsernameOrPhone :one
-SELECT id, username, full_name, lichess_username, chesscom_username, phone_number, password_hash, passcode, activated, enabled, photo, created_at FROM users 
+SELECT id, username, full_name, lichess_username, chesscom_username, phone_number, password_hash, activated, enabled, photo, created_at, lichess_id FROM users 
 WHERE 
     (phone_number = $1 OR $1 = '' ) 
     AND 
@@ -234,66 +339,37 @@ func (q *Queries) GetUserByUsernameOrPhone(ctx context.Context, arg GetUserByUse
 		&i.ChesscomUsername,
 		&i.PhoneNumber,
 		&i.PasswordHash,
-		&i.Passcode,
 		&i.Activated,
 		&i.Enabled,
 		&i.Photo,
 		&i.CreatedAt,
+		&i.LichessID,
 	)
 	return i, err
 }
 
-const getUserForResetOrActivation = `-- name: GetUserForResetOrActivation :one
-SELECT id, username, full_name, lichess_username, chesscom_username,
-phone_number, photo, passcode, password_hash, enabled, activated, created_at
-FROM users
-WHERE 
-   (phone_number = $1 OR $1 = '' ) 
-     AND 
-   (username = $2 OR $2 = '') 
-     AND
-   (passcode = $3)
+const linkLichessAccount = `-- name: LinkLichessAccount :exec
+UPDATE users SET lichess_id = $1, lichess_username = $2 WHERE id = $3
 `
 
-type GetUserForResetOrActivationParams struct {
-	PhoneNumber string `json:"phone_number"`
-	Username    string `json:"username"`
-	Passcode    []byte `json:"passcode"`
+type LinkLichessAccountParams struct {
+	LichessID       sql.NullString `json:"lichess_id"`
+	LichessUsername string         `json:"lichess_username"`
+	ID              uuid.UUID      `json:"id"`
 }
 
-type GetUserForResetOrActivationRow struct {
-	ID               uuid.UUID `json:"id"`
-	Username         string    `json:"username"`
-	FullName         string    `json:"full_name"`
-	LichessUsername  string    `json:"lichess_username"`
-	ChesscomUsername string    `json:"chesscom_username"`
-	PhoneNumber      string    `json:"phone_number"`
-	Photo            string    `json:"photo"`
-	Passcode         []byte    `json:"passcode"`
-	PasswordHash     []byte    `json:"password_hash"`
-	Enabled          bool      `json:"enabled"`
-	Activated        bool      `json:"activated"`
-	CreatedAt        time.Time `json:"created_at"`
+func (q *Queries) LinkLichessAccount(ctx context.Context, arg LinkLichessAccountParams) error {
+	_, err := q.db.ExecContext(ctx, linkLichessAccount, arg.LichessID, arg.LichessUsername, arg.ID)
+	return err
 }
 
-func (q *Queries) GetUserForResetOrActivation(ctx context.Context, arg GetUserForResetOrActivationParams) (GetUserForResetOrActivationRow, error) {
-	row := q.db.QueryRowContext(ctx, getUserForResetOrActivation, arg.PhoneNumber, arg.Username, arg.Passcode)
-	var i GetUserForResetOrActivationRow
-	err := row.Scan(
-		&i.ID,
-		&i.Username,
-		&i.FullName,
-		&i.LichessUsername,
-		&i.ChesscomUsername,
-		&i.PhoneNumber,
-		&i.Photo,
-		&i.Passcode,
-		&i.PasswordHash,
-		&i.Enabled,
-		&i.Activated,
-		&i.CreatedAt,
-	)
-	return i, err
+const unlinkLichessAccount = `-- name: UnlinkLichessAccount :exec
+UPDATE users SET lichess_id = NULL WHERE id = $1
+`
+
+func (q *Queries) UnlinkLichessAccount(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, unlinkLichessAccount, id)
+	return err
 }
 
 const updateUserById = `-- name: UpdateUserById :exec
@@ -302,15 +378,15 @@ SET
     username = $1, 
     full_name = $2, 
     lichess_username = $3, 
+    lichess_id = CASE WHEN lichess_username = $3 THEN lichess_id END,
     chesscom_username = $4, 
     phone_number = $5, 
     photo = $6,
-    passcode = $7,
-    password_hash = $8, 
-    activated = $9,
-    enabled = $10
+    password_hash = $7, 
+    activated = $8,
+    enabled = $9
 WHERE 
-    id = $11
+    id = $10
 `
 
 type UpdateUserByIdParams struct {
@@ -320,8 +396,7 @@ type UpdateUserByIdParams struct {
 	ChesscomUsername string    `json:"chesscom_username"`
 	PhoneNumber      string    `json:"phone_number"`
 	Photo            string    `json:"photo"`
-	Passcode         []byte    `json:"passcode"`
-	PasswordHash     []byte    `json:"password_hash"`
+	PasswordHash     []byte    `json:"-"`
 	Activated        bool      `json:"activated"`
 	Enabled          bool      `json:"enabled"`
 	ID               uuid.UUID `json:"id"`
@@ -335,7 +410,6 @@ func (q *Queries) UpdateUserById(ctx context.Context, arg UpdateUserByIdParams)
 		arg.ChesscomUsername,
 		arg.PhoneNumber,
 		arg.Photo,
-		arg.Passcode,
 		arg.PasswordHash,
 		arg.Activated,
 		arg.Enabled,
@@ -343,3 +417,17 @@ func (q *Queries) UpdateUserById(ctx context.Context, arg UpdateUserByIdParams)
 	)
 	return err
 }
+
+const updateUserPhoneNumber = `-- name: UpdateUserPhoneNumber :exec
+UPDATE users SET phone_number = $1 WHERE id = $2
+`
+
+type UpdateUserPhoneNumberParams struct {
+	PhoneNumber string    `json:"phone_number"`
+	ID          uuid.UUID `json:"id"`
+}
+
+func (q *Queries) UpdateUserPhoneNumber(ctx context.Context, arg UpdateUserPhoneNumberParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPhoneNumber, arg.PhoneNumber, arg.ID)
+	return err
+}
@@ -0,0 +1,196 @@
+// Package imaging validates uploaded photos and re-encodes them. Decoding and
+// encoding again drops EXIF and any other metadata the file carried, so the
+// EXIF orientation of a JPEG is applied to the pixels first.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+)
+
+// ThumbnailSizes are the edges in pixels of the square thumbnails.
+var ThumbnailSizes = []int{64, 128, 256}
+
+// MaxPixels guards against images that are small on disk but huge once
+// decoded.
+const MaxPixels = 40_000_000
+
+var (
+	ErrTooLarge    = errors.New("imaging: file too large")
+	ErrUnsupported = errors.New("imaging: unsupported image type")
+	ErrInvalid     = errors.New("imaging: invalid image")
+	// ErrTooManyPixels is an ErrInvalid for images over MaxPixels.
+	ErrTooManyPixels = fmt.Errorf("%w: more than %d pixels", ErrInvalid, MaxPixels)
+)
+
+// Image is a processed upload, Original keeps the dimensions of the upload.
+type Image struct {
+	ContentType string
+	Ext         string
+	Original    []byte
+	Thumbnails  map[int][]byte
+}
+
+// Process reads at most maxSize bytes from r and rejects anything that isn't
+// a JPEG, PNG or GIF judging by its content, whatever the file name says.
+// JPEGs stay JPEGs, PNGs and GIFs become PNGs, only the first GIF frame is kept.
+func Process(r io.Reader, maxSize int64) (*Image, error) {
+
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, ErrUnsupported
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalid
+	}
+
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrInvalid
+	}
+
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	var img image.Image
+	switch contentType {
+	case "image/jpeg":
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err == nil {
+			img = orient(img, jpegOrientation(data))
+		}
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+	case "image/gif":
+		img, err = gif.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, ErrInvalid
+	}
+
+	out := &Image{
+		ContentType: "image/png",
+		Ext:         ".png",
+		Thumbnails:  make(map[int][]byte, len(ThumbnailSizes)),
+	}
+	if contentType == "image/jpeg" {
+		out.ContentType = "image/jpeg"
+		out.Ext = ".jpg"
+	}
+
+	out.Original, err = out.encode(img)
+	if err != nil {
+		return nil, err
+	}
+
+	square := cropSquare(img)
+	for _, size := range ThumbnailSizes {
+		out.Thumbnails[size], err = out.encode(resize(square, size))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// ThumbnailName is the file name of a thumbnail of the image stored as name.
+func ThumbnailName(name string, size int) string {
+	for i := len(name) - 1; i >= 0 && name[i] != '/'; i-- {
+		if name[i] == '.' {
+			return fmt.Sprintf("%s_%d%s", name[:i], size, name[i:])
+		}
+	}
+	return fmt.Sprintf("%s_%d", name, size)
+}
+
+func (i *Image) encode(img image.Image) ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	var err error
+	if i.ContentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("imaging: encode: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// cropSquare keeps the centered square of the image.
+func cropSquare(img image.Image) image.Image {
+
+	b := img.Bounds()
+	edge := min(b.Dx(), b.Dy())
+
+	x := b.Min.X + (b.Dx()-edge)/2
+	y := b.Min.Y + (b.Dy()-edge)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, edge, edge))
+	draw.Draw(dst, dst.Bounds(), img, image.Point{X: x, Y: y}, draw.Src)
+
+	return dst
+}
+
+// resize scales a square image to size x size, averaging the source pixels
+// that fall in each destination pixel. Smaller images are scaled up.
+func resize(src image.Image, size int) image.Image {
+
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	for y := 0; y < size; y++ {
+		y0 := b.Min.Y + y*b.Dy()/size
+		y1 := max(b.Min.Y+(y+1)*b.Dy()/size, y0+1)
+
+		for x := 0; x < size; x++ {
+			x0 := b.Min.X + x*b.Dx()/size
+			x1 := max(b.Min.X+(x+1)*b.Dx()/size, x0+1)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					bl += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(bl / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,417 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+var (
+	red   = color.RGBA{R: 255, A: 255}
+	blue  = color.RGBA{B: 255, A: 255}
+	green = color.RGBA{G: 255, A: 255}
+	white = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+)
+
+// halves is a w x h image, red on the left half and blue on the right.
+func halves(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.Set(x, y, red)
+			} else {
+				img.Set(x, y, blue)
+			}
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeGIF(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// withOrientation inserts an EXIF APP1 segment holding the orientation
+// right after the JPEG's start of image marker.
+func withOrientation(data []byte, orientation uint16, order binary.ByteOrder) []byte {
+
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], exifOrientationTag)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	return append(out, data[2:]...)
+}
+
+// near reports whether c is close to want, JPEG doesn't keep exact colors.
+func near(c color.Color, want color.RGBA) bool {
+	r, g, b, _ := c.RGBA()
+	diff := func(a uint32, b uint8) bool {
+		d := int(a>>8) - int(b)
+		return d > -48 && d < 48
+	}
+	return diff(r, want.R) && diff(g, want.G) && diff(b, want.B)
+}
+
+func TestProcessSniffsContent(t *testing.T) {
+
+	img := halves(40, 20)
+
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		ext         string
+	}{
+		{name: "jpeg", data: encodeJPEG(t, img), contentType: "image/jpeg", ext: ".jpg"},
+		{name: "png", data: encodePNG(t, img), contentType: "image/png", ext: ".png"},
+		{name: "gif becomes png", data: encodeGIF(t, img), contentType: "image/png", ext: ".png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			out, err := Process(bytes.NewReader(tt.data), 1<<20)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+
+			if out.ContentType != tt.contentType || out.Ext != tt.ext {
+				t.Errorf("Process() = %s %s, want %s %s", out.ContentType, out.Ext, tt.contentType, tt.ext)
+			}
+
+			cfg, _, err := image.DecodeConfig(bytes.NewReader(out.Original))
+			if err != nil {
+				t.Fatalf("original doesn't decode: %v", err)
+			}
+			if cfg.Width != 40 || cfg.Height != 20 {
+				t.Errorf("original is %dx%d, want 40x20", cfg.Width, cfg.Height)
+			}
+
+			for _, size := range ThumbnailSizes {
+				cfg, _, err := image.DecodeConfig(bytes.NewReader(out.Thumbnails[size]))
+				if err != nil {
+					t.Fatalf("thumbnail %d doesn't decode: %v", size, err)
+				}
+				if cfg.Width != size || cfg.Height != size {
+					t.Errorf("thumbnail %d is %dx%d", size, cfg.Width, cfg.Height)
+				}
+			}
+		})
+	}
+}
+
+// pngWithSize is a valid PNG whose header claims the dimensions.
+func pngWithSize(t *testing.T, width, height uint32) []byte {
+
+	data := encodePNG(t, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+
+	// signature, IHDR length and type, then width and height
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	return data
+}
+
+func TestProcessRejects(t *testing.T) {
+
+	valid := encodePNG(t, halves(10, 10))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		maxSize int64
+		err     error
+	}{
+		{name: "text", data: []byte("hello, this is not a photo"), maxSize: 1 << 20, err: ErrUnsupported},
+		{name: "html", data: []byte("<html><body>hi</body></html>"), maxSize: 1 << 20, err: ErrUnsupported},
+		{name: "pdf", data: []byte("%PDF-1.7\n"), maxSize: 1 << 20, err: ErrUnsupported},
+		{name: "empty", data: nil, maxSize: 1 << 20, err: ErrUnsupported},
+		{name: "truncated png", data: valid[:40], maxSize: 1 << 20, err: ErrInvalid},
+		{name: "too many pixels", data: pngWithSize(t, 10000, 5000), maxSize: 1 << 20, err: ErrTooManyPixels},
+		{name: "zero width", data: pngWithSize(t, 0, 10), maxSize: 1 << 20, err: ErrInvalid},
+		{name: "file too large", data: valid, maxSize: int64(len(valid) - 1), err: ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Process(bytes.NewReader(tt.data), tt.maxSize)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Process() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestProcessMaxPixels(t *testing.T) {
+
+	// at the limit the header passes, the 1x1 body then fails to decode
+	_, err := Process(bytes.NewReader(pngWithSize(t, MaxPixels/10, 10)), 1<<20)
+	if !errors.Is(err, ErrInvalid) || errors.Is(err, ErrTooManyPixels) {
+		t.Fatalf("Process() at the limit error = %v, want a decode error", err)
+	}
+
+	_, err = Process(bytes.NewReader(pngWithSize(t, MaxPixels/10+1, 10)), 1<<20)
+	if !errors.Is(err, ErrTooManyPixels) || !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Process() over the limit error = %v, want ErrTooManyPixels", err)
+	}
+}
+
+// quadrants is a w x h image, red top left, blue top right, green bottom
+// left and white bottom right.
+func quadrants(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			switch {
+			case x < w/2 && y < h/2:
+				img.Set(x, y, red)
+			case y < h/2:
+				img.Set(x, y, blue)
+			case x < w/2:
+				img.Set(x, y, green)
+			default:
+				img.Set(x, y, white)
+			}
+		}
+	}
+	return img
+}
+
+func TestProcessAppliesOrientation(t *testing.T) {
+
+	data := encodeJPEG(t, quadrants(48, 32))
+
+	tests := []struct {
+		orientation uint16
+		order       binary.ByteOrder
+		width       int
+		height      int
+		// top left, top right, bottom left and bottom right once upright
+		corners [4]color.RGBA
+	}{
+		{orientation: 1, order: binary.BigEndian, width: 48, height: 32, corners: [4]color.RGBA{red, blue, green, white}},
+		{orientation: 2, order: binary.BigEndian, width: 48, height: 32, corners: [4]color.RGBA{blue, red, white, green}},
+		{orientation: 3, order: binary.LittleEndian, width: 48, height: 32, corners: [4]color.RGBA{white, green, blue, red}},
+		{orientation: 4, order: binary.LittleEndian, width: 48, height: 32, corners: [4]color.RGBA{green, white, red, blue}},
+		{orientation: 5, order: binary.BigEndian, width: 32, height: 48, corners: [4]color.RGBA{red, green, blue, white}},
+		{orientation: 6, order: binary.BigEndian, width: 32, height: 48, corners: [4]color.RGBA{green, red, white, blue}},
+		{orientation: 7, order: binary.LittleEndian, width: 32, height: 48, corners: [4]color.RGBA{white, blue, green, red}},
+		{orientation: 8, order: binary.LittleEndian, width: 32, height: 48, corners: [4]color.RGBA{blue, white, red, green}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.orientation), func(t *testing.T) {
+
+			out, err := Process(bytes.NewReader(withOrientation(data, tt.orientation, tt.order)), 1<<20)
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+
+			img, err := jpeg.Decode(bytes.NewReader(out.Original))
+			if err != nil {
+				t.Fatalf("original doesn't decode: %v", err)
+			}
+
+			b := img.Bounds()
+			if b.Dx() != tt.width || b.Dy() != tt.height {
+				t.Fatalf("original is %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.width, tt.height)
+			}
+
+			points := [4]image.Point{{2, 2}, {b.Dx() - 3, 2}, {2, b.Dy() - 3}, {b.Dx() - 3, b.Dy() - 3}}
+			for i, p := range points {
+				if !near(img.At(p.X, p.Y), tt.corners[i]) {
+					t.Errorf("pixel %v = %v, want %v", p, img.At(p.X, p.Y), tt.corners[i])
+				}
+			}
+
+			if jpegOrientation(out.Original) != 1 {
+				t.Errorf("original kept its orientation tag")
+			}
+		})
+	}
+}
+
+func TestJPEGOrientationMalformed(t *testing.T) {
+
+	data := encodeJPEG(t, halves(8, 8))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "no exif", data: data},
+		{name: "not a jpeg", data: []byte("GIF89a")},
+		{name: "out of range", data: withOrientation(data, 9, binary.BigEndian)},
+		{name: "truncated segment", data: withOrientation(data, 6, binary.BigEndian)[:20]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != 1 {
+				t.Errorf("jpegOrientation() = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestCropSquare(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		img   image.Image
+		edge  int
+		left  color.RGBA
+		right color.RGBA
+	}{
+		// the centered 20x20 of a 40x20 image straddles both halves
+		{name: "landscape", img: halves(40, 20), edge: 20, left: red, right: blue},
+		{name: "portrait", img: halves(20, 40), edge: 20, left: red, right: blue},
+		{name: "square", img: halves(16, 16), edge: 16, left: red, right: blue},
+		{name: "offset bounds", img: halves(40, 20).SubImage(image.Rect(20, 0, 40, 20)), edge: 20, left: blue, right: blue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got := cropSquare(tt.img)
+
+			b := got.Bounds()
+			if b.Dx() != tt.edge || b.Dy() != tt.edge {
+				t.Fatalf("cropSquare() is %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.edge, tt.edge)
+			}
+
+			if got.At(0, tt.edge/2) != tt.left {
+				t.Errorf("left = %v, want %v", got.At(0, tt.edge/2), tt.left)
+			}
+			if got.At(tt.edge-1, tt.edge/2) != tt.right {
+				t.Errorf("right = %v, want %v", got.At(tt.edge-1, tt.edge/2), tt.right)
+			}
+		})
+	}
+}
+
+func TestResize(t *testing.T) {
+
+	t.Run("down averages", func(t *testing.T) {
+
+		// 4x4 of 2x2 blocks, each block half black and half white
+		src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 4; x++ {
+				if x%2 == 0 {
+					src.Set(x, y, color.RGBA{R: 200, G: 100, B: 0, A: 255})
+				} else {
+					src.Set(x, y, color.RGBA{R: 0, G: 100, B: 200, A: 255})
+				}
+			}
+		}
+
+		got := resize(src, 2)
+		if got.Bounds().Dx() != 2 || got.Bounds().Dy() != 2 {
+			t.Fatalf("resize() is %v, want 2x2", got.Bounds())
+		}
+
+		want := color.RGBA{R: 100, G: 100, B: 100, A: 255}
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 2; x++ {
+				if got.At(x, y) != want {
+					t.Errorf("pixel %d,%d = %v, want %v", x, y, got.At(x, y), want)
+				}
+			}
+		}
+	})
+
+	t.Run("up repeats", func(t *testing.T) {
+
+		src := image.NewRGBA(image.Rect(0, 0, 1, 1))
+		src.Set(0, 0, red)
+
+		got := resize(src, 3)
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 3; x++ {
+				if got.At(x, y) != red {
+					t.Errorf("pixel %d,%d = %v, want %v", x, y, got.At(x, y), red)
+				}
+			}
+		}
+	})
+
+	t.Run("keeps halves", func(t *testing.T) {
+
+		got := resize(halves(64, 64), 8)
+		if got.At(1, 4) != red || got.At(6, 4) != blue {
+			t.Errorf("resize() = %v and %v, want red and blue", got.At(1, 4), got.At(6, 4))
+		}
+	})
+}
+
+func TestThumbnailName(t *testing.T) {
+
+	tests := []struct {
+		name string
+		size int
+		want string
+	}{
+		{name: "photos/abc.jpg", size: 64, want: "photos/abc_64.jpg"},
+		{name: "abc.png", size: 256, want: "abc_256.png"},
+		{name: "photos.d/abc", size: 128, want: "photos.d/abc_128"},
+		{name: "abc", size: 64, want: "abc_64"},
+	}
+
+	for _, tt := range tests {
+		if got := ThumbnailName(tt.name, tt.size); got != tt.want {
+			t.Errorf("ThumbnailName(%q, %d) = %q, want %q", tt.name, tt.size, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+)
+
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusDead    = "dead"
+)
+
+var (
+	errUnknownKind = errors.New("no handler for job kind")
+	errExpired     = errors.New("job expired")
+)
+
+// Handler runs a single job. Returning an error schedules a retry until the
+// job runs out of attempts and is moved to the dead state.
+type Handler func(ctx context.Context, payload json.RawMessage) error
+
+type Config struct {
+	Workers      int
+	PollInterval time.Duration
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	JobTimeout   time.Duration
+	// running jobs locked for longer than this are assumed to belong to a
+	// crashed worker and are put back in the queue
+	StaleAfter time.Duration
+	// done and dead jobs are deleted once they are this old
+	Retention time.Duration
+}
+
+// Options changes how a job is stored, the zero value runs it as soon as
+// possible and retries it until it runs out of attempts.
+type Options struct {
+	// the job is given up on instead of run or retried after this time
+	ExpiresAt time.Time
+	// only one job is ever stored for a key, the others are dropped
+	DedupeKey string
+}
+
+// Queue is an outbox stored in the jobs table. Workers claim jobs with
+// SELECT ... FOR UPDATE SKIP LOCKED so several API replicas can share it.
+type Queue struct {
+	store    db.Store
+	cfg      Config
+	handlers map[string]Handler
+}
+
+func New(store db.Store, cfg Config) *Queue {
+	return &Queue{
+		store:    store,
+		cfg:      cfg,
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Register sets the handler for a job kind, it must be called before Run.
+func (q *Queue) Register(kind string, h Handler) {
+	q.handlers[kind] = h
+}
+
+// Enqueue stores a job to be run as soon as a worker is free.
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any) (int64, error) {
+	return q.EnqueueWith(ctx, q.store, kind, payload, Options{})
+}
+
+// EnqueueWith stores a job using querier, which may be a transaction so the
+// job is only queued if the writes it belongs to are committed. The id is 0
+// when a job with the same dedupe key was already queued.
+func (q *Queue) EnqueueWith(ctx context.Context, querier db.Querier, kind string, payload any, opts Options) (int64, error) {
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	args := db.EnqueueJobParams{
+		Kind:        kind,
+		Payload:     data,
+		MaxAttempts: int32(q.cfg.MaxAttempts),
+		RunAt:       time.Now(),
+		ExpiresAt:   sql.NullTime{Time: opts.ExpiresAt, Valid: !opts.ExpiresAt.IsZero()},
+		DedupeKey:   sql.NullString{String: opts.DedupeKey, Valid: opts.DedupeKey != ""},
+	}
+
+	id, err := querier.EnqueueJob(ctx, args)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+
+	return id, err
+}
+
+// Run starts the workers and blocks until ctx is cancelled and the jobs in
+// progress have finished.
+func (q *Queue) Run(ctx context.Context) {
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < q.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		q.requeueStale(ctx)
+	}()
+
+	slog.Info("job workers started", "workers", q.cfg.Workers)
+
+	wg.Wait()
+
+	slog.Info("job workers stopped")
+}
+
+func (q *Queue) work(ctx context.Context) {
+
+	for {
+		jobs, err := q.store.ClaimJobs(ctx, 1)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("failed to claim jobs", "error", err.Error())
+		}
+
+		for _, job := range jobs {
+			q.process(job)
+		}
+
+		if len(jobs) > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(q.cfg.PollInterval):
+		}
+	}
+}
+
+func (q *Queue) process(job db.Job) {
+
+	// a claimed job is finished even when shutdown has started
+	ctx, cancel := context.WithTimeout(context.Background(), q.cfg.JobTimeout)
+	defer cancel()
+
+	var err error
+	if job.ExpiresAt.Valid && !time.Now().Before(job.ExpiresAt.Time) {
+		err = errExpired
+	} else {
+		err = q.run(ctx, job)
+	}
+
+	if err == nil {
+		err = q.store.CompleteJob(ctx, job.ID)
+		if err != nil {
+			slog.Error("failed to mark job done", "job", job.ID, "error", err.Error())
+		}
+		return
+	}
+
+	slog.Error("job failed", "job", job.ID, "kind", job.Kind, "attempt", job.Attempts, "error", err.Error())
+
+	runAt := time.Now().Add(q.backoff(int(job.Attempts)))
+
+	// a retry after the expiry would only be killed when claimed
+	expired := job.ExpiresAt.Valid && !runAt.Before(job.ExpiresAt.Time)
+
+	if job.Attempts >= job.MaxAttempts || expired || errors.Is(err, errUnknownKind) || errors.Is(err, errExpired) {
+		err = q.store.KillJob(ctx, db.KillJobParams{ID: job.ID, LastError: err.Error()})
+		if err != nil {
+			slog.Error("failed to mark job dead", "job", job.ID, "error", err.Error())
+		}
+		return
+	}
+
+	args := db.RetryJobParams{
+		ID:        job.ID,
+		RunAt:     runAt,
+		LastError: err.Error(),
+	}
+
+	err = q.store.RetryJob(ctx, args)
+	if err != nil {
+		slog.Error("failed to reschedule job", "job", job.ID, "error", err.Error())
+	}
+}
+
+func (q *Queue) run(ctx context.Context, job db.Job) (err error) {
+
+	h, ok := q.handlers[job.Kind]
+	if !ok {
+		return fmt.Errorf("%w %q", errUnknownKind, job.Kind)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return h(ctx, job.Payload)
+}
+
+func (q *Queue) requeueStale(ctx context.Context) {
+
+	ticker := time.NewTicker(q.cfg.StaleAfter)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		rows, err := q.store.RequeueStaleJobs(ctx, int32(q.cfg.StaleAfter.Seconds()))
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("failed to requeue stale jobs", "error", err.Error())
+			}
+			continue
+		}
+
+		if rows > 0 {
+			slog.Warn("requeued stale jobs", "count", rows)
+		}
+
+		if q.cfg.Retention <= 0 {
+			continue
+		}
+
+		rows, err = q.store.DeleteFinishedJobs(ctx, int32(q.cfg.Retention.Seconds()))
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("failed to delete finished jobs", "error", err.Error())
+			}
+			continue
+		}
+
+		if rows > 0 {
+			slog.Info("deleted finished jobs", "count", rows)
+		}
+	}
+}
+
+func (q *Queue) backoff(attempts int) time.Duration {
+
+	backoff := q.cfg.BaseBackoff
+	for i := 1; i < attempts && backoff < q.cfg.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, q.cfg.MaxBackoff)
+}
@@ -0,0 +1,327 @@
+package lichess
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultURL = "https://lichess.org"
+
+// MaxUsersPerRequest is the most ids lichess accepts in one /api/users call.
+const MaxUsersPerRequest = 300
+
+const userAgent = "swahilichess-api (+https://swahilichess.com)"
+
+var (
+	ErrNotFound = errors.New("lichess: not found")
+	// ErrRateLimited is returned when lichess asks to wait past the deadline of ctx.
+	ErrRateLimited = errors.New("lichess: rate limited")
+)
+
+// StatusError is returned when lichess answers with an unexpected status.
+type StatusError struct {
+	Code int
+	Path string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("lichess: %s returned %d %s", e.Path, e.Code, http.StatusText(e.Code))
+}
+
+type Perf struct {
+	Games  int  `json:"games"`
+	Rating int  `json:"rating"`
+	Rd     int  `json:"rd"`
+	Prog   int  `json:"prog"`
+	Prov   bool `json:"prov"`
+}
+
+type User struct {
+	ID           string          `json:"id"`
+	Username     string          `json:"username"`
+	Perfs        map[string]Perf `json:"perfs"`
+	Disabled     bool            `json:"disabled"`
+	TosViolation bool            `json:"tosViolation"`
+	CreatedAt    int64           `json:"createdAt"`
+	SeenAt       int64           `json:"seenAt"`
+}
+
+// PerfStats is the subset of /api/user/{username}/perf/{perf} we use.
+type PerfStats struct {
+	User struct {
+		Name string `json:"name"`
+	} `json:"user"`
+	Perf struct {
+		Glicko struct {
+			Rating      float64 `json:"rating"`
+			Deviation   float64 `json:"deviation"`
+			Provisional bool    `json:"provisional"`
+		} `json:"glicko"`
+		Games    int `json:"nb"`
+		Progress int `json:"progress"`
+	} `json:"perf"`
+	Rank       *int    `json:"rank"`
+	Percentile float64 `json:"percentile"`
+}
+
+type GamePlayer struct {
+	User struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"user"`
+	Rating     int `json:"rating"`
+	RatingDiff int `json:"ratingDiff"`
+}
+
+type Game struct {
+	ID         string `json:"id"`
+	Rated      bool   `json:"rated"`
+	Variant    string `json:"variant"`
+	Speed      string `json:"speed"`
+	Perf       string `json:"perf"`
+	CreatedAt  int64  `json:"createdAt"`
+	LastMoveAt int64  `json:"lastMoveAt"`
+	Status     string `json:"status"`
+	Winner     string `json:"winner"`
+	Players    struct {
+		White GamePlayer `json:"white"`
+		Black GamePlayer `json:"black"`
+	} `json:"players"`
+}
+
+type TeamMember struct {
+	ID           string `json:"id"`
+	Username     string `json:"username"`
+	JoinedTeamAt int64  `json:"joinedTeamAt"`
+}
+
+// GamesOptions filters the games exported by Games.
+type GamesOptions struct {
+	Max      int
+	Since    time.Time
+	PerfType string
+	Rated    bool
+}
+
+// Client talks to the lichess API. Requests answered with 429 are retried
+// after waiting, as lichess asks, a full minute or the Retry-After delay,
+// unless the wait would outlast the deadline of the request's ctx.
+type Client struct {
+	baseURL    string
+	token      string
+	http       *http.Client
+	MaxRetries int
+	RetryWait  time.Duration
+}
+
+// New returns a client for the lichess instance at baseURL, the token is
+// optional and only raises rate limits.
+func New(baseURL string, token string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		http: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		MaxRetries: 2,
+		RetryWait:  time.Minute,
+	}
+}
+
+// Users fetches the users by id, splitting the ids into batches lichess accepts.
+// Unknown ids are left out of the result.
+func (c *Client) Users(ctx context.Context, ids []string) ([]User, error) {
+
+	users := []User{}
+
+	for start := 0; start < len(ids); start += MaxUsersPerRequest {
+		end := min(start+MaxUsersPerRequest, len(ids))
+
+		var batch []User
+		err := c.do(ctx, http.MethodPost, "/api/users", nil, "text/plain", strings.Join(ids[start:end], ","), func(body io.Reader) error {
+			return json.NewDecoder(body).Decode(&batch)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, batch...)
+	}
+
+	return users, nil
+}
+
+func (c *Client) User(ctx context.Context, username string) (User, error) {
+
+	var user User
+	err := c.do(ctx, http.MethodGet, "/api/user/"+url.PathEscape(username), nil, "", "", func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&user)
+	})
+
+	return user, err
+}
+
+func (c *Client) PerfStats(ctx context.Context, username string, perf string) (PerfStats, error) {
+
+	var stats PerfStats
+	path := fmt.Sprintf("/api/user/%s/perf/%s", url.PathEscape(username), url.PathEscape(perf))
+	err := c.do(ctx, http.MethodGet, path, nil, "", "", func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&stats)
+	})
+
+	return stats, err
+}
+
+// Games exports the user's games, newest first.
+func (c *Client) Games(ctx context.Context, username string, opts GamesOptions) ([]Game, error) {
+
+	query := url.Values{}
+	if opts.Max > 0 {
+		query.Set("max", strconv.Itoa(opts.Max))
+	}
+	if !opts.Since.IsZero() {
+		query.Set("since", strconv.FormatInt(opts.Since.UnixMilli(), 10))
+	}
+	if opts.PerfType != "" {
+		query.Set("perfType", opts.PerfType)
+	}
+	if opts.Rated {
+		query.Set("rated", "true")
+	}
+
+	games := []Game{}
+	err := c.do(ctx, http.MethodGet, "/api/games/user/"+url.PathEscape(username), query, "", "", func(body io.Reader) error {
+		return decodeNDJSON(body, func(line []byte) error {
+			var game Game
+			err := json.Unmarshal(line, &game)
+			if err != nil {
+				return err
+			}
+			games = append(games, game)
+			return nil
+		})
+	})
+
+	return games, err
+}
+
+// TeamMembers streams the whole roster of the team.
+func (c *Client) TeamMembers(ctx context.Context, teamID string) ([]TeamMember, error) {
+
+	members := []TeamMember{}
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/team/%s/users", url.PathEscape(teamID)), nil, "", "", func(body io.Reader) error {
+		return decodeNDJSON(body, func(line []byte) error {
+			var member TeamMember
+			err := json.Unmarshal(line, &member)
+			if err != nil {
+				return err
+			}
+			members = append(members, member)
+			return nil
+		})
+	})
+
+	return members, err
+}
+
+// do sends the request and hands a successful response body to decode.
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, contentType string, body string, decode func(io.Reader) error) error {
+
+	for attempt := 0; ; attempt++ {
+
+		u := c.baseURL + path
+		if len(query) > 0 {
+			u += "?" + query.Encode()
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, u, strings.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("User-Agent", userAgent)
+		req.Header.Set("Accept", "application/json, application/x-ndjson")
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusOK, resp.StatusCode == http.StatusNoContent:
+			err = decode(resp.Body)
+			resp.Body.Close()
+			return err
+
+		case resp.StatusCode == http.StatusTooManyRequests && attempt < c.MaxRetries:
+			wait := retryAfter(resp.Header.Get("Retry-After"), c.RetryWait)
+			resp.Body.Close()
+
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+				return fmt.Errorf("%w, retry after %s", ErrRateLimited, wait)
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+
+		case resp.StatusCode == http.StatusNotFound:
+			resp.Body.Close()
+			return ErrNotFound
+
+		default:
+			resp.Body.Close()
+			return &StatusError{Code: resp.StatusCode, Path: path}
+		}
+	}
+}
+
+func retryAfter(header string, fallback time.Duration) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return fallback
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// decodeNDJSON calls fn with every non empty line of body.
+func decodeNDJSON(body io.Reader, fn func([]byte) error) error {
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+
+		err := fn(line)
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,198 @@
+package lichess_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"api.swahilichess.com/internal/lichess"
+	"api.swahilichess.com/internal/lichess/lichesstest"
+)
+
+func TestUsersBatches(t *testing.T) {
+
+	srv := lichesstest.NewServer()
+	defer srv.Close()
+
+	ids := make([]string, lichess.MaxUsersPerRequest+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("player%d", i)
+		srv.AddUser(lichess.User{Username: fmt.Sprintf("Player%d", i)})
+	}
+
+	// unknown ids are left out
+	ids = append(ids, "nobody")
+
+	users, err := srv.Client().Users(context.Background(), ids)
+	if err != nil {
+		t.Fatalf("Users() error = %v", err)
+	}
+
+	if len(users) != lichess.MaxUsersPerRequest+1 {
+		t.Errorf("Users() returned %d users, want %d", len(users), lichess.MaxUsersPerRequest+1)
+	}
+
+	if got := srv.Requests(); got != 2 {
+		t.Errorf("Users() sent %d requests, want 2", got)
+	}
+}
+
+func TestUsersEmpty(t *testing.T) {
+
+	srv := lichesstest.NewServer()
+	defer srv.Close()
+
+	users, err := srv.Client().Users(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Users() error = %v", err)
+	}
+
+	if len(users) != 0 || srv.Requests() != 0 {
+		t.Errorf("Users(nil) = %d users after %d requests, want none", len(users), srv.Requests())
+	}
+}
+
+func TestUserNotFound(t *testing.T) {
+
+	srv := lichesstest.NewServer()
+	defer srv.Close()
+
+	_, err := srv.Client().User(context.Background(), "nobody")
+	if !errors.Is(err, lichess.ErrNotFound) {
+		t.Fatalf("User() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRateLimitRetried(t *testing.T) {
+
+	srv := lichesstest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser(lichess.User{Username: "Juma"})
+	srv.RateLimit(2)
+
+	user, err := srv.Client().User(context.Background(), "juma")
+	if err != nil {
+		t.Fatalf("User() error = %v", err)
+	}
+
+	if user.Username != "Juma" {
+		t.Errorf("User() = %q, want Juma", user.Username)
+	}
+
+	if got := srv.Requests(); got != 3 {
+		t.Errorf("User() sent %d requests, want 3", got)
+	}
+}
+
+func TestRateLimitRetriesExhausted(t *testing.T) {
+
+	srv := lichesstest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser(lichess.User{Username: "Juma"})
+	srv.RateLimit(3)
+
+	_, err := srv.Client().User(context.Background(), "juma")
+
+	var statusErr *lichess.StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != 429 {
+		t.Fatalf("User() error = %v, want a 429 StatusError", err)
+	}
+}
+
+func TestRateLimitPastDeadline(t *testing.T) {
+
+	srv := lichesstest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser(lichess.User{Username: "Juma"})
+	srv.RateLimit(1)
+	srv.SetRetryAfter(60)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+
+	_, err := srv.Client().User(ctx, "juma")
+	if !errors.Is(err, lichess.ErrRateLimited) {
+		t.Fatalf("User() error = %v, want ErrRateLimited", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("User() returned after %s, want it to fail without waiting", elapsed)
+	}
+
+	if got := srv.Requests(); got != 1 {
+		t.Errorf("User() sent %d requests, want 1", got)
+	}
+}
+
+func TestGamesNDJSON(t *testing.T) {
+
+	srv := lichesstest.NewServer()
+	defer srv.Close()
+
+	srv.AddGames("juma",
+		lichess.Game{ID: "g3", Perf: "blitz", Rated: true},
+		lichess.Game{ID: "g2", Perf: "rapid", Rated: true},
+		lichess.Game{ID: "g1", Perf: "blitz", Rated: true},
+	)
+
+	tests := []struct {
+		name string
+		opts lichess.GamesOptions
+		want []string
+	}{
+		{name: "all", want: []string{"g3", "g2", "g1"}},
+		{name: "max", opts: lichess.GamesOptions{Max: 2}, want: []string{"g3", "g2"}},
+		{name: "perf type", opts: lichess.GamesOptions{PerfType: "blitz"}, want: []string{"g3", "g1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			games, err := srv.Client().Games(context.Background(), "Juma", tt.opts)
+			if err != nil {
+				t.Fatalf("Games() error = %v", err)
+			}
+
+			var got []string
+			for _, g := range games {
+				got = append(got, g.ID)
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Games() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTeamMembersNDJSON(t *testing.T) {
+
+	srv := lichesstest.NewServer()
+	defer srv.Close()
+
+	srv.SetTeamMembers("swahilichess",
+		lichess.TeamMember{ID: "juma", Username: "Juma", JoinedTeamAt: 1700000000000},
+		lichess.TeamMember{ID: "asha", Username: "Asha", JoinedTeamAt: 1710000000000},
+	)
+
+	members, err := srv.Client().TeamMembers(context.Background(), "swahilichess")
+	if err != nil {
+		t.Fatalf("TeamMembers() error = %v", err)
+	}
+
+	if len(members) != 2 || members[0].ID != "juma" || members[1].JoinedTeamAt != 1710000000000 {
+		t.Errorf("TeamMembers() = %+v", members)
+	}
+
+	_, err = srv.Client().TeamMembers(context.Background(), "unknown")
+	if !errors.Is(err, lichess.ErrNotFound) {
+		t.Errorf("TeamMembers(unknown) error = %v, want ErrNotFound", err)
+	}
+}
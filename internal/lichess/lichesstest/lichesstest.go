@@ -0,0 +1,376 @@
+// Package lichesstest runs a fake lichess API on an httptest server so code
+// using the lichess client can run without network access.
+package lichesstest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"api.swahilichess.com/internal/lichess"
+)
+
+type authCode struct {
+	username    string
+	challenge   string
+	clientID    string
+	redirectURI string
+}
+
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	users       map[string]lichess.User
+	perfs       map[string]lichess.PerfStats
+	games       map[string][]lichess.Game
+	teams       map[string][]lichess.TeamMember
+	authorizing string
+	codes       map[string]authCode
+	tokens      map[string]string
+	rateLimited int
+	retryAfter  int
+	requests    int
+}
+
+// NewServer starts the fake, close it with Close.
+func NewServer() *Server {
+
+	s := &Server{
+		users:  make(map[string]lichess.User),
+		perfs:  make(map[string]lichess.PerfStats),
+		games:  make(map[string][]lichess.Game),
+		teams:  make(map[string][]lichess.TeamMember),
+		codes:  make(map[string]authCode),
+		tokens: make(map[string]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/users", s.handleUsers)
+	mux.HandleFunc("GET /api/user/{username}", s.handleUser)
+	mux.HandleFunc("GET /api/user/{username}/perf/{perf}", s.handlePerf)
+	mux.HandleFunc("GET /api/games/user/{username}", s.handleGames)
+	mux.HandleFunc("GET /api/team/{team}/users", s.handleTeamMembers)
+	mux.HandleFunc("GET /oauth", s.handleAuthorize)
+	mux.HandleFunc("POST /api/token", s.handleToken)
+	mux.HandleFunc("DELETE /api/token", s.handleRevoke)
+	mux.HandleFunc("GET /api/account", s.handleAccount)
+
+	s.Server = httptest.NewServer(s.limit(mux))
+
+	return s
+}
+
+// Client returns a lichess client pointed at the fake that doesn't wait
+// before retrying rate limited requests.
+func (s *Server) Client() *lichess.Client {
+	c := lichess.New(s.URL, "")
+	c.RetryWait = 0
+	return c
+}
+
+// AddUser adds or replaces a user, the id defaults to the lowercase username.
+func (s *Server) AddUser(user lichess.User) {
+	if user.ID == "" {
+		user.ID = strings.ToLower(user.Username)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[user.ID] = user
+}
+
+func (s *Server) AddPerfStats(username string, perf string, stats lichess.PerfStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.perfs[strings.ToLower(username)+"/"+perf] = stats
+}
+
+// AddGames appends games to the user's export, newest first.
+func (s *Server) AddGames(username string, games ...lichess.Game) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := strings.ToLower(username)
+	s.games[id] = append(s.games[id], games...)
+}
+
+// SetTeamMembers replaces the roster of the team.
+func (s *Server) SetTeamMembers(teamID string, members ...lichess.TeamMember) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.teams[teamID] = members
+}
+
+// Authorize makes the OAuth page grant access as username, until then
+// every authorization is denied.
+func (s *Server) Authorize(username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authorizing = username
+}
+
+// Tokens returns how many access tokens are still valid.
+func (s *Server) Tokens() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tokens)
+}
+
+// RateLimit answers the next n requests with 429.
+func (s *Server) RateLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimited = n
+}
+
+// SetRetryAfter sets the Retry-After seconds sent with 429 answers, 0 by default.
+func (s *Server) SetRetryAfter(seconds int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retryAfter = seconds
+}
+
+// Requests returns how many requests the fake received, rate limited ones included.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func (s *Server) limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		limited := s.rateLimited > 0
+		if limited {
+			s.rateLimited--
+		}
+		retryAfter := s.retryAfter
+		s.mu.Unlock()
+
+		if limited {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ids := strings.Split(string(body), ",")
+	if len(ids) > lichess.MaxUsersPerRequest {
+		http.Error(w, "too many ids", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	users := []lichess.User{}
+	for _, id := range ids {
+		if user, ok := s.users[strings.ToLower(strings.TrimSpace(id))]; ok {
+			users = append(users, user)
+		}
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, users)
+}
+
+func (s *Server) handleUser(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	user, ok := s.users[strings.ToLower(r.PathValue("username"))]
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	writeJSON(w, user)
+}
+
+func (s *Server) handlePerf(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	stats, ok := s.perfs[strings.ToLower(r.PathValue("username"))+"/"+r.PathValue("perf")]
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	writeJSON(w, stats)
+}
+
+func (s *Server) handleGames(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	games := s.games[strings.ToLower(r.PathValue("username"))]
+	s.mu.Unlock()
+
+	max, err := strconv.Atoi(r.URL.Query().Get("max"))
+	if err != nil || max <= 0 {
+		max = len(games)
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+
+	enc := json.NewEncoder(w)
+	for _, game := range games {
+		if max == 0 {
+			break
+		}
+		if perf := r.URL.Query().Get("perfType"); perf != "" && game.Perf != perf {
+			continue
+		}
+		enc.Encode(game)
+		max--
+	}
+}
+
+func (s *Server) handleTeamMembers(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	members, ok := s.teams[r.PathValue("team")]
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+
+	enc := json.NewEncoder(w)
+	for _, member := range members {
+		enc.Encode(member)
+	}
+}
+
+// handleAuthorize stands in for the page where the user approves the
+// request, it redirects straight back with a code or access_denied.
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	if query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	params := url.Values{}
+	params.Set("state", query.Get("state"))
+
+	s.mu.Lock()
+	if s.authorizing == "" {
+		params.Set("error", "access_denied")
+	} else {
+		code := randomString()
+		s.codes[code] = authCode{
+			username:    s.authorizing,
+			challenge:   query.Get("code_challenge"),
+			clientID:    query.Get("client_id"),
+			redirectURI: redirect.String(),
+		}
+		params.Set("code", code)
+	}
+	s.mu.Unlock()
+
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+		code.clientID != r.PostForm.Get("client_id") ||
+		code.redirectURI != r.PostForm.Get("redirect_uri") ||
+		code.challenge != lichess.Challenge(r.PostForm.Get("code_verifier")) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	token := randomString()
+	s.tokens[token] = code.username
+
+	writeJSON(w, map[string]any{
+		"token_type":   "Bearer",
+		"access_token": token,
+		"expires_in":   31536000,
+	})
+}
+
+func (s *Server) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	delete(s.tokens, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleAccount(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	username, ok := s.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	user, known := s.users[strings.ToLower(username)]
+	s.mu.Unlock()
+
+	if !ok {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	if !known {
+		user = lichess.User{ID: strings.ToLower(username), Username: username}
+	}
+
+	writeJSON(w, user)
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,105 @@
+package lichess
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ErrInvalidGrant is returned when lichess refuses an authorization code,
+// it was already used, expired or the verifier doesn't match.
+var ErrInvalidGrant = errors.New("lichess: invalid authorization code")
+
+// OAuthConfig identifies this application to lichess. Lichess doesn't
+// require registering clients, any stable ClientID works.
+type OAuthConfig struct {
+	ClientID    string
+	RedirectURL string
+}
+
+// NewVerifier returns a random PKCE code verifier.
+func NewVerifier() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Challenge is the S256 code challenge of the verifier.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL is the page where the user grants access, lichess then
+// redirects to the RedirectURL with the code and the state.
+func (c *Client) AuthCodeURL(cfg OAuthConfig, state string, verifier string) string {
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", cfg.ClientID)
+	query.Set("redirect_uri", cfg.RedirectURL)
+	query.Set("state", state)
+	query.Set("code_challenge_method", "S256")
+	query.Set("code_challenge", Challenge(verifier))
+
+	return c.baseURL + "/oauth?" + query.Encode()
+}
+
+// Exchange trades an authorization code for an access token.
+func (c *Client) Exchange(ctx context.Context, cfg OAuthConfig, code string, verifier string) (string, error) {
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("code_verifier", verifier)
+	form.Set("redirect_uri", cfg.RedirectURL)
+	form.Set("client_id", cfg.ClientID)
+
+	var res struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	err := c.do(ctx, http.MethodPost, "/api/token", nil, "application/x-www-form-urlencoded", form.Encode(), func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&res)
+	})
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusBadRequest {
+		return "", ErrInvalidGrant
+	}
+
+	return res.AccessToken, err
+}
+
+// Account returns the user the access token belongs to.
+func (c *Client) Account(ctx context.Context, accessToken string) (User, error) {
+
+	var user User
+	err := c.withToken(accessToken).do(ctx, http.MethodGet, "/api/account", nil, "", "", func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&user)
+	})
+
+	return user, err
+}
+
+// RevokeToken invalidates the access token.
+func (c *Client) RevokeToken(ctx context.Context, accessToken string) error {
+	return c.withToken(accessToken).do(ctx, http.MethodDelete, "/api/token", nil, "", "", func(io.Reader) error {
+		return nil
+	})
+}
+
+func (c *Client) withToken(token string) *Client {
+	clone := *c
+	clone.token = token
+	return &clone
+}
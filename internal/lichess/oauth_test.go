@@ -0,0 +1,157 @@
+package lichess_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"api.swahilichess.com/internal/lichess"
+	"api.swahilichess.com/internal/lichess/lichesstest"
+)
+
+var oauthConfig = lichess.OAuthConfig{
+	ClientID:    "swahilichess-test",
+	RedirectURL: "https://api.example.com/v1/auth/lichess/callback",
+}
+
+func TestChallenge(t *testing.T) {
+
+	// the example of RFC 7636 appendix B
+	got := lichess.Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("Challenge() = %q, want %q", got, want)
+	}
+}
+
+func TestNewVerifier(t *testing.T) {
+
+	a, err := lichess.NewVerifier()
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+
+	b, err := lichess.NewVerifier()
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+
+	// RFC 7636 wants 43 to 128 characters
+	if len(a) < 43 || len(a) > 128 {
+		t.Errorf("NewVerifier() length = %d, want between 43 and 128", len(a))
+	}
+
+	if a == b {
+		t.Errorf("NewVerifier() returned %q twice", a)
+	}
+}
+
+// authorize opens the auth url on the fake and returns the query lichess
+// redirects back with.
+func authorize(t *testing.T, authURL string) url.Values {
+
+	t.Helper()
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("GET %s error = %v", authURL, err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("GET %s status = %d, want 302", authURL, resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect %q: %v", resp.Header.Get("Location"), err)
+	}
+
+	return location.Query()
+}
+
+func TestOAuthFlow(t *testing.T) {
+
+	srv := lichesstest.NewServer()
+	defer srv.Close()
+
+	srv.AddUser(lichess.User{Username: "Juma"})
+	srv.Authorize("Juma")
+
+	client := srv.Client()
+
+	verifier, err := lichess.NewVerifier()
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+
+	query := authorize(t, client.AuthCodeURL(oauthConfig, "the-state", verifier))
+	if query.Get("state") != "the-state" {
+		t.Fatalf("state = %q, want the-state", query.Get("state"))
+	}
+
+	token, err := client.Exchange(context.Background(), oauthConfig, query.Get("code"), verifier)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+
+	account, err := client.Account(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Account() error = %v", err)
+	}
+
+	if account.ID != "juma" || account.Username != "Juma" {
+		t.Errorf("Account() = %q %q, want juma Juma", account.ID, account.Username)
+	}
+
+	err = client.RevokeToken(context.Background(), token)
+	if err != nil {
+		t.Fatalf("RevokeToken() error = %v", err)
+	}
+
+	if srv.Tokens() != 0 {
+		t.Errorf("%d tokens left after RevokeToken()", srv.Tokens())
+	}
+
+	// codes are single use
+	_, err = client.Exchange(context.Background(), oauthConfig, query.Get("code"), verifier)
+	if !errors.Is(err, lichess.ErrInvalidGrant) {
+		t.Errorf("second Exchange() error = %v, want ErrInvalidGrant", err)
+	}
+}
+
+func TestOAuthExchangeWrongVerifier(t *testing.T) {
+
+	srv := lichesstest.NewServer()
+	defer srv.Close()
+
+	srv.Authorize("Juma")
+
+	client := srv.Client()
+
+	query := authorize(t, client.AuthCodeURL(oauthConfig, "state", "the-verifier-sent-as-challenge-0123456789ab"))
+
+	_, err := client.Exchange(context.Background(), oauthConfig, query.Get("code"), "another-verifier-0123456789abcdefghijklmnopq")
+	if !errors.Is(err, lichess.ErrInvalidGrant) {
+		t.Fatalf("Exchange() error = %v, want ErrInvalidGrant", err)
+	}
+}
+
+func TestOAuthDenied(t *testing.T) {
+
+	srv := lichesstest.NewServer()
+	defer srv.Close()
+
+	query := authorize(t, srv.Client().AuthCodeURL(oauthConfig, "state", "verifier"))
+
+	if query.Get("error") != "access_denied" || query.Get("code") != "" {
+		t.Errorf("redirect query = %v, want access_denied without a code", query)
+	}
+}
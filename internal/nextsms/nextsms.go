@@ -9,26 +9,32 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/google/uuid"
+	"api.swahilichess.com/internal/sms"
 )
 
-const next_url = "https://messaging-service.co.tz/api/sms/v1/text/single"
+const DefaultURL = "https://messaging-service.co.tz"
+
+const singleTextPath = "/api/sms/v1/text/single"
 
 const sourceAddr = "Chess"
 
 type NextSmS struct {
 	Username string
 	Password string
+	Url      string
+	client   *http.Client
 }
 
 type Message struct {
-	To        string `json:"to"`
-	Status    Status `json:"status"`
-	MessageID int64  `json:"messageId"`
-	SMSCount  int    `json:"smsCount"`
-	Message   string `json:"message"`
+	To        string      `json:"to"`
+	Status    Status      `json:"status"`
+	MessageID json.Number `json:"messageId"`
+	SMSCount  int         `json:"smsCount"`
+	Message   string      `json:"message"`
+	Price     *Price      `json:"price,omitempty"`
 }
 
 type Status struct {
@@ -39,19 +45,38 @@ type Status struct {
 	Description string `json:"description"`
 }
 
+type Price struct {
+	PricePerMessage float64 `json:"pricePerMessage"`
+	Currency        string  `json:"currency"`
+}
+
 // Define a struct to encompass the entire JSON body
 type Payload struct {
 	Messages []Message `json:"messages"`
 }
 
-func New(username string, password string) NextSmS {
+var _ sms.Sender = NextSmS{}
+
+func New(username string, password string, url string) NextSmS {
+	if url == "" {
+		url = DefaultURL
+	}
+
 	return NextSmS{
 		Username: username,
 		Password: password,
+		Url:      strings.TrimSuffix(url, "/"),
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
 	}
 }
 
-func (n NextSmS) SendSmS(msg string, recipient_phone string) error {
+func (n NextSmS) Name() string {
+	return "nextsms"
+}
+
+func (n NextSmS) Send(ctx context.Context, msg sms.Message) (sms.Result, error) {
 
 	payload := struct {
 		From      string `json:"from"`
@@ -60,43 +85,38 @@ func (n NextSmS) SendSmS(msg string, recipient_phone string) error {
 		Reference string `json:"reference"`
 	}{
 		From:      sourceAddr,
-		To:        recipient_phone[1:], // exclude the +
-		Text:      msg,
-		Reference: uuid.NewString(),
+		To:        strings.TrimPrefix(msg.To, "+"),
+		Text:      msg.Text,
+		Reference: msg.Reference,
 	}
 
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
 		slog.Error("failed encoding JSON payload", "error", err)
-		return err
+		return sms.Result{}, err
 	}
 
 	encoded := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", n.Username, n.Password)))
-	req, err := http.NewRequest("POST", next_url, bytes.NewBuffer(jsonPayload))
+	req, err := http.NewRequestWithContext(ctx, "POST", n.Url+singleTextPath, bytes.NewBuffer(jsonPayload))
 	if err != nil {
 		slog.Error("failed creating HTTP request to send to nextsms", "error", err)
-		return err
+		return sms.Result{}, err
 	}
 
 	req.Header.Add("Authorization", fmt.Sprintf("Basic %s", encoded))
-	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Accept", "application/json")
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	res, err := client.Do(req)
+	res, err := n.client.Do(req)
 	if err != nil {
 		slog.Error("failed sending request to nextsms", "error", err)
-		return err
+		return sms.Result{}, err
 	}
 
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return errors.New("nextsms failed to send sms")
+		return sms.Result{}, errors.New("nextsms failed to send sms")
 	}
 
 	var p Payload
@@ -104,15 +124,36 @@ func (n NextSmS) SendSmS(msg string, recipient_phone string) error {
 	err = json.NewDecoder(res.Body).Decode(&p)
 	if err != nil {
 		slog.Error(fmt.Sprintf("Error decoding JSON response: %s", err))
-		return err
+		return sms.Result{}, err
 	}
 
-	slog.LogAttrs(context.Background(),
+	slog.LogAttrs(ctx,
 		slog.LevelInfo,
 		"Success response from NextSmS",
 		slog.Int("http-status", res.StatusCode),
 		slog.String("body", fmt.Sprintf("%+v", p)),
 	)
 
-	return nil
+	if len(p.Messages) == 0 {
+		return sms.Result{}, errors.New("nextsms response has no messages")
+	}
+
+	return p.Messages[0].Result(), nil
+}
+
+// Result converts a message from a send response or delivery report.
+func (m Message) Result() sms.Result {
+
+	res := sms.Result{
+		MessageID:   m.MessageID.String(),
+		Status:      m.Status.GroupName,
+		Description: m.Status.Description,
+		SMSCount:    m.SMSCount,
+	}
+
+	if m.Price != nil {
+		res.Cost = m.Price.PricePerMessage * float64(m.SMSCount)
+	}
+
+	return res
 }
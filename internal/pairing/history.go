@@ -0,0 +1,103 @@
+package pairing
+
+import "sort"
+
+// Entrant is a player still taking part in the tournament.
+type Entrant struct {
+	ID     int64
+	Rating int
+}
+
+// Game is a game of a previous round. Black is 0 for a bye. Forfeited games
+// score but the players aren't considered to have met. Bye is set for the
+// bye given by the pairing, a requested half or zero point bye doesn't keep
+// the player from getting it later.
+type Game struct {
+	Round       int
+	White       int64
+	Black       int64
+	WhitePoints float64
+	BlackPoints float64
+	Forfeit     bool
+	Bye         bool
+}
+
+// Players builds the entrants' state from the games played so far. Games of
+// players who left the tournament still count for their opponents.
+func Players(entrants []Entrant, games []Game) []Player {
+
+	sorted := append([]Game(nil), games...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Round < sorted[j].Round
+	})
+
+	lastRound := 0
+	for _, g := range sorted {
+		lastRound = max(lastRound, g.Round)
+	}
+
+	scores := make(map[int64]float64)
+	before := make(map[int64]float64)
+	players := make(map[int64]*Player, len(entrants))
+
+	for _, e := range entrants {
+		players[e.ID] = &Player{ID: e.ID, Rating: e.Rating}
+	}
+
+	round := 0
+	for _, g := range sorted {
+
+		if g.Round != round {
+			round = g.Round
+			for id, score := range scores {
+				before[id] = score
+			}
+		}
+
+		scores[g.White] += g.WhitePoints
+		if g.Black != 0 {
+			scores[g.Black] += g.BlackPoints
+		}
+
+		white, black := players[g.White], players[g.Black]
+
+		if g.Black == 0 {
+			if white != nil && g.Bye {
+				white.HadBye = true
+				white.FloatedDown = white.FloatedDown || g.Round == lastRound
+			}
+			continue
+		}
+
+		if g.Round == lastRound {
+			if white != nil && before[g.White] > before[g.Black] {
+				white.FloatedDown = true
+			}
+			if black != nil && before[g.Black] > before[g.White] {
+				black.FloatedDown = true
+			}
+		}
+
+		if g.Forfeit {
+			continue
+		}
+
+		if white != nil {
+			white.Opponents = append(white.Opponents, g.Black)
+			white.Colors = append(white.Colors, White)
+		}
+		if black != nil {
+			black.Opponents = append(black.Opponents, g.White)
+			black.Colors = append(black.Colors, Black)
+		}
+	}
+
+	result := make([]Player, len(entrants))
+	for i, e := range entrants {
+		p := players[e.ID]
+		p.Score = scores[e.ID]
+		result[i] = *p
+	}
+
+	return result
+}
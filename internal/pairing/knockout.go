@@ -0,0 +1,131 @@
+package pairing
+
+import "errors"
+
+var ErrMatchUndecided = errors.New("pairing: match is undecided")
+
+// MatchGame is a game of a knockout match. In an armageddon game a draw
+// sends black through.
+type MatchGame struct {
+	White       int64
+	Black       int64
+	WhitePoints float64
+	BlackPoints float64
+	Armageddon  bool
+}
+
+// Knockout pairs the first round of a knockout, ids are the players in seed
+// order. The bracket is filled up to a power of two so the top seeds get the
+// byes, a bye has Black set to 0. Seeds meet as in 1-8, 4-5, 2-7, 3-6 so the
+// top two can only meet in the final, the higher seed has white on odd
+// boards.
+func Knockout(ids []int64) ([]Pairing, error) {
+
+	if len(ids) < 2 {
+		return nil, ErrTooFewPlayers
+	}
+
+	slots := bracketSlots(len(ids))
+	pairings := make([]Pairing, 0, len(slots)/2)
+
+	for i := 0; i < len(slots); i += 2 {
+		higher, lower := ids[slots[i]-1], int64(0)
+		if slots[i+1] <= len(ids) {
+			lower = ids[slots[i+1]-1]
+		}
+
+		if lower == 0 || (i/2)%2 == 0 {
+			pairings = append(pairings, Pairing{White: higher, Black: lower})
+		} else {
+			pairings = append(pairings, Pairing{White: lower, Black: higher})
+		}
+	}
+
+	return pairings, nil
+}
+
+// KnockoutRounds is the number of rounds of a knockout of n players.
+func KnockoutRounds(n int) int {
+	rounds := 0
+	for size := 1; size < n; size *= 2 {
+		rounds++
+	}
+	return rounds
+}
+
+// NextKnockoutRound pairs the winners of the previous round's matches, given
+// in board order, so neighbouring matches meet. The winner coming from the
+// upper match has white on odd boards.
+func NextKnockoutRound(winners []int64) ([]Pairing, error) {
+
+	if len(winners) < 2 || len(winners)%2 == 1 {
+		return nil, ErrTooFewPlayers
+	}
+
+	pairings := make([]Pairing, 0, len(winners)/2)
+
+	for i := 0; i < len(winners); i += 2 {
+		if (i/2)%2 == 0 {
+			pairings = append(pairings, Pairing{White: winners[i], Black: winners[i+1]})
+		} else {
+			pairings = append(pairings, Pairing{White: winners[i+1], Black: winners[i]})
+		}
+	}
+
+	return pairings, nil
+}
+
+// MatchWinner decides a match from its reported games. A bye, a game without
+// Black, wins outright and an armageddon game settles the match, otherwise
+// the player with more points goes through. Tied matches need another game.
+func MatchWinner(games []MatchGame) (int64, error) {
+
+	if len(games) == 0 {
+		return 0, ErrMatchUndecided
+	}
+
+	if games[0].Black == 0 {
+		return games[0].White, nil
+	}
+
+	points := make(map[int64]float64, 2)
+	a, b := games[0].White, games[0].Black
+
+	for _, g := range games {
+		if g.Armageddon {
+			if g.WhitePoints > g.BlackPoints {
+				return g.White, nil
+			}
+			return g.Black, nil
+		}
+		points[g.White] += g.WhitePoints
+		points[g.Black] += g.BlackPoints
+	}
+
+	switch {
+	case points[a] > points[b]:
+		return a, nil
+	case points[b] > points[a]:
+		return b, nil
+	default:
+		return 0, ErrMatchUndecided
+	}
+}
+
+// bracketSlots lists the seeds by bracket position for the smallest power of
+// two holding n players, seeds above n are byes.
+func bracketSlots(n int) []int {
+
+	slots := []int{1}
+
+	for len(slots) < n {
+		size := len(slots) * 2
+		next := make([]int, 0, size)
+		for _, seed := range slots {
+			next = append(next, seed, size+1-seed)
+		}
+		slots = next
+	}
+
+	return slots
+}
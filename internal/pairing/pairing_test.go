@@ -0,0 +1,452 @@
+package pairing
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// field returns n entrants rated 2000, 1990, ... so ids match pairing numbers.
+func field(n int) []Entrant {
+	entrants := make([]Entrant, n)
+	for i := range entrants {
+		entrants[i] = Entrant{ID: int64(i + 1), Rating: 2000 - 10*i}
+	}
+	return entrants
+}
+
+func win(round int, white, black int64) Game {
+	return Game{Round: round, White: white, Black: black, WhitePoints: 1}
+}
+
+func loss(round int, white, black int64) Game {
+	return Game{Round: round, White: white, Black: black, BlackPoints: 1}
+}
+
+func draw(round int, white, black int64) Game {
+	return Game{Round: round, White: white, Black: black, WhitePoints: 0.5, BlackPoints: 0.5}
+}
+
+func bye(round int, id int64) Game {
+	return Game{Round: round, White: id, WhitePoints: 1, Bye: true}
+}
+
+func halfBye(round int, id int64) Game {
+	return Game{Round: round, White: id, WhitePoints: 0.5}
+}
+
+func pairings(ids ...int64) []Pairing {
+	list := make([]Pairing, 0, len(ids)/2)
+	for i := 0; i+1 < len(ids); i += 2 {
+		list = append(list, Pairing{White: ids[i], Black: ids[i+1]})
+	}
+	return list
+}
+
+func TestSwiss(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		entrants []Entrant
+		games    []Game
+		want     Round
+	}{
+		{
+			name:     "first round, top half meets bottom half",
+			entrants: field(8),
+			want:     Round{Pairings: pairings(1, 5, 6, 2, 3, 7, 8, 4)},
+		},
+		{
+			name:     "first round, odd field",
+			entrants: field(5),
+			want:     Round{Pairings: pairings(1, 3, 4, 2), Bye: 5},
+		},
+		{
+			name:     "winners meet and colors alternate",
+			entrants: field(4),
+			games:    []Game{win(1, 1, 3), loss(1, 4, 2)},
+			want:     Round{Pairings: pairings(2, 1, 3, 4)},
+		},
+		{
+			name:     "no repeat opponents, equal preferences favour the higher ranked",
+			entrants: field(4),
+			games:    []Game{draw(1, 1, 2), draw(1, 3, 4)},
+			want:     Round{Pairings: pairings(3, 1, 2, 4)},
+		},
+		{
+			name:     "the lowest player floats and meets the top of the next group",
+			entrants: field(6),
+			games:    []Game{win(1, 1, 4), loss(1, 5, 2), win(1, 3, 6)},
+			want:     Round{Pairings: pairings(2, 1, 4, 3, 6, 5)},
+		},
+		{
+			name:     "bye to the lowest without one, last round's bye doesn't float again",
+			entrants: field(5),
+			games:    []Game{win(1, 1, 3), loss(1, 4, 2), bye(1, 5)},
+			want:     Round{Pairings: pairings(5, 1, 2, 3), Bye: 4},
+		},
+		{
+			name:     "the whole group floats when it can't be paired",
+			entrants: field(4),
+			games:    []Game{draw(1, 1, 2), draw(1, 3, 4), loss(2, 3, 1), win(2, 2, 4)},
+			want:     Round{Pairings: pairings(4, 1, 2, 3)},
+		},
+		{
+			name:     "players who left aren't paired",
+			entrants: field(4)[:3],
+			games:    []Game{win(1, 1, 3), loss(1, 4, 2), loss(2, 3, 2), win(2, 1, 4)},
+			want:     Round{Pairings: pairings(2, 1), Bye: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Swiss(Players(tt.entrants, tt.games))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSwissAbsoluteColors(t *testing.T) {
+
+	// 1 and 3 must both get black, so 1 meets 4 instead of 3
+	players := []Player{
+		{ID: 1, Rating: 2000, Score: 1, Colors: []Color{White, White}},
+		{ID: 2, Rating: 1990, Score: 1, Colors: []Color{Black, Black}},
+		{ID: 3, Rating: 1980, Score: 1, Colors: []Color{White, White}},
+		{ID: 4, Rating: 1970, Score: 1, Colors: []Color{Black, Black}},
+	}
+
+	got, err := Swiss(players)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Round{Pairings: pairings(4, 1, 2, 3)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestSwissInputOrder(t *testing.T) {
+
+	entrants := field(7)
+	games := []Game{win(1, 1, 4), draw(1, 5, 2), loss(1, 3, 6), bye(1, 7)}
+	want, err := Swiss(Players(entrants, games))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for shift := 1; shift < len(entrants); shift++ {
+		rotated := append(append([]Entrant{}, entrants[shift:]...), entrants[:shift]...)
+		got, err := Swiss(Players(rotated, games))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("shift %d: got %+v, want %+v", shift, got, want)
+		}
+	}
+}
+
+func TestSwissErrors(t *testing.T) {
+
+	_, err := Swiss([]Player{{ID: 1}})
+	if !errors.Is(err, ErrTooFewPlayers) {
+		t.Errorf("one player: got %v, want ErrTooFewPlayers", err)
+	}
+
+	_, err = Swiss(Players(field(2), []Game{draw(1, 1, 2)}))
+	if !errors.Is(err, ErrNoPairing) {
+		t.Errorf("players who met: got %v, want ErrNoPairing", err)
+	}
+
+	_, err = Swiss([]Player{{ID: 1}, {ID: 1}})
+	if err == nil {
+		t.Error("duplicate ids: got no error")
+	}
+}
+
+// TestSwissTournament plays a whole event and checks the rules hold in every
+// round. The higher rated player wins, every third board is drawn.
+func TestSwissTournament(t *testing.T) {
+
+	entrants := field(15)
+	var games []Game
+
+	for round := 1; round <= 9; round++ {
+
+		players := Players(entrants, games)
+		got, err := Swiss(players)
+		if err != nil {
+			t.Fatalf("round %d: %v", round, err)
+		}
+
+		state := make(map[int64]Player, len(players))
+		for _, p := range players {
+			state[p.ID] = p
+		}
+
+		seen := make(map[int64]bool)
+		for board, p := range got.Pairings {
+			for _, id := range []int64{p.White, p.Black} {
+				if seen[id] {
+					t.Fatalf("round %d: player %d paired twice", round, id)
+				}
+				seen[id] = true
+			}
+
+			for _, id := range state[p.White].Opponents {
+				if id == p.Black {
+					t.Fatalf("round %d: %d and %d meet again", round, p.White, p.Black)
+				}
+			}
+
+			switch {
+			case (board+round)%3 == 0:
+				games = append(games, draw(round, p.White, p.Black))
+			case p.White < p.Black:
+				games = append(games, win(round, p.White, p.Black))
+			default:
+				games = append(games, loss(round, p.White, p.Black))
+			}
+		}
+
+		if got.Bye == 0 || seen[got.Bye] {
+			t.Fatalf("round %d: bye %d", round, got.Bye)
+		}
+		if state[got.Bye].HadBye {
+			t.Fatalf("round %d: second bye for %d", round, got.Bye)
+		}
+		games = append(games, bye(round, got.Bye))
+	}
+
+	for _, p := range Players(entrants, games) {
+		diff := 0
+		for i, c := range p.Colors {
+			diff += int(c)
+			if i >= 2 && c == p.Colors[i-1] && c == p.Colors[i-2] {
+				t.Errorf("player %d got %s three times in a row", p.ID, c)
+			}
+		}
+		if abs(diff) > 2 {
+			t.Errorf("player %d has a color difference of %d", p.ID, diff)
+		}
+	}
+}
+
+func TestPlayers(t *testing.T) {
+
+	games := []Game{
+		win(1, 1, 3),
+		loss(1, 4, 2),
+		{Round: 2, White: 2, Black: 1, BlackPoints: 1, Forfeit: true},
+		win(2, 3, 4),
+	}
+
+	got := Players(field(4), games)
+
+	want := []Player{
+		{ID: 1, Rating: 2000, Score: 2, Opponents: []int64{3}, Colors: []Color{White}},
+		{ID: 2, Rating: 1990, Score: 1, Opponents: []int64{4}, Colors: []Color{Black}},
+		{ID: 3, Rating: 1980, Score: 1, Opponents: []int64{1, 4}, Colors: []Color{Black, White}},
+		{ID: 4, Rating: 1970, Score: 0, Opponents: []int64{2, 3}, Colors: []Color{White, Black}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestPlayersByes(t *testing.T) {
+
+	games := []Game{
+		win(1, 1, 2),
+		bye(1, 3),
+		{Round: 1, White: 4},
+		halfBye(2, 1),
+		draw(2, 3, 4),
+	}
+
+	// only the pairing bye counts, the requested half and zero point byes
+	// neither use it up nor float the player down
+	want := map[int64][2]bool{1: {false, false}, 2: {false, false}, 3: {true, true}, 4: {false, false}}
+
+	for _, p := range Players(field(4), games) {
+		if got := [2]bool{p.HadBye, p.FloatedDown}; got != want[p.ID] {
+			t.Errorf("player %d had bye and floated down %v, want %v", p.ID, got, want[p.ID])
+		}
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+
+	tests := []struct {
+		name string
+		ids  []int64
+		want []Round
+	}{
+		{
+			name: "berger table of six",
+			ids:  []int64{1, 2, 3, 4, 5, 6},
+			want: []Round{
+				{Pairings: pairings(1, 6, 2, 5, 3, 4)},
+				{Pairings: pairings(6, 4, 5, 3, 1, 2)},
+				{Pairings: pairings(2, 6, 3, 1, 4, 5)},
+				{Pairings: pairings(6, 5, 1, 4, 2, 3)},
+				{Pairings: pairings(3, 6, 4, 2, 5, 1)},
+			},
+		},
+		{
+			name: "odd field, the missing seed is the bye",
+			ids:  []int64{1, 2, 3},
+			want: []Round{
+				{Pairings: pairings(2, 3), Bye: 1},
+				{Pairings: pairings(1, 2), Bye: 3},
+				{Pairings: pairings(3, 1), Bye: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RoundRobin(tt.ids, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoubleRoundRobin(t *testing.T) {
+
+	ids := []int64{10, 20, 30, 40, 50, 60, 70}
+
+	rounds, err := RoundRobin(ids, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rounds) != 14 {
+		t.Fatalf("got %d rounds, want 14", len(rounds))
+	}
+
+	games := make(map[Pairing]int)
+	byes := make(map[int64]int)
+	for _, r := range rounds {
+		byes[r.Bye]++
+		for _, p := range r.Pairings {
+			games[p]++
+		}
+	}
+
+	for _, a := range ids {
+		if byes[a] != 2 {
+			t.Errorf("player %d got %d byes, want 2", a, byes[a])
+		}
+		for _, b := range ids {
+			if a != b && games[Pairing{White: a, Black: b}] != 1 {
+				t.Errorf("%d had white against %d %d times", a, b, games[Pairing{White: a, Black: b}])
+			}
+		}
+	}
+}
+
+func TestKnockout(t *testing.T) {
+
+	got, err := Knockout([]int64{1, 2, 3, 4, 5, 6, 7, 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := pairings(1, 8, 5, 4, 2, 7, 6, 3); !reflect.DeepEqual(got, want) {
+		t.Errorf("eight players: got %+v, want %+v", got, want)
+	}
+
+	// seeds 6 to 8 are missing, the top three get byes
+	got, err = Knockout([]int64{1, 2, 3, 4, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := pairings(1, 0, 5, 4, 2, 0, 3, 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("five players: got %+v, want %+v", got, want)
+	}
+
+	if KnockoutRounds(5) != 3 || KnockoutRounds(8) != 3 || KnockoutRounds(2) != 1 {
+		t.Error("wrong number of knockout rounds")
+	}
+
+	got, err = NextKnockoutRound([]int64{1, 5, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := pairings(1, 5, 3, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("next round: got %+v, want %+v", got, want)
+	}
+
+	if _, err := NextKnockoutRound([]int64{1}); !errors.Is(err, ErrTooFewPlayers) {
+		t.Errorf("after the final: got %v, want ErrTooFewPlayers", err)
+	}
+}
+
+func TestMatchWinner(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		games []MatchGame
+		want  int64
+		err   error
+	}{
+		{
+			name:  "bye",
+			games: []MatchGame{{White: 1}},
+			want:  1,
+		},
+		{
+			name:  "decisive game",
+			games: []MatchGame{{White: 1, Black: 2, BlackPoints: 1}},
+			want:  2,
+		},
+		{
+			name:  "drawn game needs a tiebreak",
+			games: []MatchGame{{White: 1, Black: 2, WhitePoints: 0.5, BlackPoints: 0.5}},
+			err:   ErrMatchUndecided,
+		},
+		{
+			name: "tiebreak game decides",
+			games: []MatchGame{
+				{White: 1, Black: 2, WhitePoints: 0.5, BlackPoints: 0.5},
+				{White: 2, Black: 1, WhitePoints: 1},
+			},
+			want: 2,
+		},
+		{
+			name: "armageddon draw goes to black",
+			games: []MatchGame{
+				{White: 1, Black: 2, WhitePoints: 0.5, BlackPoints: 0.5},
+				{White: 2, Black: 1, WhitePoints: 0.5, BlackPoints: 0.5},
+				{White: 1, Black: 2, WhitePoints: 0.5, BlackPoints: 0.5, Armageddon: true},
+			},
+			want: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MatchWinner(tt.games)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
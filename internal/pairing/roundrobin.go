@@ -0,0 +1,82 @@
+package pairing
+
+// RoundRobin schedules every round of a round-robin with the Berger tables,
+// ids are the players in seed order. An odd field gets a bye in every round
+// for the player the tables pair with the missing last seed. A double
+// round-robin plays the tables twice, with colors reversed the second time.
+func RoundRobin(ids []int64, double bool) ([]Round, error) {
+
+	if len(ids) < 2 {
+		return nil, ErrTooFewPlayers
+	}
+
+	// seed n is the fixed player of the tables, 0 stands in for the bye
+	seeds := append([]int64{}, ids...)
+	if len(seeds)%2 == 1 {
+		seeds = append(seeds, 0)
+	}
+	n := len(seeds)
+
+	id := func(seed int) int64 { return seeds[seed-1] }
+
+	// wrap keeps seeds in 1..n-1
+	wrap := func(seed int) int {
+		return ((seed-1)%(n-1)+(n-1))%(n-1) + 1
+	}
+
+	rounds := make([]Round, 0, n-1)
+
+	for r := 1; r < n; r++ {
+
+		var round Round
+
+		// the player facing the fixed seed is the one whose double is r+1
+		p := 1
+		for wrap(2*p) != wrap(r+1) {
+			p++
+		}
+
+		var boards [][2]int
+		if r%2 == 0 {
+			boards = append(boards, [2]int{n, p})
+		} else {
+			boards = append(boards, [2]int{p, n})
+		}
+
+		for k := 1; k < n/2; k++ {
+			a, b := wrap(p+k), wrap(p-k)
+			// the seed reaching the other by an odd number of steps has white
+			if wrap(b-a)%2 == 1 {
+				boards = append(boards, [2]int{a, b})
+			} else {
+				boards = append(boards, [2]int{b, a})
+			}
+		}
+
+		for _, board := range boards {
+			white, black := id(board[0]), id(board[1])
+			switch {
+			case white == 0:
+				round.Bye = black
+			case black == 0:
+				round.Bye = white
+			default:
+				round.Pairings = append(round.Pairings, Pairing{White: white, Black: black})
+			}
+		}
+
+		rounds = append(rounds, round)
+	}
+
+	if double {
+		for _, round := range rounds[:n-1] {
+			reversed := Round{Bye: round.Bye, Pairings: make([]Pairing, len(round.Pairings))}
+			for i, p := range round.Pairings {
+				reversed.Pairings[i] = Pairing{White: p.Black, Black: p.White}
+			}
+			rounds = append(rounds, reversed)
+		}
+	}
+
+	return rounds, nil
+}
@@ -0,0 +1,671 @@
+// Package pairing pairs the rounds of over the board tournaments.
+package pairing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+type Color int
+
+const (
+	NoColor Color = 0
+	White   Color = 1
+	Black   Color = -1
+)
+
+func (c Color) String() string {
+	switch c {
+	case White:
+		return "white"
+	case Black:
+		return "black"
+	default:
+		return "none"
+	}
+}
+
+var (
+	ErrNoPairing     = errors.New("pairing: no pairing satisfies the rules")
+	ErrTooFewPlayers = errors.New("pairing: at least two players are needed")
+)
+
+// maxSteps bounds the search, a club sized field needs a tiny fraction of it.
+const maxSteps = 2_000_000
+
+// Player is a participant's state before the round is paired.
+type Player struct {
+	ID     int64
+	Rating int
+	Score  float64
+	// Opponents are the players already met over the board.
+	Opponents []int64
+	// Colors are the colors played so far in round order, byes and
+	// forfeits are left out.
+	Colors []Color
+	HadBye bool
+	// FloatedDown is set when the player was paired below their score group,
+	// or got the bye, in the previous round.
+	FloatedDown bool
+}
+
+type Pairing struct {
+	White int64 `json:"white"`
+	Black int64 `json:"black"`
+}
+
+// Round lists the pairings by board. Bye is the player left out of an odd
+// field, 0 when everyone is paired.
+type Round struct {
+	Pairings []Pairing `json:"pairings"`
+	Bye      int64     `json:"bye"`
+}
+
+type player struct {
+	*Player
+	pn        int
+	opponents map[int64]bool
+	diff      int
+	pref      Color
+	strength  int
+}
+
+// preference strengths of the Dutch system
+const (
+	noPreference = iota
+	mildPreference
+	strongPreference
+	absolutePreference
+)
+
+type search struct {
+	steps       int
+	avoidFloats bool
+	// failed remembers the brackets that couldn't be paired, keyed by the
+	// number of groups left and the floaters coming in.
+	failed map[string]bool
+}
+
+// Swiss pairs the next round with the Dutch system. Players are ranked by
+// score then pairing number, pairing numbers follow rating. Within each score
+// group the top half meets the bottom half, transpositions and exchanges
+// resolve conflicts and unpaired players float down to the next group. Two
+// players never meet twice and two players who must get the same color never
+// meet. Among the pairings satisfying those rules, repeating last round's
+// downfloaters is avoided when possible.
+func Swiss(players []Player) (Round, error) {
+
+	if len(players) < 2 {
+		return Round{}, ErrTooFewPlayers
+	}
+
+	field, err := prepare(players)
+	if err != nil {
+		return Round{}, err
+	}
+
+	var bye *player
+	var pairs [][2]*player
+
+	for _, avoidFloats := range []bool{true, false} {
+		s := &search{avoidFloats: avoidFloats}
+		pairs, bye = s.pairField(field)
+		if pairs != nil {
+			break
+		}
+	}
+
+	if pairs == nil {
+		s := &search{}
+		pairs, bye = s.fallback(field)
+	}
+
+	if pairs == nil {
+		return Round{}, ErrNoPairing
+	}
+
+	return makeRound(pairs, bye), nil
+}
+
+func prepare(players []Player) ([]*player, error) {
+
+	field := make([]*player, len(players))
+	seen := make(map[int64]bool, len(players))
+
+	for i := range players {
+		p := &players[i]
+
+		if p.ID == 0 {
+			return nil, fmt.Errorf("pairing: player ids must not be 0")
+		}
+		if seen[p.ID] {
+			return nil, fmt.Errorf("pairing: duplicate player %d", p.ID)
+		}
+		seen[p.ID] = true
+
+		field[i] = &player{Player: p, opponents: make(map[int64]bool, len(p.Opponents))}
+		for _, id := range p.Opponents {
+			field[i].opponents[id] = true
+		}
+		field[i].colorPreference()
+	}
+
+	// pairing numbers follow rating, ties are broken by id so the result
+	// doesn't depend on the order of the input
+	sort.SliceStable(field, func(i, j int) bool {
+		if field[i].Rating != field[j].Rating {
+			return field[i].Rating > field[j].Rating
+		}
+		return field[i].ID < field[j].ID
+	})
+	for i, p := range field {
+		p.pn = i + 1
+	}
+
+	sortByRank(field)
+
+	return field, nil
+}
+
+func (p *player) colorPreference() {
+
+	n := len(p.Colors)
+	if n == 0 {
+		return
+	}
+
+	for _, c := range p.Colors {
+		p.diff += int(c)
+	}
+
+	last := p.Colors[n-1]
+	twice := n >= 2 && p.Colors[n-2] == last
+
+	switch {
+	case p.diff < -1 || (twice && last == Black):
+		p.pref, p.strength = White, absolutePreference
+	case p.diff > 1 || (twice && last == White):
+		p.pref, p.strength = Black, absolutePreference
+	case p.diff == -1:
+		p.pref, p.strength = White, strongPreference
+	case p.diff == 1:
+		p.pref, p.strength = Black, strongPreference
+	default:
+		p.pref, p.strength = -last, mildPreference
+	}
+}
+
+// compatible reports whether a and b may meet: they haven't met yet and
+// don't both need the same color.
+func compatible(a, b *player) bool {
+	if a.opponents[b.ID] || b.opponents[a.ID] {
+		return false
+	}
+	return !(a.strength == absolutePreference && b.strength == absolutePreference && a.pref == b.pref)
+}
+
+// ranksAbove orders players by score then pairing number.
+func ranksAbove(a, b *player) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.pn < b.pn
+}
+
+func sortByRank(players []*player) {
+	sort.SliceStable(players, func(i, j int) bool {
+		return ranksAbove(players[i], players[j])
+	})
+}
+
+// pairField gives the bye, when the field is odd, to the lowest ranked player
+// who hasn't had one and for whom the rest of the field can be paired.
+func (s *search) pairField(field []*player) ([][2]*player, *player) {
+
+	if len(field)%2 == 0 {
+		pairs, ok := s.pairBrackets(scoreGroups(field), nil)
+		if !ok {
+			return nil, nil
+		}
+		return pairs, nil
+	}
+
+	for _, allowRepeat := range []bool{false, true} {
+		for i := len(field) - 1; i >= 0; i-- {
+			candidate := field[i]
+			if candidate.HadBye != allowRepeat {
+				continue
+			}
+
+			rest := make([]*player, 0, len(field)-1)
+			rest = append(rest, field[:i]...)
+			rest = append(rest, field[i+1:]...)
+
+			if len(rest) == 0 {
+				return [][2]*player{}, candidate
+			}
+
+			// the groups differ with every candidate
+			s.failed = nil
+
+			pairs, ok := s.pairBrackets(scoreGroups(rest), nil)
+			if ok {
+				return pairs, candidate
+			}
+
+			if s.steps > maxSteps {
+				return nil, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func scoreGroups(field []*player) [][]*player {
+
+	var groups [][]*player
+
+	for i := 0; i < len(field); {
+		j := i
+		for j < len(field) && field[j].Score == field[i].Score {
+			j++
+		}
+		groups = append(groups, field[i:j])
+		i = j
+	}
+
+	return groups
+}
+
+// pairBrackets pairs the first score group together with the players who
+// floated into it, the players it leaves unpaired float into the next group.
+// A bracket's pairing is only kept when every bracket below can be paired.
+func (s *search) pairBrackets(groups [][]*player, floaters []*player) ([][2]*player, bool) {
+
+	if len(groups) == 0 {
+		return [][2]*player{}, len(floaters) == 0
+	}
+
+	key := failureKey(len(groups), floaters)
+	if s.failed[key] {
+		return nil, false
+	}
+
+	bracket := make([]*player, 0, len(floaters)+len(groups[0]))
+	bracket = append(bracket, floaters...)
+	bracket = append(bracket, groups[0]...)
+	sortByRank(bracket)
+
+	rest := groups[1:]
+	last := len(rest) == 0
+
+	var result [][2]*player
+
+	found := s.bracketCandidates(bracket, len(floaters), last, func(pairs [][2]*player, down []*player) bool {
+
+		if last && len(down) > 0 {
+			return false
+		}
+
+		if s.avoidFloats && !last {
+			for _, p := range down {
+				if p.FloatedDown {
+					return false
+				}
+			}
+		}
+
+		sub, ok := s.pairBrackets(rest, down)
+		if !ok {
+			return false
+		}
+
+		result = append(append([][2]*player{}, pairs...), sub...)
+		return true
+	})
+
+	// an exhausted budget doesn't prove anything
+	if !found && s.steps <= maxSteps {
+		if s.failed == nil {
+			s.failed = make(map[string]bool)
+		}
+		s.failed[key] = true
+	}
+
+	return result, found
+}
+
+func failureKey(groups int, floaters []*player) string {
+
+	ids := make([]int64, len(floaters))
+	for i, p := range floaters {
+		ids[i] = p.ID
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return fmt.Sprint(groups, ids)
+}
+
+// bracketCandidates calls yield with the bracket's pairings in the order the
+// Dutch system prefers them until yield returns true. The first floaters of
+// the bracket, the players who floated into it, are paired with the residents
+// first and the remaining residents are then paired among themselves.
+func (s *search) bracketCandidates(bracket []*player, floaters int, last bool, yield func(pairs [][2]*player, down []*player) bool) bool {
+
+	if floaters == 0 {
+		return s.homogeneous(bracket, last, yield)
+	}
+
+	residents := bracket[floaters:]
+
+	for paired := min(floaters, len(residents)); paired >= 0; paired-- {
+
+		// the lowest floaters keep floating
+		unpaired := bracket[paired:floaters]
+
+		found := s.transpositions(bracket[:paired], residents, func(pairs [][2]*player, remainder []*player) bool {
+			pairs = append([][2]*player{}, pairs...)
+
+			return s.homogeneous(remainder, last, func(more [][2]*player, down []*player) bool {
+				return yield(append(pairs, more...), append(append([]*player{}, unpaired...), down...))
+			})
+		})
+		if found {
+			return true
+		}
+
+		if s.steps > maxSteps {
+			return false
+		}
+	}
+
+	return false
+}
+
+// homogeneous pairs a bracket of players with the same score: as many pairs
+// as possible first, for each number of pairs the transpositions of S2 and
+// then the exchanges between S1 and S2.
+func (s *search) homogeneous(bracket []*player, last bool, yield func(pairs [][2]*player, down []*player) bool) bool {
+
+	n := len(bracket)
+
+	for p := n / 2; p >= 0; p-- {
+
+		// the last bracket has nowhere to float players to
+		if last && p*2 != n {
+			return false
+		}
+
+		if s.transpositions(bracket[:p], bracket[p:], yield) {
+			return true
+		}
+
+		for _, ex := range exchanges(p, n) {
+			s1 := make([]*player, 0, p)
+			s2 := make([]*player, 0, n-p)
+			for i, pl := range bracket {
+				inS1 := i < p
+				if i == ex[0] || i == ex[1] {
+					inS1 = !inS1
+				}
+				if inS1 {
+					s1 = append(s1, pl)
+				} else {
+					s2 = append(s2, pl)
+				}
+			}
+
+			if s.transpositions(s1, s2, yield) {
+				return true
+			}
+
+			if s.steps > maxSteps {
+				return false
+			}
+		}
+
+		if s.steps > maxSteps {
+			return false
+		}
+	}
+
+	return false
+}
+
+// exchanges lists the swaps of one S1 player with one S2 player by bracket
+// index, those moving players the least come first and among them the lowest
+// S1 player is swapped first.
+func exchanges(p int, n int) [][2]int {
+
+	var list [][2]int
+	for a := 0; a < p; a++ {
+		for b := p; b < n; b++ {
+			list = append(list, [2]int{a, b})
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		di, dj := list[i][1]-list[i][0], list[j][1]-list[j][0]
+		if di != dj {
+			return di < dj
+		}
+		return list[i][0] > list[j][0]
+	})
+
+	return list
+}
+
+// transpositions pairs S1[i] with the i-th player chosen from S2, trying the
+// choices in lexicographic order. S2 players left over float down.
+func (s *search) transpositions(s1 []*player, s2 []*player, yield func(pairs [][2]*player, down []*player) bool) bool {
+
+	used := make([]bool, len(s2))
+	pairs := make([][2]*player, len(s1))
+
+	var choose func(i int) bool
+	choose = func(i int) bool {
+
+		s.steps++
+		if s.steps > maxSteps {
+			return false
+		}
+
+		if i == len(s1) {
+			down := make([]*player, 0, len(s2)-len(s1))
+			for j, pl := range s2 {
+				if !used[j] {
+					down = append(down, pl)
+				}
+			}
+			return yield(pairs, down)
+		}
+
+		for j, candidate := range s2 {
+			if used[j] || !compatible(s1[i], candidate) {
+				continue
+			}
+
+			used[j] = true
+			pairs[i] = [2]*player{s1[i], candidate}
+			if choose(i + 1) {
+				return true
+			}
+			used[j] = false
+		}
+
+		return false
+	}
+
+	return choose(0)
+}
+
+// fallback ignores score groups and pairs every player with the closest
+// ranked opponent they may meet, it is only used when the Dutch search finds
+// nothing within its budget.
+func (s *search) fallback(field []*player) ([][2]*player, *player) {
+
+	var bye *player
+	rest := field
+
+	if len(field)%2 == 1 {
+		bye = field[len(field)-1]
+		for i := len(field) - 1; i >= 0; i-- {
+			if !field[i].HadBye {
+				bye = field[i]
+				break
+			}
+		}
+		rest = make([]*player, 0, len(field)-1)
+		for _, p := range field {
+			if p != bye {
+				rest = append(rest, p)
+			}
+		}
+	}
+
+	used := make([]bool, len(rest))
+	var pairs [][2]*player
+
+	var match func() bool
+	match = func() bool {
+
+		s.steps++
+		if s.steps > maxSteps {
+			return false
+		}
+
+		first := -1
+		for i := range rest {
+			if !used[i] {
+				first = i
+				break
+			}
+		}
+		if first == -1 {
+			return true
+		}
+
+		used[first] = true
+		for j := first + 1; j < len(rest); j++ {
+			if used[j] || !compatible(rest[first], rest[j]) {
+				continue
+			}
+
+			used[j] = true
+			pairs = append(pairs, [2]*player{rest[first], rest[j]})
+			if match() {
+				return true
+			}
+			pairs = pairs[:len(pairs)-1]
+			used[j] = false
+		}
+		used[first] = false
+
+		return false
+	}
+
+	if !match() {
+		return nil, nil
+	}
+
+	return pairs, bye
+}
+
+// makeRound orders the boards by the score of their higher ranked player,
+// then the sum of scores, then the higher ranked player's pairing number, and
+// allocates the colors.
+func makeRound(pairs [][2]*player, bye *player) Round {
+
+	for i, pair := range pairs {
+		if ranksAbove(pair[1], pair[0]) {
+			pairs[i] = [2]*player{pair[1], pair[0]}
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		a, b := pairs[i], pairs[j]
+		if a[0].Score != b[0].Score {
+			return a[0].Score > b[0].Score
+		}
+		if sa, sb := a[0].Score+a[1].Score, b[0].Score+b[1].Score; sa != sb {
+			return sa > sb
+		}
+		return a[0].pn < b[0].pn
+	})
+
+	round := Round{Pairings: make([]Pairing, len(pairs))}
+
+	for board, pair := range pairs {
+		white, black := allocateColors(pair[0], pair[1], board)
+		round.Pairings[board] = Pairing{White: white.ID, Black: black.ID}
+	}
+
+	if bye != nil {
+		round.Bye = bye.ID
+	}
+
+	return round
+}
+
+// allocateColors grants both preferences when possible, otherwise the
+// stronger preference, then the bigger color imbalance, then alternates from
+// the last round the two had different colors, then favours the higher ranked
+// player. Players without games follow the board, the higher ranked player
+// gets white on odd boards.
+func allocateColors(higher *player, lower *player, board int) (*player, *player) {
+
+	switch {
+	case higher.pref == NoColor && lower.pref == NoColor:
+		if board%2 == 0 {
+			return higher, lower
+		}
+		return lower, higher
+
+	case higher.pref == NoColor:
+		return byPreference(lower, higher)
+
+	case lower.pref == NoColor, higher.pref != lower.pref:
+		return byPreference(higher, lower)
+
+	case higher.strength != lower.strength:
+		if higher.strength > lower.strength {
+			return byPreference(higher, lower)
+		}
+		return byPreference(lower, higher)
+
+	case abs(higher.diff) != abs(lower.diff):
+		if abs(higher.diff) > abs(lower.diff) {
+			return byPreference(higher, lower)
+		}
+		return byPreference(lower, higher)
+	}
+
+	for i, j := len(higher.Colors)-1, len(lower.Colors)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if higher.Colors[i] != lower.Colors[j] {
+			// both alternate from that round
+			if higher.Colors[i] == White {
+				return lower, higher
+			}
+			return higher, lower
+		}
+	}
+
+	return byPreference(higher, lower)
+}
+
+// byPreference gives granted the color it prefers.
+func byPreference(granted *player, other *player) (*player, *player) {
+	if granted.pref == Black {
+		return other, granted
+	}
+	return granted, other
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
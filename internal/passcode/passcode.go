@@ -1,13 +1,52 @@
 package passcode
 
 import (
+	"context"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
 	"math/big"
 	mrand "math/rand"
-	"strconv"
+	"time"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+	"github.com/google/uuid"
+)
+
+const (
+	PurposeActivation    = "activation"
+	PurposePasswordReset = "password_reset"
+	PurposePhoneChange   = "phone_change"
+)
+
+var (
+	ErrNotFound        = errors.New("passcode not found")
+	ErrMismatch        = errors.New("passcode doesn't match")
+	ErrExpired         = errors.New("passcode expired")
+	ErrTooManyAttempts = errors.New("too many passcode attempts")
 )
 
+// Manager issues one-time passcodes and verifies them against the otp table.
+// Only an HMAC of the code keyed by the server secret is stored.
+type Manager struct {
+	store       db.Store
+	secret      []byte
+	ttl         time.Duration
+	maxAttempts int
+}
+
+func New(store db.Store, secret string, ttl time.Duration, maxAttempts int) *Manager {
+	return &Manager{
+		store:       store,
+		secret:      []byte(secret),
+		ttl:         ttl,
+		maxAttempts: maxAttempts,
+	}
+}
+
 func GenSecureRandomNumber() int {
 
 	max := new(big.Int).SetInt64(900000)
@@ -24,12 +63,83 @@ func GenSecureRandomNumber() int {
 
 }
 
-func HashPasscode() (int, [32]byte) {
+// IssueWith creates a new passcode for the purpose through q, which may be a
+// transaction, invalidating the ones issued before it. The passcode is tied
+// to target, such as the phone number it was sent to, that Verify hands back
+// once the code is confirmed.
+func (m *Manager) IssueWith(ctx context.Context, q db.Querier, user_id uuid.UUID, purpose string, target string) (int, time.Time, error) {
+
+	err := q.InvalidateOtps(ctx, db.InvalidateOtpsParams{UserID: user_id, Purpose: purpose})
+	if err != nil {
+		return 0, time.Time{}, err
+	}
 
 	passcode := GenSecureRandomNumber()
-	numStr := strconv.Itoa(passcode)
-	data := []byte(numStr)
+	expiry := time.Now().Add(m.ttl)
+
+	args := db.CreateOtpParams{
+		UserID:      user_id,
+		Purpose:     purpose,
+		CodeHash:    m.hash(user_id, purpose, target, passcode),
+		MaxAttempts: int32(m.maxAttempts),
+		Expiry:      expiry,
+		Target:      target,
+	}
+
+	_, err = q.CreateOtp(ctx, args)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
 
-	return passcode, sha256.Sum256(data)
+	return passcode, expiry, nil
+}
+
+// Verify checks the passcode and consumes it on success. Every guess claims
+// one of the passcode's attempts before it is compared, in a single update,
+// so concurrent guesses can't go over the limit. The target the passcode was
+// issued for is returned.
+func (m *Manager) Verify(ctx context.Context, user_id uuid.UUID, purpose string, passcode int) (string, error) {
+
+	otp, err := m.store.GetActiveOtp(ctx, db.GetActiveOtpParams{UserID: user_id, Purpose: purpose})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+
+	if time.Now().After(otp.Expiry) {
+		return "", ErrExpired
+	}
+
+	_, err = m.store.ClaimOtpAttempt(ctx, otp.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrTooManyAttempts
+		}
+		return "", err
+	}
+
+	if !hmac.Equal(otp.CodeHash, m.hash(user_id, purpose, otp.Target, passcode)) {
+		return "", ErrMismatch
+	}
+
+	rows, err := m.store.ConsumeOtp(ctx, otp.ID)
+	if err != nil {
+		return "", err
+	}
+
+	// consumed by a concurrent request
+	if rows == 0 {
+		return "", ErrNotFound
+	}
+
+	return otp.Target, nil
+}
 
+// hash binds the code to the user, purpose and target so a code can't be replayed elsewhere.
+func (m *Manager) hash(user_id uuid.UUID, purpose string, target string, passcode int) []byte {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(fmt.Sprintf("%s:%s:%s:%d", user_id, purpose, target, passcode)))
+	return mac.Sum(nil)
 }
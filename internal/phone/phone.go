@@ -0,0 +1,113 @@
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+// Numbers without a country code are read as Tanzanian.
+const DefaultCountryCode = "255"
+
+var (
+	ErrInvalid       = errors.New("invalid phone number")
+	ErrUnsupported   = errors.New("unsupported country code")
+	ErrInvalidPrefix = errors.New("invalid carrier prefix")
+)
+
+type country struct {
+	name string
+	// length of the national number without the trunk 0
+	length   int
+	prefixes []string
+}
+
+// East African mobile networks, keyed by country calling code.
+var countries = map[string]country{
+	"255": {name: "Tanzania", length: 9, prefixes: []string{"61", "62", "65", "67", "68", "69", "71", "73", "74", "75", "76", "77", "78", "79"}},
+	"254": {name: "Kenya", length: 9, prefixes: []string{"10", "11", "70", "71", "72", "73", "74", "75", "76", "77", "78", "79"}},
+	"256": {name: "Uganda", length: 9, prefixes: []string{"70", "71", "72", "74", "75", "76", "77", "78", "79"}},
+	"250": {name: "Rwanda", length: 9, prefixes: []string{"72", "73", "78", "79"}},
+	"257": {name: "Burundi", length: 8, prefixes: []string{"61", "62", "65", "66", "68", "69", "71", "72", "75", "76", "77", "79"}},
+}
+
+// Parse converts a phone number written as +255712345678, 255712345678,
+// 00255712345678, 0712345678 or 712345678 into E.164 (+255712345678).
+func Parse(s string) (string, error) {
+
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+
+	international := false
+	switch {
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+		international = true
+	case strings.HasPrefix(s, "00"):
+		s = s[2:]
+		international = true
+	}
+
+	if s == "" || !isDigits(s) {
+		return "", ErrInvalid
+	}
+
+	if !international {
+		if strings.HasPrefix(s, "0") {
+			return normalize(DefaultCountryCode, s[1:])
+		}
+
+		if len(s) == countries[DefaultCountryCode].length {
+			return normalize(DefaultCountryCode, s)
+		}
+	}
+
+	for code := range countries {
+		if strings.HasPrefix(s, code) {
+			return normalize(code, s[len(code):])
+		}
+	}
+
+	return "", ErrUnsupported
+}
+
+// Valid reports whether s can be parsed into an E.164 number.
+func Valid(s string) bool {
+	_, err := Parse(s)
+	return err == nil
+}
+
+func normalize(code, national string) (string, error) {
+
+	c := countries[code]
+
+	// tolerate a trunk 0 written after the country code, +255 0712...
+	if len(national) == c.length+1 && strings.HasPrefix(national, "0") {
+		national = national[1:]
+	}
+
+	if len(national) != c.length {
+		return "", ErrInvalid
+	}
+
+	for _, prefix := range c.prefixes {
+		if strings.HasPrefix(national, prefix) {
+			return "+" + code + national, nil
+		}
+	}
+
+	return "", ErrInvalidPrefix
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
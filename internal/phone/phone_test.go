@@ -0,0 +1,76 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		err   error
+	}{
+		{name: "local with trunk 0", input: "0712345678", want: "+255712345678"},
+		{name: "local without trunk 0", input: "712345678", want: "+255712345678"},
+		{name: "country code", input: "255712345678", want: "+255712345678"},
+		{name: "plus country code", input: "+255712345678", want: "+255712345678"},
+		{name: "00 country code", input: "00255712345678", want: "+255712345678"},
+		{name: "spaces and dashes", input: " +255 71-234.5678 ", want: "+255712345678"},
+		{name: "parentheses", input: "(0712) 345 678", want: "+255712345678"},
+		{name: "trunk 0 after country code", input: "+2550712345678", want: "+255712345678"},
+		{name: "halotel prefix", input: "0621234567", want: "+255621234567"},
+		{name: "kenya", input: "+254712345678", want: "+254712345678"},
+		{name: "kenya without plus", input: "254112345678", want: "+254112345678"},
+		{name: "uganda", input: "+256772345678", want: "+256772345678"},
+		{name: "rwanda", input: "+250788123456", want: "+250788123456"},
+		{name: "burundi, 8 digit numbers", input: "+25761234567", want: "+25761234567"},
+
+		{name: "empty", input: "", err: ErrInvalid},
+		{name: "only a plus", input: "+", err: ErrInvalid},
+		{name: "letters", input: "07123abc78", err: ErrInvalid},
+		{name: "local too short", input: "071234567", err: ErrInvalid},
+		{name: "local too long", input: "07123456789", err: ErrInvalid},
+		{name: "international too short", input: "+25571234567", err: ErrInvalid},
+		{name: "international too long", input: "+2557123456789", err: ErrInvalid},
+		{name: "landline prefix", input: "0221234567", err: ErrInvalidPrefix},
+		{name: "unknown tanzanian prefix", input: "+255812345678", err: ErrInvalidPrefix},
+		{name: "unsupported country", input: "+447911123456", err: ErrUnsupported},
+		{name: "country code outside east africa", input: "0027821234567", err: ErrUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got, err := Parse(tt.input)
+
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Parse(%q) error = %v, want %v", tt.input, err, tt.err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.input, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValid(t *testing.T) {
+
+	if !Valid("0712345678") {
+		t.Error("Valid(0712345678) = false, want true")
+	}
+
+	if Valid("12345") {
+		t.Error("Valid(12345) = true, want false")
+	}
+}
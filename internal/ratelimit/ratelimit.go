@@ -0,0 +1,210 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+)
+
+// Rule allows Requests in each Window.
+type Rule struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Config controls request windows and the lockout applied after repeated
+// failures. Routes overrides the default rule by route name.
+type Config struct {
+	Requests     int
+	Window       time.Duration
+	Routes       map[string]Rule
+	MaxFailures  int
+	LockoutBase  time.Duration
+	LockoutMax   time.Duration
+	FailureReset time.Duration
+}
+
+// Limiter keeps its counters in Postgres so limits survive restarts and are
+// shared between API replicas.
+type Limiter struct {
+	store db.Store
+	cfg   Config
+}
+
+func New(store db.Store, cfg Config) *Limiter {
+	return &Limiter{
+		store: store,
+		cfg:   cfg,
+	}
+}
+
+// ParseRules reads route rules written as name=requests/window separated by
+// commas, e.g. "login=10/1m,register=5/1h". Every name must be one of routes.
+func ParseRules(s string, routes []string) (map[string]Rule, error) {
+
+	rules := make(map[string]Rule)
+
+	for _, part := range strings.Split(s, ",") {
+
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, rule, ok := strings.Cut(part, "=")
+		requests, window, ok2 := strings.Cut(rule, "/")
+		if !ok || !ok2 || name == "" {
+			return nil, fmt.Errorf("ratelimit: invalid rule %q", part)
+		}
+
+		if !slices.Contains(routes, name) {
+			return nil, fmt.Errorf("ratelimit: unknown route in rule %q", part)
+		}
+
+		var r Rule
+		var err error
+
+		r.Requests, err = strconv.Atoi(requests)
+		if err != nil || r.Requests < 1 {
+			return nil, fmt.Errorf("ratelimit: invalid requests in rule %q", part)
+		}
+
+		r.Window, err = time.ParseDuration(window)
+		if err != nil || r.Window < time.Second {
+			return nil, fmt.Errorf("ratelimit: invalid window in rule %q", part)
+		}
+
+		rules[name] = r
+	}
+
+	return rules, nil
+}
+
+// Allow counts a request against key under the rule of the route name and
+// reports whether it fits in the current window. When it doesn't, the time
+// until the window resets is returned.
+func (l *Limiter) Allow(ctx context.Context, name string, key string) (bool, time.Duration, error) {
+
+	rule := l.rule(name)
+
+	args := db.HitRateLimitParams{
+		Key:           key,
+		WindowSeconds: int32(rule.Window.Seconds()),
+	}
+
+	hit, err := l.store.HitRateLimit(ctx, args)
+	if err != nil {
+		return false, 0, err
+	}
+
+	if int(hit.Hits) <= rule.Requests {
+		return true, 0, nil
+	}
+
+	return false, time.Until(hit.WindowStart.Add(rule.Window)), nil
+}
+
+func (l *Limiter) rule(name string) Rule {
+	if rule, ok := l.cfg.Routes[name]; ok {
+		return rule
+	}
+	return Rule{Requests: l.cfg.Requests, Window: l.cfg.Window}
+}
+
+// Locked returns how long key stays locked out, zero when it isn't locked.
+func (l *Limiter) Locked(ctx context.Context, key string) (time.Duration, error) {
+
+	failure, err := l.store.GetLoginFailure(ctx, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	if !failure.LockedUntil.Valid {
+		return 0, nil
+	}
+
+	remaining := time.Until(failure.LockedUntil.Time)
+	if remaining < 0 {
+		return 0, nil
+	}
+
+	return remaining, nil
+}
+
+// Fail records a failed attempt for key. Once MaxFailures is reached every
+// further failure doubles the lockout up to LockoutMax.
+func (l *Limiter) Fail(ctx context.Context, key string) (time.Duration, error) {
+
+	args := db.RecordLoginFailureParams{
+		Key:          key,
+		ResetSeconds: int32(l.cfg.FailureReset.Seconds()),
+	}
+
+	failures, err := l.store.RecordLoginFailure(ctx, args)
+	if err != nil {
+		return 0, err
+	}
+
+	if int(failures) < l.cfg.MaxFailures {
+		return 0, nil
+	}
+
+	lockout := l.lockout(int(failures) - l.cfg.MaxFailures)
+
+	err = l.store.LockLogin(ctx, db.LockLoginParams{
+		Key:         key,
+		LockedUntil: sql.NullTime{Time: time.Now().Add(lockout), Valid: true},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return lockout, nil
+}
+
+// Reset clears the failures recorded for key after a successful attempt.
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	return l.store.ResetLoginFailures(ctx, key)
+}
+
+// Prune deletes request windows that have ended and failures that were
+// forgotten, it returns how many rows it removed.
+func (l *Limiter) Prune(ctx context.Context) (int64, error) {
+
+	window := l.cfg.Window
+	for _, rule := range l.cfg.Routes {
+		window = max(window, rule.Window)
+	}
+
+	windows, err := l.store.DeleteStaleRateLimits(ctx, int32(window.Seconds()))
+	if err != nil {
+		return 0, err
+	}
+
+	failures, err := l.store.DeleteStaleLoginFailures(ctx, int32(l.cfg.FailureReset.Seconds()))
+	if err != nil {
+		return windows, err
+	}
+
+	return windows + failures, nil
+}
+
+func (l *Limiter) lockout(n int) time.Duration {
+
+	lockout := l.cfg.LockoutBase
+	for i := 0; i < n && lockout < l.cfg.LockoutMax; i++ {
+		lockout *= 2
+	}
+
+	return min(lockout, l.cfg.LockoutMax)
+}
@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRules(t *testing.T) {
+
+	routes := []string{"login", "register"}
+
+	tests := []struct {
+		name    string
+		rules   string
+		want    map[string]Rule
+		wantErr bool
+	}{
+		{name: "empty", rules: "", want: map[string]Rule{}},
+		{
+			name:  "rules",
+			rules: "login=10/1m, register=5/1h,",
+			want: map[string]Rule{
+				"login":    {Requests: 10, Window: time.Minute},
+				"register": {Requests: 5, Window: time.Hour},
+			},
+		},
+		{name: "unknown route", rules: "logn=10/1m", wantErr: true},
+		{name: "missing name", rules: "=10/1m", wantErr: true},
+		{name: "missing window", rules: "login=10", wantErr: true},
+		{name: "bad requests", rules: "login=ten/1m", wantErr: true},
+		{name: "no requests", rules: "login=0/1m", wantErr: true},
+		{name: "bad duration", rules: "login=10/1 minute", wantErr: true},
+		{name: "duration without unit", rules: "login=10/60", wantErr: true},
+		{name: "window under a second", rules: "login=10/500ms", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got, err := ParseRules(tt.rules, routes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRules(%q) error = %v, wantErr %t", tt.rules, err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseRules(%q) = %v, want %v", tt.rules, got, tt.want)
+			}
+
+			for name, rule := range tt.want {
+				if got[name] != rule {
+					t.Errorf("rule %s = %+v, want %+v", name, got[name], rule)
+				}
+			}
+		})
+	}
+}
+
+func TestLockout(t *testing.T) {
+
+	l := New(nil, Config{LockoutBase: time.Minute, LockoutMax: 10 * time.Minute})
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: time.Minute},
+		{failures: 1, want: 2 * time.Minute},
+		{failures: 3, want: 8 * time.Minute},
+		{failures: 4, want: 10 * time.Minute},
+		{failures: 1000, want: 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := l.lockout(tt.failures); got != tt.want {
+			t.Errorf("lockout(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Fake keeps messages in memory instead of sending them, and appends them to
+// a file as JSON lines when a path is given. Meant for development.
+type Fake struct {
+	path     string
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewFake(path string) *Fake {
+	return &Fake{path: path}
+}
+
+func (f *Fake) Name() string {
+	return "fake"
+}
+
+func (f *Fake) Send(ctx context.Context, msg Message) (Result, error) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.messages = append(f.messages, msg)
+
+	slog.Info("fake sms sent", "to", msg.To, "reference", msg.Reference, "text", msg.Text)
+
+	if f.path != "" {
+		err := f.write(msg)
+		if err != nil {
+			return Result{}, err
+		}
+	}
+
+	return Result{
+		MessageID:   fmt.Sprintf("fake-%d", len(f.messages)),
+		Status:      "DELIVERED",
+		Description: "Message stored by the fake sender",
+		SMSCount:    1,
+	}, nil
+}
+
+// Messages returns the messages sent so far.
+func (f *Fake) Messages() []Message {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	messages := make([]Message, len(f.messages))
+	copy(messages, f.messages)
+	return messages
+}
+
+func (f *Fake) write(msg Message) error {
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	line := struct {
+		Message
+		SentAt time.Time `json:"sent_at"`
+	}{
+		Message: msg,
+		SentAt:  time.Now(),
+	}
+
+	return json.NewEncoder(file).Encode(line)
+}
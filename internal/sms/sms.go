@@ -0,0 +1,27 @@
+package sms
+
+import (
+	"context"
+)
+
+// Message is a single text message, Reference is our own id for it.
+type Message struct {
+	Reference string
+	To        string
+	Text      string
+}
+
+// Result is what the provider reported back after accepting a message.
+type Result struct {
+	MessageID   string
+	Status      string
+	Description string
+	SMSCount    int
+	Cost        float64
+}
+
+// Sender delivers text messages through an SMS provider.
+type Sender interface {
+	Name() string
+	Send(ctx context.Context, msg Message) (Result, error)
+}
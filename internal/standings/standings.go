@@ -0,0 +1,289 @@
+// Package standings ranks tournament players by points and tiebreaks.
+package standings
+
+import (
+	"fmt"
+	"sort"
+)
+
+// tiebreaks, following the FIDE tiebreak regulations
+const (
+	Buchholz        = "buchholz"
+	BuchholzCut1    = "buchholz_cut1"
+	SonnebornBerger = "sonneborn_berger"
+	DirectEncounter = "direct_encounter"
+	Wins            = "wins"
+	Progressive     = "progressive"
+)
+
+var Tiebreaks = []string{Buchholz, BuchholzCut1, SonnebornBerger, DirectEncounter, Wins, Progressive}
+
+// Game is a reported game. Black is 0 for a bye, Played is false for byes and
+// forfeits.
+type Game struct {
+	Round       int
+	White       int64
+	Black       int64
+	WhitePoints float64
+	BlackPoints float64
+	Played      bool
+}
+
+// Entry is a player's line in the standings, Tiebreaks holds the values in
+// the order they were asked for. Tied players share a rank.
+type Entry struct {
+	Rank      int       `json:"rank"`
+	ID        int64     `json:"id"`
+	Points    float64   `json:"points"`
+	Played    int       `json:"played"`
+	Wins      int       `json:"wins"`
+	Tiebreaks []float64 `json:"tiebreaks"`
+}
+
+type result struct {
+	opponent int64
+	points   float64
+	played   bool
+}
+
+type player struct {
+	id       int64
+	seed     int
+	points   float64
+	adjusted float64
+	rounds   []result
+	entry    *Entry
+}
+
+// Compute ranks the players after the given number of rounds, ids are the
+// players in seed order which breaks the ties left. Rounds a player has no
+// game in count as unplayed with no points.
+//
+// An opponent's score used by Buchholz and Sonneborn-Berger counts their
+// unplayed rounds as draws, and a player's own unplayed rounds count as games
+// against a virtual opponent with the player's final score, as in the 2023
+// FIDE regulations, not the score they had at the unplayed round. Direct
+// encounter only applies when all the players tied before it have met each
+// other.
+func Compute(ids []int64, rounds int, games []Game, tiebreaks []string) ([]Entry, error) {
+
+	for _, name := range tiebreaks {
+		if !known(name) {
+			return nil, fmt.Errorf("standings: unknown tiebreak %q", name)
+		}
+	}
+
+	players := make(map[int64]*player, len(ids))
+	list := make([]*player, len(ids))
+
+	for i, id := range ids {
+		p := &player{id: id, seed: i, rounds: make([]result, rounds)}
+		p.entry = &Entry{ID: id, Tiebreaks: make([]float64, len(tiebreaks))}
+		players[id] = p
+		list[i] = p
+	}
+
+	record := func(id int64, round int, opponent int64, points float64, played bool) {
+		p := players[id]
+		if p == nil || round < 1 || round > rounds {
+			return
+		}
+		r := &p.rounds[round-1]
+		r.points += points
+		r.played = r.played || played
+		if played {
+			r.opponent = opponent
+		}
+	}
+
+	for _, g := range games {
+		record(g.White, g.Round, g.Black, g.WhitePoints, g.Played && g.Black != 0)
+		if g.Black != 0 {
+			record(g.Black, g.Round, g.White, g.BlackPoints, g.Played)
+		}
+	}
+
+	for _, p := range list {
+		for _, r := range p.rounds {
+			p.points += r.points
+			if r.played {
+				p.adjusted += r.points
+				p.entry.Played++
+				if r.points == 1 {
+					p.entry.Wins++
+				}
+			} else {
+				p.adjusted += 0.5
+			}
+		}
+		p.entry.Points = p.points
+	}
+
+	for i, name := range tiebreaks {
+		if name == DirectEncounter {
+			continue
+		}
+		for _, p := range list {
+			p.entry.Tiebreaks[i] = p.tiebreak(name, players)
+		}
+	}
+
+	// direct encounter depends on who is still tied, so it is worked out in
+	// order with the tiebreaks before it settled
+	for i, name := range tiebreaks {
+		if name != DirectEncounter {
+			continue
+		}
+		for _, group := range tiedGroups(list, i) {
+			directEncounter(group, i)
+		}
+	}
+
+	sort.SliceStable(list, func(a, b int) bool {
+		if c := compare(list[a], list[b], len(tiebreaks)); c != 0 {
+			return c > 0
+		}
+		return list[a].seed < list[b].seed
+	})
+
+	entries := make([]Entry, len(list))
+	for i, p := range list {
+		p.entry.Rank = i + 1
+		if i > 0 && compare(p, list[i-1], len(tiebreaks)) == 0 {
+			p.entry.Rank = entries[i-1].Rank
+		}
+		entries[i] = *p.entry
+	}
+
+	return entries, nil
+}
+
+func known(name string) bool {
+	for _, t := range Tiebreaks {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *player) tiebreak(name string, players map[int64]*player) float64 {
+
+	var total float64
+	lowest := -1.0
+
+	running := 0.0
+
+	for _, r := range p.rounds {
+
+		// what the round's opponent contributes, a virtual one with the
+		// player's final score for unplayed rounds
+		opponent := p.points
+		if r.played {
+			if o := players[r.opponent]; o != nil {
+				opponent = o.adjusted
+			} else {
+				opponent = 0
+			}
+		}
+
+		switch name {
+		case Buchholz, BuchholzCut1:
+			total += opponent
+			if lowest < 0 || opponent < lowest {
+				lowest = opponent
+			}
+		case SonnebornBerger:
+			total += r.points * opponent
+		case Progressive:
+			running += r.points
+			total += running
+		case Wins:
+			if r.played && r.points == 1 {
+				total++
+			}
+		}
+	}
+
+	if name == BuchholzCut1 && lowest > 0 {
+		total -= lowest
+	}
+
+	return total
+}
+
+// tiedGroups groups the players level on points and on the tiebreaks before
+// the i-th one.
+func tiedGroups(list []*player, i int) [][]*player {
+
+	groups := make(map[string][]*player)
+	var keys []string
+
+	for _, p := range list {
+		key := fmt.Sprint(p.points, p.entry.Tiebreaks[:i])
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], p)
+	}
+
+	result := make([][]*player, 0, len(keys))
+	for _, key := range keys {
+		if len(groups[key]) > 1 {
+			result = append(result, groups[key])
+		}
+	}
+
+	return result
+}
+
+// directEncounter scores the points each player of the group made against
+// the others, when every pair of them has met.
+func directEncounter(group []*player, i int) {
+
+	inGroup := make(map[int64]bool, len(group))
+	for _, p := range group {
+		inGroup[p.id] = true
+	}
+
+	scores := make([]float64, len(group))
+
+	for n, p := range group {
+		met := make(map[int64]bool)
+		for _, r := range p.rounds {
+			if r.played && inGroup[r.opponent] {
+				met[r.opponent] = true
+				scores[n] += r.points
+			}
+		}
+		if len(met) < len(group)-1 {
+			return
+		}
+	}
+
+	for n, p := range group {
+		p.entry.Tiebreaks[i] = scores[n]
+	}
+}
+
+func compare(a *player, b *player, n int) int {
+
+	if a.points != b.points {
+		return sign(a.points - b.points)
+	}
+
+	for i := 0; i < n; i++ {
+		if a.entry.Tiebreaks[i] != b.entry.Tiebreaks[i] {
+			return sign(a.entry.Tiebreaks[i] - b.entry.Tiebreaks[i])
+		}
+	}
+
+	return 0
+}
+
+func sign(f float64) int {
+	if f > 0 {
+		return 1
+	}
+	return -1
+}
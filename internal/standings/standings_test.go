@@ -0,0 +1,235 @@
+package standings
+
+import (
+	"fmt"
+	"testing"
+)
+
+func played(round int, white, black int64, whitePoints, blackPoints float64) Game {
+	return Game{Round: round, White: white, Black: black, WhitePoints: whitePoints, BlackPoints: blackPoints, Played: true}
+}
+
+func unplayed(round int, white, black int64, whitePoints, blackPoints float64) Game {
+	return Game{Round: round, White: white, Black: black, WhitePoints: whitePoints, BlackPoints: blackPoints}
+}
+
+// swiss is a three round swiss of five players with a full-point bye, a
+// forfeit, a half-point bye and an absence.
+//
+//	round 1: 1-2 1-0, 3-4 ½-½, 5 bye
+//	round 2: 1-3 ½-½, 5-2 +-, 4 half-bye
+//	round 3: 1-5 1-0, 2-4 0-1, 3 absent
+//
+// Scores counting unplayed rounds as draws, for the opponents: 1 2.5,
+// 2 0.5, 3 1.5, 4 2 and 5 1. The virtual opponents of unplayed rounds have
+// the player's final score: 2 for player 4's half-bye of round 2 although
+// they had 0.5 then.
+var swiss = []Game{
+	played(1, 1, 2, 1, 0),
+	played(1, 3, 4, 0.5, 0.5),
+	unplayed(1, 5, 0, 1, 0),
+	played(2, 1, 3, 0.5, 0.5),
+	unplayed(2, 5, 2, 1, 0),
+	unplayed(2, 4, 0, 0.5, 0),
+	played(3, 1, 5, 1, 0),
+	played(3, 2, 4, 0, 1),
+}
+
+type want struct {
+	id        int64
+	rank      int
+	points    float64
+	tiebreaks []float64
+}
+
+func TestCompute(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		ids       []int64
+		rounds    int
+		games     []Game
+		tiebreaks []string
+		want      []want
+	}{
+		{
+			name:      "buchholz with byes and forfeits",
+			ids:       []int64{1, 2, 3, 4, 5},
+			rounds:    3,
+			games:     swiss,
+			tiebreaks: []string{Buchholz},
+			want: []want{
+				{id: 1, rank: 1, points: 2.5, tiebreaks: []float64{3}}, // 0.5 + 1.5 + 1
+				{id: 5, rank: 2, points: 2, tiebreaks: []float64{6.5}}, // 2 + 2 + 2.5
+				{id: 4, rank: 3, points: 2, tiebreaks: []float64{4}},   // 1.5 + 2 + 0.5
+				{id: 3, rank: 4, points: 1, tiebreaks: []float64{5.5}}, // 2 + 2.5 + 1
+				{id: 2, rank: 5, points: 0, tiebreaks: []float64{4.5}}, // 2.5 + 0 + 2
+			},
+		},
+		{
+			name:      "buchholz cut 1",
+			ids:       []int64{1, 2, 3, 4, 5},
+			rounds:    3,
+			games:     swiss,
+			tiebreaks: []string{BuchholzCut1},
+			want: []want{
+				{id: 1, rank: 1, points: 2.5, tiebreaks: []float64{2.5}},
+				{id: 5, rank: 2, points: 2, tiebreaks: []float64{4.5}},
+				{id: 4, rank: 3, points: 2, tiebreaks: []float64{3.5}},
+				{id: 3, rank: 4, points: 1, tiebreaks: []float64{4.5}},
+				{id: 2, rank: 5, points: 0, tiebreaks: []float64{4.5}},
+			},
+		},
+		{
+			name:      "sonneborn-berger with byes and forfeits",
+			ids:       []int64{1, 2, 3, 4, 5},
+			rounds:    3,
+			games:     swiss,
+			tiebreaks: []string{SonnebornBerger},
+			want: []want{
+				{id: 1, rank: 1, points: 2.5, tiebreaks: []float64{2.25}}, // 1×0.5 + ½×1.5 + 1×1
+				{id: 5, rank: 2, points: 2, tiebreaks: []float64{4}},      // 1×2 + 1×2 + 0×2.5
+				{id: 4, rank: 3, points: 2, tiebreaks: []float64{2.25}},   // ½×1.5 + ½×2 + 1×0.5
+				{id: 3, rank: 4, points: 1, tiebreaks: []float64{2.25}},   // ½×2 + ½×2.5 + 0×1
+				{id: 2, rank: 5, points: 0, tiebreaks: []float64{0}},
+			},
+		},
+		{
+			name:      "progressive and wins",
+			ids:       []int64{1, 2, 3, 4, 5},
+			rounds:    3,
+			games:     swiss,
+			tiebreaks: []string{Wins, Progressive},
+			want: []want{
+				{id: 1, rank: 1, points: 2.5, tiebreaks: []float64{2, 5}},
+				{id: 4, rank: 2, points: 2, tiebreaks: []float64{1, 3.5}},
+				{id: 5, rank: 3, points: 2, tiebreaks: []float64{0, 5}},
+				{id: 3, rank: 4, points: 1, tiebreaks: []float64{0, 2.5}},
+				{id: 2, rank: 5, points: 0, tiebreaks: []float64{0, 0}},
+			},
+		},
+		{
+			name:      "earlier round",
+			ids:       []int64{1, 2, 3, 4, 5},
+			rounds:    1,
+			games:     swiss,
+			tiebreaks: []string{Buchholz},
+			want: []want{
+				{id: 5, rank: 1, points: 1, tiebreaks: []float64{1}},
+				{id: 1, rank: 2, points: 1, tiebreaks: []float64{0}},
+				{id: 3, rank: 3, points: 0.5, tiebreaks: []float64{0.5}},
+				{id: 4, rank: 3, points: 0.5, tiebreaks: []float64{0.5}},
+				{id: 2, rank: 5, points: 0, tiebreaks: []float64{1}},
+			},
+		},
+		{
+			name:   "direct encounter",
+			ids:    []int64{1, 2, 3, 4},
+			rounds: 2,
+			games: []Game{
+				played(1, 1, 2, 0, 1),
+				played(1, 3, 4, 0.5, 0.5),
+				played(2, 1, 3, 1, 0),
+				played(2, 2, 4, 0, 1),
+			},
+			tiebreaks: []string{DirectEncounter},
+			want: []want{
+				{id: 4, rank: 1, points: 1.5, tiebreaks: []float64{0}},
+				{id: 2, rank: 2, points: 1, tiebreaks: []float64{1}},
+				{id: 1, rank: 3, points: 1, tiebreaks: []float64{0}},
+				{id: 3, rank: 4, points: 0.5, tiebreaks: []float64{0}},
+			},
+		},
+		{
+			// 1, 2, 3 and 6 are tied but only 1-2 and 3-6 met, so the win
+			// of 1 over 2 doesn't count
+			name:   "direct encounter with an incomplete group",
+			ids:    []int64{3, 2, 1, 4, 5, 6},
+			rounds: 2,
+			games: []Game{
+				played(1, 1, 2, 1, 0),
+				played(1, 3, 4, 1, 0),
+				played(1, 5, 6, 1, 0),
+				played(2, 2, 4, 1, 0),
+				played(2, 5, 1, 1, 0),
+				played(2, 6, 3, 1, 0),
+			},
+			tiebreaks: []string{DirectEncounter},
+			want: []want{
+				{id: 5, rank: 1, points: 2, tiebreaks: []float64{0}},
+				{id: 3, rank: 2, points: 1, tiebreaks: []float64{0}},
+				{id: 2, rank: 2, points: 1, tiebreaks: []float64{0}},
+				{id: 1, rank: 2, points: 1, tiebreaks: []float64{0}},
+				{id: 6, rank: 2, points: 1, tiebreaks: []float64{0}},
+				{id: 4, rank: 6, points: 0, tiebreaks: []float64{0}},
+			},
+		},
+		{
+			// the cycle leaves the three players level after direct
+			// encounter and Sonneborn-Berger, where each opponent counts 1.5 with
+			// their unplayed round as a draw, so they keep their seed order and
+			// share the rank
+			name:   "shared ranks",
+			ids:    []int64{2, 1, 3},
+			rounds: 3,
+			games: []Game{
+				played(1, 1, 2, 1, 0),
+				played(2, 2, 3, 1, 0),
+				played(3, 3, 1, 1, 0),
+			},
+			tiebreaks: []string{DirectEncounter, SonnebornBerger},
+			want: []want{
+				{id: 2, rank: 1, points: 1, tiebreaks: []float64{1, 1.5}},
+				{id: 1, rank: 1, points: 1, tiebreaks: []float64{1, 1.5}},
+				{id: 3, rank: 1, points: 1, tiebreaks: []float64{1, 1.5}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			entries, err := Compute(tt.ids, tt.rounds, tt.games, tt.tiebreaks)
+			if err != nil {
+				t.Fatalf("Compute() error = %v", err)
+			}
+
+			if len(entries) != len(tt.ids) {
+				t.Fatalf("Compute() returned %d entries, want %d", len(entries), len(tt.ids))
+			}
+
+			for i, w := range tt.want {
+				e := entries[i]
+				if e.ID != w.id || e.Rank != w.rank || e.Points != w.points || fmt.Sprint(e.Tiebreaks) != fmt.Sprint(w.tiebreaks) {
+					t.Errorf("entry %d = #%d %d with %v %v, want #%d %d with %v %v",
+						i, e.Rank, e.ID, e.Points, e.Tiebreaks, w.rank, w.id, w.points, w.tiebreaks)
+				}
+			}
+		})
+	}
+}
+
+func TestComputePlayedAndWins(t *testing.T) {
+
+	entries, err := Compute([]int64{1, 2, 3, 4, 5}, 3, swiss, nil)
+	if err != nil {
+		t.Fatalf("Compute() error = %v", err)
+	}
+
+	// byes, forfeits and absences are neither played nor won
+	want := map[int64][2]int{1: {3, 2}, 2: {2, 0}, 3: {2, 0}, 4: {2, 1}, 5: {1, 0}}
+
+	for _, e := range entries {
+		if got := [2]int{e.Played, e.Wins}; got != want[e.ID] {
+			t.Errorf("player %d played and won %v, want %v", e.ID, got, want[e.ID])
+		}
+	}
+}
+
+func TestComputeUnknownTiebreak(t *testing.T) {
+
+	_, err := Compute([]int64{1, 2}, 1, nil, []string{"koya"})
+	if err == nil {
+		t.Fatal("Compute() error = nil, want an unknown tiebreak error")
+	}
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory keeps objects in a map, for development and tests.
+type Memory struct {
+	baseURL string
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func NewMemory(baseURL string) *Memory {
+	return &Memory{
+		baseURL: baseURL,
+		objects: make(map[string][]byte),
+	}
+}
+
+func (m *Memory) Put(ctx context.Context, key string, data []byte, contentType string) error {
+
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = append([]byte(nil), data...)
+
+	return nil
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+
+	return nil
+}
+
+func (m *Memory) URL(key string) string {
+	return joinURL(m.baseURL, key)
+}
+
+// Get returns a copy of the object.
+func (m *Memory) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, false
+	}
+
+	return append([]byte(nil), data...), true
+}
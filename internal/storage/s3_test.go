@@ -0,0 +1,165 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"api.swahilichess.com/internal/storage"
+	"api.swahilichess.com/internal/storage/s3test"
+)
+
+func TestS3PutDelete(t *testing.T) {
+
+	srv := s3test.NewServer("media", "access")
+	defer srv.Close()
+
+	s := srv.Storage()
+	ctx := context.Background()
+
+	err := s.Put(ctx, "photos/abc.jpg", []byte("jpeg"), "image/jpeg")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	err = s.Put(ctx, "photos/abc_64.jpg", []byte("thumb"), "image/jpeg")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	data, contentType, ok := srv.Object("photos/abc.jpg")
+	if !ok || string(data) != "jpeg" || contentType != "image/jpeg" {
+		t.Errorf("object = %q %q %v, want jpeg image/jpeg", data, contentType, ok)
+	}
+
+	err = s.Delete(ctx, "photos/abc.jpg")
+	if err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if keys := srv.Keys(); fmt.Sprint(keys) != "[photos/abc_64.jpg]" {
+		t.Errorf("Keys() = %v after Delete(), want [photos/abc_64.jpg]", keys)
+	}
+
+	// deleting a missing object is not an error
+	err = s.Delete(ctx, "photos/abc.jpg")
+	if err != nil {
+		t.Errorf("Delete() of a missing object error = %v", err)
+	}
+}
+
+func TestS3PutEscapesKey(t *testing.T) {
+
+	srv := s3test.NewServer("media", "access")
+	defer srv.Close()
+
+	err := srv.Storage().Put(context.Background(), "photos/a b+c.png", []byte("png"), "image/png")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if _, _, ok := srv.Object("photos/a b+c.png"); !ok {
+		t.Errorf("Keys() = %v, want photos/a b+c.png", srv.Keys())
+	}
+}
+
+func TestS3RejectsInvalidKey(t *testing.T) {
+
+	srv := s3test.NewServer("media", "access")
+	defer srv.Close()
+
+	s := srv.Storage()
+
+	for _, key := range []string{"", "../abc.jpg", "/abc.jpg", "photos//abc.jpg"} {
+
+		if err := s.Put(context.Background(), key, []byte("x"), ""); !errors.Is(err, storage.ErrInvalidKey) {
+			t.Errorf("Put(%q) error = %v, want ErrInvalidKey", key, err)
+		}
+
+		if err := s.Delete(context.Background(), key); !errors.Is(err, storage.ErrInvalidKey) {
+			t.Errorf("Delete(%q) error = %v, want ErrInvalidKey", key, err)
+		}
+	}
+
+	if len(srv.Keys()) != 0 {
+		t.Errorf("Keys() = %v, want an empty bucket", srv.Keys())
+	}
+}
+
+func TestS3WrongAccessKey(t *testing.T) {
+
+	srv := s3test.NewServer("media", "access")
+	defer srv.Close()
+
+	s, err := storage.NewS3(storage.S3Config{
+		Endpoint:  srv.URL,
+		Bucket:    "media",
+		AccessKey: "someone-else",
+		SecretKey: "secret",
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Put(context.Background(), "photos/abc.jpg", []byte("jpeg"), "image/jpeg")
+
+	var statusErr *storage.StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != http.StatusForbidden || statusErr.Method != http.MethodPut {
+		t.Fatalf("Put() error = %v, want a 403 StatusError", err)
+	}
+}
+
+func TestS3URL(t *testing.T) {
+
+	tests := []struct {
+		name string
+		cfg  storage.S3Config
+		want string
+	}{
+		{
+			name: "virtual hosted",
+			cfg:  storage.S3Config{Endpoint: "https://s3.eu-west-1.amazonaws.com", Bucket: "media"},
+			want: "https://media.s3.eu-west-1.amazonaws.com/photos/abc.jpg",
+		},
+		{
+			name: "path style",
+			cfg:  storage.S3Config{Endpoint: "http://minio:9000", Bucket: "media", PathStyle: true},
+			want: "http://minio:9000/media/photos/abc.jpg",
+		},
+		{
+			name: "public url",
+			cfg:  storage.S3Config{Endpoint: "http://minio:9000", Bucket: "media", PublicURL: "https://cdn.swahilichess.com/"},
+			want: "https://cdn.swahilichess.com/photos/abc.jpg",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			s, err := storage.NewS3(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := s.URL("photos/abc.jpg"); got != tt.want {
+				t.Errorf("URL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewS3Invalid(t *testing.T) {
+
+	for _, cfg := range []storage.S3Config{
+		{Endpoint: "minio:9000", Bucket: "media"},
+		{Endpoint: "", Bucket: "media"},
+		{Endpoint: "http://minio:9000"},
+	} {
+		if _, err := storage.NewS3(cfg); err == nil {
+			t.Errorf("NewS3(%+v) error = nil", cfg)
+		}
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
+	"database/sql"
 	"encoding/base32"
+	"errors"
 	"time"
 
 	db "api.swahilichess.com/internal/db/sqlc"
@@ -13,17 +15,47 @@ import (
 
 const (
 	ScopeAuthentication = "authentication"
-	ttl                 = 365 * 24 * time.Hour
+	ScopeRefresh        = "refresh"
+	accessTTL           = 15 * time.Minute
+	refreshTTL          = 30 * 24 * time.Hour
 )
 
-func New(user_id uuid.UUID, store db.Store, scope string) (string, time.Time, error) {
+var (
+	ErrInvalidToken = errors.New("invalid or expired refresh token")
+	ErrTokenReused  = errors.New("refresh token reuse detected")
+)
+
+// Metadata describes the client a token family was issued to.
+type Metadata struct {
+	UserAgent string
+	IP        string
+}
+
+// Pair is a short lived access token and the refresh token used to renew it.
+type Pair struct {
+	AccessToken   string
+	AccessExpiry  time.Time
+	RefreshToken  string
+	RefreshExpiry time.Time
+}
+
+// New creates a token of the given scope that belongs to the token family.
+func New(ctx context.Context, user_id uuid.UUID, family_id uuid.UUID, store db.Store, scope string, meta Metadata) (string, time.Time, error) {
 
-	token, tokenText, err := generateToken(user_id, ttl, scope)
+	ttl := accessTTL
+	if scope == ScopeRefresh {
+		ttl = refreshTTL
+	}
+
+	token, tokenText, err := generateToken(user_id, family_id, ttl, scope)
 	if err != nil {
 		return "", time.Time{}, err
 	}
 
-	err = store.CreateToken(context.Background(), *token)
+	token.UserAgent = meta.UserAgent
+	token.Ip = meta.IP
+
+	err = store.CreateToken(ctx, *token)
 	if err != nil {
 		return "", time.Time{}, err
 	}
@@ -31,12 +63,89 @@ func New(user_id uuid.UUID, store db.Store, scope string) (string, time.Time, er
 	return tokenText, token.Expiry, err
 }
 
-func generateToken(user_id uuid.UUID, ttl time.Duration, scope string) (*db.CreateTokenParams, string, error) {
+// NewPair starts a new token family with an access and a refresh token.
+func NewPair(ctx context.Context, user_id uuid.UUID, store db.Store, meta Metadata) (*Pair, error) {
+	return newPair(ctx, user_id, uuid.New(), store, meta)
+}
+
+// Rotate exchanges a refresh token for a new pair in the same family. A refresh
+// token can only be used once, presenting it again revokes the whole family.
+func Rotate(ctx context.Context, refreshToken string, store db.Store) (*Pair, error) {
+
+	hash := Hash(refreshToken)
+
+	token, err := store.GetToken(ctx, db.GetTokenParams{Hash: hash, Scope: ScopeRefresh})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if token.Rotated {
+		return nil, revokeFamily(ctx, token.FamilyID, store)
+	}
+
+	if time.Now().After(token.Expiry) {
+		return nil, ErrInvalidToken
+	}
+
+	rows, err := store.RotateToken(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Another request rotated the token first.
+	if rows == 0 {
+		return nil, revokeFamily(ctx, token.FamilyID, store)
+	}
+
+	meta := Metadata{UserAgent: token.UserAgent, IP: token.Ip}
+
+	return newPair(ctx, token.UserID, token.FamilyID, store, meta)
+}
+
+// Hash returns the hash under which the plaintext token is stored.
+func Hash(tokenPlaintext string) []byte {
+	hash := sha256.Sum256([]byte(tokenPlaintext))
+	return hash[:]
+}
+
+func newPair(ctx context.Context, user_id uuid.UUID, family_id uuid.UUID, store db.Store, meta Metadata) (*Pair, error) {
+
+	access, accessExpiry, err := New(ctx, user_id, family_id, store, ScopeAuthentication, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, refreshExpiry, err := New(ctx, user_id, family_id, store, ScopeRefresh, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pair{
+		AccessToken:   access,
+		AccessExpiry:  accessExpiry,
+		RefreshToken:  refresh,
+		RefreshExpiry: refreshExpiry,
+	}, nil
+}
+
+func revokeFamily(ctx context.Context, family_id uuid.UUID, store db.Store) error {
+	err := store.DeleteTokensByFamily(ctx, family_id)
+	if err != nil {
+		return err
+	}
+	return ErrTokenReused
+}
+
+func generateToken(user_id uuid.UUID, family_id uuid.UUID, ttl time.Duration, scope string) (*db.CreateTokenParams, string, error) {
 
 	token := &db.CreateTokenParams{
-		UserID: user_id,
-		Expiry: time.Now().Add(ttl),
-		Scope:  scope,
+		UserID:   user_id,
+		Expiry:   time.Now().Add(ttl),
+		Scope:    scope,
+		FamilyID: family_id,
 	}
 
 	randomBytes := make([]byte, 16)
@@ -46,7 +155,6 @@ func generateToken(user_id uuid.UUID, ttl time.Duration, scope string) (*db.Crea
 	}
 
 	tokenPlaintext := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
-	hash := sha256.Sum256([]byte(tokenPlaintext))
-	token.Hash = hash[:]
+	token.Hash = Hash(tokenPlaintext)
 	return token, tokenPlaintext, nil
 }
@@ -0,0 +1,175 @@
+package token
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	db "api.swahilichess.com/internal/db/sqlc"
+	"github.com/google/uuid"
+)
+
+// tokenStore keeps the tokens in memory by hash.
+type tokenStore struct {
+	db.Store
+
+	tokens map[string]db.Token
+}
+
+func (s *tokenStore) CreateToken(ctx context.Context, arg db.CreateTokenParams) error {
+	s.tokens[string(arg.Hash)] = db.Token{
+		Hash:      arg.Hash,
+		UserID:    arg.UserID,
+		Expiry:    arg.Expiry,
+		Scope:     arg.Scope,
+		FamilyID:  arg.FamilyID,
+		UserAgent: arg.UserAgent,
+		Ip:        arg.Ip,
+	}
+	return nil
+}
+
+func (s *tokenStore) GetToken(ctx context.Context, arg db.GetTokenParams) (db.Token, error) {
+	token, ok := s.tokens[string(arg.Hash)]
+	if !ok || token.Scope != arg.Scope {
+		return db.Token{}, sql.ErrNoRows
+	}
+	return token, nil
+}
+
+func (s *tokenStore) RotateToken(ctx context.Context, hash []byte) (int64, error) {
+	token, ok := s.tokens[string(hash)]
+	if !ok || token.Rotated {
+		return 0, nil
+	}
+	token.Rotated = true
+	s.tokens[string(hash)] = token
+	return 1, nil
+}
+
+func (s *tokenStore) DeleteTokensByFamily(ctx context.Context, familyID uuid.UUID) error {
+	for hash, token := range s.tokens {
+		if token.FamilyID == familyID {
+			delete(s.tokens, hash)
+		}
+	}
+	return nil
+}
+
+func (s *tokenStore) family(familyID uuid.UUID) int {
+	n := 0
+	for _, token := range s.tokens {
+		if token.FamilyID == familyID {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRotate(t *testing.T) {
+
+	ctx := context.Background()
+	store := &tokenStore{tokens: make(map[string]db.Token)}
+	meta := Metadata{UserAgent: "curl", IP: "127.0.0.1"}
+
+	first, err := NewPair(ctx, uuid.New(), store, meta)
+	if err != nil {
+		t.Fatalf("NewPair() error = %v", err)
+	}
+
+	familyID := store.tokens[string(Hash(first.RefreshToken))].FamilyID
+
+	second, err := Rotate(ctx, first.RefreshToken, store)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == first.AccessToken {
+		t.Error("Rotate() returned the same tokens")
+	}
+
+	refresh := store.tokens[string(Hash(second.RefreshToken))]
+	if refresh.FamilyID != familyID || refresh.UserAgent != meta.UserAgent || refresh.Ip != meta.IP {
+		t.Errorf("rotated refresh token = %+v, want family %s and the same metadata", refresh, familyID)
+	}
+
+	if !store.tokens[string(Hash(first.RefreshToken))].Rotated {
+		t.Error("first refresh token is not marked rotated")
+	}
+
+	if n := store.family(familyID); n != 4 {
+		t.Errorf("family has %d tokens, want 4", n)
+	}
+}
+
+func TestRotateReuse(t *testing.T) {
+
+	ctx := context.Background()
+	store := &tokenStore{tokens: make(map[string]db.Token)}
+
+	first, err := NewPair(ctx, uuid.New(), store, Metadata{})
+	if err != nil {
+		t.Fatalf("NewPair() error = %v", err)
+	}
+
+	other, err := NewPair(ctx, uuid.New(), store, Metadata{})
+	if err != nil {
+		t.Fatalf("NewPair() error = %v", err)
+	}
+
+	familyID := store.tokens[string(Hash(first.RefreshToken))].FamilyID
+
+	_, err = Rotate(ctx, first.RefreshToken, store)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+
+	_, err = Rotate(ctx, first.RefreshToken, store)
+	if !errors.Is(err, ErrTokenReused) {
+		t.Fatalf("Rotate() reused error = %v, want %v", err, ErrTokenReused)
+	}
+
+	if n := store.family(familyID); n != 0 {
+		t.Errorf("reused family has %d tokens, want 0", n)
+	}
+
+	if _, ok := store.tokens[string(Hash(other.RefreshToken))]; !ok {
+		t.Error("other family was revoked")
+	}
+}
+
+func TestRotateInvalid(t *testing.T) {
+
+	ctx := context.Background()
+	store := &tokenStore{tokens: make(map[string]db.Token)}
+
+	pair, err := NewPair(ctx, uuid.New(), store, Metadata{})
+	if err != nil {
+		t.Fatalf("NewPair() error = %v", err)
+	}
+
+	expired := store.tokens[string(Hash(pair.RefreshToken))]
+	expired.Expiry = time.Now().Add(-time.Minute)
+	store.tokens[string(Hash(pair.RefreshToken))] = expired
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "unknown", token: "AAAAAAAAAAAAAAAAAAAAAAAAAA"},
+		{name: "access token", token: pair.AccessToken},
+		{name: "expired", token: pair.RefreshToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			_, err := Rotate(ctx, tt.token, store)
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Rotate() error = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
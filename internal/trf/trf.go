@@ -0,0 +1,373 @@
+// Package trf reads and writes the FIDE Tournament Report File, TRF-16, used
+// to submit tournaments for rating and by pairing programs.
+package trf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// result codes of a player's round
+const (
+	Win          = '1'
+	Loss         = '0'
+	Draw         = '='
+	ForfeitWin   = '+'
+	ForfeitLoss  = '-'
+	UnratedWin   = 'W'
+	UnratedDraw  = 'D'
+	UnratedLoss  = 'L'
+	HalfBye      = 'H'
+	FullBye      = 'F'
+	PairingBye   = 'U'
+	ZeroBye      = 'Z'
+	NotPlayedYet = ' '
+)
+
+// colors of a player's round
+const (
+	WhiteColor = 'w'
+	BlackColor = 'b'
+	NoColor    = '-'
+)
+
+// maxRounds bounds the round columns read from a line.
+const maxRounds = 99
+
+// Tournament is the part of a report the API keeps, fields left empty aren't
+// written.
+type Tournament struct {
+	Name        string
+	City        string
+	Federation  string
+	StartDate   string
+	EndDate     string
+	Type        string
+	Arbiter     string
+	TimeControl string
+	Rounds      int
+	Players     []Player
+}
+
+// Player is a 001 line. Results are by round, Opponent is the opponent's
+// starting rank or 0 when the round has none.
+type Player struct {
+	StartRank  int
+	Sex        string
+	Title      string
+	Name       string
+	Rating     int
+	Federation string
+	FideID     string
+	BirthDate  string
+	Points     float64
+	Rank       int
+	Results    []Result
+}
+
+type Result struct {
+	Opponent int
+	Color    rune
+	Code     rune
+}
+
+// Played reports whether the result is a game played over the board.
+func (r Result) Played() bool {
+	switch r.Code {
+	case Win, Loss, Draw, UnratedWin, UnratedDraw, UnratedLoss:
+		return r.Opponent != 0
+	default:
+		return false
+	}
+}
+
+// SyntaxError reports a malformed line.
+type SyntaxError struct {
+	Line int
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("trf: line %d: %s", e.Line, e.Msg)
+}
+
+// Write renders the tournament, round columns run up to the longest results.
+func Write(w io.Writer, t Tournament) error {
+
+	bw := bufio.NewWriter(w)
+
+	header := []struct {
+		code  string
+		value string
+	}{
+		{"012", t.Name},
+		{"022", t.City},
+		{"032", t.Federation},
+		{"042", t.StartDate},
+		{"052", t.EndDate},
+		{"062", strconv.Itoa(len(t.Players))},
+		{"072", strconv.Itoa(ratedPlayers(t.Players))},
+		{"092", t.Type},
+		{"102", t.Arbiter},
+		{"122", t.TimeControl},
+	}
+
+	for _, h := range header {
+		if h.value != "" {
+			fmt.Fprintf(bw, "%s %s\n", h.code, oneLine(h.value))
+		}
+	}
+
+	for _, p := range t.Players {
+		bw.WriteString(playerLine(p))
+		bw.WriteByte('\n')
+	}
+
+	// the number of rounds, an extension pairing programs read
+	if t.Rounds > 0 {
+		fmt.Fprintf(bw, "XXR %d\n", t.Rounds)
+	}
+
+	return bw.Flush()
+}
+
+func playerLine(p Player) string {
+
+	line := []rune(strings.Repeat(" ", 89+10*len(p.Results)))
+
+	put := func(col int, width int, value string, right bool) {
+		runes := []rune(value)
+		if len(runes) > width {
+			runes = runes[:width]
+		}
+		start := col - 1
+		if right {
+			start += width - len(runes)
+		}
+		copy(line[start:], runes)
+	}
+
+	put(1, 3, "001", false)
+	put(5, 4, strconv.Itoa(p.StartRank), true)
+	put(10, 1, p.Sex, false)
+	put(11, 3, p.Title, true)
+	put(15, 33, oneLine(p.Name), false)
+	if p.Rating > 0 {
+		put(49, 4, strconv.Itoa(p.Rating), true)
+	}
+	put(54, 3, p.Federation, false)
+	put(58, 11, p.FideID, true)
+	put(70, 10, p.BirthDate, false)
+	put(81, 4, strconv.FormatFloat(p.Points, 'f', 1, 64), true)
+	if p.Rank > 0 {
+		put(86, 4, strconv.Itoa(p.Rank), true)
+	}
+
+	for i, r := range p.Results {
+		col := 92 + 10*i
+		opponent := "0000"
+		if r.Opponent != 0 {
+			opponent = fmt.Sprintf("%4d", r.Opponent)
+		}
+		color := r.Color
+		if color == 0 || r.Opponent == 0 {
+			color = NoColor
+		}
+		code := r.Code
+		if code == 0 {
+			code = NotPlayedYet
+		}
+		put(col, 4, opponent, true)
+		put(col+5, 1, string(color), false)
+		put(col+7, 1, string(code), false)
+	}
+
+	return strings.TrimRight(string(line), " ")
+}
+
+// Parse reads a report. Lines it doesn't use, such as team lines, are
+// skipped.
+func Parse(r io.Reader) (Tournament, error) {
+
+	var t Tournament
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	ranks := make(map[int]bool)
+
+	for n := 1; scanner.Scan(); n++ {
+
+		line := strings.TrimRight(scanner.Text(), " \r\t")
+		if n == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if !utf8.ValidString(line) {
+			return t, &SyntaxError{Line: n, Msg: "invalid UTF-8"}
+		}
+		if len(line) < 3 {
+			continue
+		}
+
+		value := ""
+		if runes := []rune(line); len(runes) > 4 {
+			value = strings.TrimSpace(string(runes[4:]))
+		}
+
+		switch line[:3] {
+		case "012":
+			t.Name = value
+		case "022":
+			t.City = value
+		case "032":
+			t.Federation = value
+		case "042":
+			t.StartDate = value
+		case "052":
+			t.EndDate = value
+		case "092":
+			t.Type = value
+		case "102":
+			t.Arbiter = value
+		case "122":
+			t.TimeControl = value
+		case "XXR":
+			rounds, err := strconv.Atoi(value)
+			if err != nil || rounds < 1 || rounds > maxRounds {
+				return t, &SyntaxError{Line: n, Msg: "invalid number of rounds"}
+			}
+			t.Rounds = rounds
+		case "001":
+			p, err := parsePlayer([]rune(line))
+			if err != nil {
+				return t, &SyntaxError{Line: n, Msg: err.Error()}
+			}
+			if ranks[p.StartRank] {
+				return t, &SyntaxError{Line: n, Msg: fmt.Sprintf("starting rank %d is used twice", p.StartRank)}
+			}
+			ranks[p.StartRank] = true
+			t.Players = append(t.Players, p)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return t, err
+	}
+
+	for _, p := range t.Players {
+		for round, r := range p.Results {
+			if r.Opponent != 0 && !ranks[r.Opponent] {
+				return t, fmt.Errorf("trf: player %d meets unknown player %d in round %d", p.StartRank, r.Opponent, round+1)
+			}
+		}
+		t.Rounds = max(t.Rounds, len(p.Results))
+	}
+
+	return t, nil
+}
+
+func parsePlayer(line []rune) (Player, error) {
+
+	field := func(col int, width int) string {
+		start := col - 1
+		if start >= len(line) {
+			return ""
+		}
+		end := min(start+width, len(line))
+		return strings.TrimSpace(string(line[start:end]))
+	}
+
+	var p Player
+	var err error
+
+	p.StartRank, err = strconv.Atoi(field(5, 4))
+	if err != nil || p.StartRank < 1 {
+		return p, errors.New("invalid starting rank")
+	}
+
+	p.Sex = field(10, 1)
+	p.Title = field(11, 3)
+	p.Name = field(15, 33)
+	if p.Name == "" {
+		return p, errors.New("missing player name")
+	}
+
+	if rating := field(49, 4); rating != "" {
+		p.Rating, err = strconv.Atoi(rating)
+		if err != nil || p.Rating < 0 {
+			return p, errors.New("invalid rating")
+		}
+	}
+
+	p.Federation = field(54, 3)
+	p.FideID = field(58, 11)
+	p.BirthDate = field(70, 10)
+
+	if points := field(81, 4); points != "" {
+		p.Points, err = strconv.ParseFloat(points, 64)
+		if err != nil {
+			return p, errors.New("invalid points")
+		}
+	}
+
+	if rank := field(86, 4); rank != "" {
+		p.Rank, err = strconv.Atoi(rank)
+		if err != nil {
+			return p, errors.New("invalid rank")
+		}
+	}
+
+	for col := 92; col <= len(line); col += 10 {
+
+		if len(p.Results) == maxRounds {
+			return p, errors.New("too many rounds")
+		}
+
+		opponent := field(col, 4)
+		color := []rune(field(col+5, 1) + string(NoColor))[0]
+		code := []rune(field(col+7, 1) + string(NotPlayedYet))[0]
+
+		r := Result{Color: color, Code: code}
+		if opponent != "" {
+			r.Opponent, err = strconv.Atoi(opponent)
+			if err != nil || r.Opponent < 0 {
+				return p, fmt.Errorf("invalid opponent in round %d", len(p.Results)+1)
+			}
+		}
+
+		if r.Opponent == p.StartRank {
+			return p, fmt.Errorf("player meets themselves in round %d", len(p.Results)+1)
+		}
+
+		if !strings.ContainsRune("wb-", r.Color) {
+			return p, fmt.Errorf("invalid color in round %d", len(p.Results)+1)
+		}
+
+		if !strings.ContainsRune("10=+-WDLHFUZ ", r.Code) {
+			return p, fmt.Errorf("invalid result in round %d", len(p.Results)+1)
+		}
+
+		p.Results = append(p.Results, r)
+	}
+
+	return p, nil
+}
+
+func ratedPlayers(players []Player) int {
+	n := 0
+	for _, p := range players {
+		if p.Rating > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
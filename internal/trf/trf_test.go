@@ -0,0 +1,363 @@
+package trf
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testdata/swiss.trf is laid out the way Swiss-Manager exports a report: a
+// byte order mark, CRLF line endings, the 112 and 132 lines the API doesn't
+// read and no XXR line. Its three rounds have a pairing bye, a forfeit, a
+// half-point bye, an absence and an unrated player.
+func parseFixture(t *testing.T) Tournament {
+
+	t.Helper()
+
+	f, err := os.Open("testdata/swiss.trf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tournament, err := Parse(f)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	return tournament
+}
+
+func TestParse(t *testing.T) {
+
+	tournament := parseFixture(t)
+
+	header := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"name", tournament.Name, "Dar es Salaam Rapid Open 2026"},
+		{"city", tournament.City, "Dar es Salaam"},
+		{"federation", tournament.Federation, "TAN"},
+		{"start date", tournament.StartDate, "2026/03/14"},
+		{"end date", tournament.EndDate, "2026/03/15"},
+		{"type", tournament.Type, "Individual: Swiss-System"},
+		{"arbiter", tournament.Arbiter, "IA Mwanaisha Bakari"},
+		{"time control", tournament.TimeControl, "15 minutes + 10 seconds per move"},
+	}
+
+	for _, h := range header {
+		if h.got != h.want {
+			t.Errorf("%s = %q, want %q", h.name, h.got, h.want)
+		}
+	}
+
+	if tournament.Rounds != 3 || len(tournament.Players) != 7 {
+		t.Fatalf("Parse() = %d rounds and %d players, want 3 and 7", tournament.Rounds, len(tournament.Players))
+	}
+
+	want := Player{
+		StartRank:  3,
+		Sex:        "w",
+		Title:      "WCM",
+		Name:       "Mushi, Neema",
+		Rating:     1987,
+		Federation: "TAN",
+		FideID:     "14100037",
+		BirthDate:  "2001/06/25",
+		Points:     0.5,
+		Rank:       6,
+		Results: []Result{
+			{Opponent: 7, Color: WhiteColor, Code: Draw},
+			{Opponent: 4, Color: BlackColor, Code: ForfeitLoss},
+			{Opponent: 2, Color: WhiteColor, Code: Loss},
+		},
+	}
+
+	if got := tournament.Players[2]; !reflect.DeepEqual(got, want) {
+		t.Errorf("player 3 = %+v, want %+v", got, want)
+	}
+
+	byes := []struct {
+		player int
+		round  int
+		code   rune
+	}{
+		{player: 4, round: 1, code: PairingBye},
+		{player: 7, round: 2, code: HalfBye},
+		{player: 5, round: 3, code: ZeroBye},
+	}
+
+	for _, b := range byes {
+		r := tournament.Players[b.player-1].Results[b.round-1]
+		if r != (Result{Color: NoColor, Code: b.code}) {
+			t.Errorf("player %d round %d = %+v, want a %c bye", b.player, b.round, r, b.code)
+		}
+	}
+
+	if p := tournament.Players[5]; p.Rating != 0 || p.FideID != "" {
+		t.Errorf("unrated player = rating %d, fide id %q, want none", p.Rating, p.FideID)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+
+	tournament := parseFixture(t)
+
+	var first bytes.Buffer
+	if err := Write(&first, tournament); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	again, err := Parse(bytes.NewReader(first.Bytes()))
+	if err != nil {
+		t.Fatalf("Parse(Write()) error = %v", err)
+	}
+
+	if !reflect.DeepEqual(again, tournament) {
+		t.Errorf("Parse(Write()) = %+v, want %+v", again, tournament)
+	}
+
+	var second bytes.Buffer
+	if err := Write(&second, again); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if first.String() != second.String() {
+		t.Errorf("second Write() = %q, want %q", second.String(), first.String())
+	}
+
+	// the player lines keep the columns of the original report
+	fixture, err := os.ReadFile("testdata/swiss.trf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(fixture), "\r\n") {
+		if strings.HasPrefix(line, "001") && !strings.Contains(first.String(), line+"\n") {
+			t.Errorf("Write() lost the line %q", line)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	err := Write(&buf, Tournament{
+		Name:   "Kombe la\nMeya",
+		Rounds: 5,
+		Players: []Player{
+			{StartRank: 1, Name: "Kimaro, Baraka", Rating: 2102, Points: 1, Results: []Result{
+				{Opponent: 2, Color: WhiteColor, Code: Win},
+				{Opponent: 2, Color: BlackColor},
+			}},
+			{StartRank: 2, Name: strings.Repeat("Long Name ", 5), Results: []Result{
+				{Opponent: 1, Color: BlackColor, Code: Loss},
+				{Opponent: 1, Color: WhiteColor},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"012 Kombe la Meya",
+		"062 2",
+		"072 1",
+		"001    1      Kimaro, Baraka                    2102                             1.0          2 w 1     2 b",
+		"001    2      Long Name Long Name Long Name Lon                                  0.0          1 b 0     1 w",
+		"XXR 5",
+		"",
+	}, "\n")
+
+	if buf.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestParsePlayer(t *testing.T) {
+
+	// the columns of a player line, a round takes ten from column 92
+	line := func(rounds ...string) string {
+		return "001    1 m FM Mwakyusa, Juma                    2215 TAN    14100010 1994/03/12  2.5    1  " + strings.Join(rounds, "  ")
+	}
+
+	tests := []struct {
+		name    string
+		line    string
+		results []Result
+		wantErr string
+	}{
+		{name: "no rounds", line: line()},
+		{name: "rounds", line: line("   5 w 1", "0000 - H", "  12 b ="), results: []Result{
+			{Opponent: 5, Color: WhiteColor, Code: Win},
+			{Color: NoColor, Code: HalfBye},
+			{Opponent: 12, Color: BlackColor, Code: Draw},
+		}},
+		{name: "round not played yet", line: line("   5 w 1", "   7 b"), results: []Result{
+			{Opponent: 5, Color: WhiteColor, Code: Win},
+			{Opponent: 7, Color: BlackColor, Code: NotPlayedYet},
+		}},
+		{name: "empty round", line: line("        ", "   7 b 0"), results: []Result{
+			{Color: NoColor, Code: NotPlayedYet},
+			{Opponent: 7, Color: BlackColor, Code: Loss},
+		}},
+		{name: "invalid starting rank", line: "001    x" + line()[8:], wantErr: "invalid starting rank"},
+		{name: "missing name", line: line()[:14] + strings.Repeat(" ", 33) + line()[47:], wantErr: "missing player name"},
+		{name: "invalid rating", line: line()[:48] + "22x5" + line()[52:], wantErr: "invalid rating"},
+		{name: "invalid points", line: line()[:80] + " 2,5" + line()[84:], wantErr: "invalid points"},
+		{name: "invalid rank", line: line()[:85] + "   x" + line()[89:], wantErr: "invalid rank"},
+		{name: "invalid opponent", line: line("  x5 w 1"), wantErr: "invalid opponent in round 1"},
+		{name: "meets themselves", line: line("   5 w 1", "   1 b 0"), wantErr: "player meets themselves in round 2"},
+		{name: "invalid color", line: line("   5 x 1"), wantErr: "invalid color in round 1"},
+		{name: "invalid result", line: line("   5 w 1", "   6 b 2"), wantErr: "invalid result in round 2"},
+		{name: "too many rounds", line: line(strings.Split(strings.Repeat("   5 w 1,", maxRounds+1), ",")[:maxRounds+1]...), wantErr: "too many rounds"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			p, err := parsePlayer([]rune(tt.line))
+
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("parsePlayer() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parsePlayer() error = %v", err)
+			}
+
+			if p.StartRank != 1 || p.Name != "Mwakyusa, Juma" || p.Rating != 2215 || p.Points != 2.5 || p.Rank != 1 {
+				t.Errorf("parsePlayer() = %+v", p)
+			}
+
+			if !reflect.DeepEqual(p.Results, tt.results) {
+				t.Errorf("parsePlayer() results = %+v, want %+v", p.Results, tt.results)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+
+	fixture, err := os.ReadFile("testdata/swiss.trf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(fixture), "\r\n"), "\r\n")
+
+	// replace swaps the fixture's line n, counted from 1
+	replace := func(n int, line string) string {
+		changed := append([]string{}, lines...)
+		changed[n-1] = line
+		return strings.Join(changed, "\r\n")
+	}
+
+	tests := []struct {
+		name     string
+		report   string
+		wantLine int
+		wantErr  string
+	}{
+		{
+			name:     "starting rank used twice",
+			report:   replace(16, strings.Replace(lines[15], "001    3", "001    1", 1)),
+			wantLine: 16,
+			wantErr:  "trf: line 16: starting rank 1 is used twice",
+		},
+		{
+			name:    "unknown opponent",
+			report:  replace(16, strings.Replace(lines[15], "   7 w =", "   9 w =", 1)),
+			wantErr: "trf: player 3 meets unknown player 9 in round 1",
+		},
+		{
+			name:     "invalid player line",
+			report:   replace(17, strings.Replace(lines[16], "   3 w +", "   3 w ?", 1)),
+			wantLine: 17,
+			wantErr:  "trf: line 17: invalid result in round 2",
+		},
+		{
+			name:     "invalid number of rounds",
+			report:   string(fixture) + "XXR 100\r\n",
+			wantLine: 21,
+			wantErr:  "trf: line 21: invalid number of rounds",
+		},
+		{
+			name:     "invalid UTF-8",
+			report:   replace(2, "022 Dar es Salaam \xff"),
+			wantLine: 2,
+			wantErr:  "trf: line 2: invalid UTF-8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			_, err := Parse(strings.NewReader(tt.report))
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+
+			var syntaxErr *SyntaxError
+			if tt.wantLine > 0 && (!errors.As(err, &syntaxErr) || syntaxErr.Line != tt.wantLine) {
+				t.Errorf("Parse() error = %#v, want a SyntaxError on line %d", err, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestParseRounds(t *testing.T) {
+
+	// XXR announces rounds not paired yet, the longest results win when
+	// they run further
+	report := "012 Kombe la Meya\n" +
+		"001    1          Kimaro, Baraka                                                  1.0          2 w 1\n" +
+		"001    2          Mushi, Neema                                                    0.0          1 b 0\n"
+
+	for _, tt := range []struct {
+		xxr  string
+		want int
+	}{
+		{xxr: "", want: 1},
+		{xxr: "XXR 7\n", want: 7},
+	} {
+		tournament, err := Parse(strings.NewReader(report + tt.xxr))
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		if tournament.Rounds != tt.want {
+			t.Errorf("Parse() with %q = %d rounds, want %d", tt.xxr, tournament.Rounds, tt.want)
+		}
+	}
+}
+
+func TestResultPlayed(t *testing.T) {
+
+	tests := []struct {
+		result Result
+		want   bool
+	}{
+		{Result{Opponent: 2, Code: Win}, true},
+		{Result{Opponent: 2, Code: Draw}, true},
+		{Result{Opponent: 2, Code: UnratedLoss}, true},
+		{Result{Opponent: 2, Code: ForfeitWin}, false},
+		{Result{Opponent: 2, Code: NotPlayedYet}, false},
+		{Result{Code: PairingBye}, false},
+		{Result{Code: Win}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.result.Played(); got != tt.want {
+			t.Errorf("%+v.Played() = %t, want %t", tt.result, got, tt.want)
+		}
+	}
+}